@@ -0,0 +1,695 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ApplyFunc is called for each node visited by Apply. The return value
+// controls the traversal, see Apply.
+type ApplyFunc func(cursor *Cursor) bool
+
+// ModifierFunc receives a node and returns the node that should take its place.
+type ModifierFunc func(node Node) Node
+
+//---[ Module API Functions ]---------------------------------------------------
+
+// Apply traverses the tree rooted at root depth-first, calling pre before the
+// children of a node are visited and post afterwards. Either may be nil.
+//
+// If pre returns false, the children and post of that node are skipped. If
+// post returns false, the traversal stops. Nil children (e.g. a missing
+// IfExpression.Alternative) are not visited.
+//
+// The callbacks may rewrite the tree through the Cursor. A node that pre
+// replaces the current one with is walked in its place: its children are
+// visited and post is called for it. Replacements made in post and inserted
+// nodes are not walked. The (possibly replaced) root is returned.
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+	app := &application{
+		pre:  pre,
+		post: post,
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil && recovered != errAbort {
+			panic(recovered)
+		}
+
+		result = app.root
+	}()
+
+	app.root = root
+	if !isNil(root) {
+		app.apply(nil, "", nil, root)
+	}
+
+	return app.root
+}
+
+// Modify walks the tree bottom-up and replaces every node with the result of
+// calling modifier on it. The modifier must return a non-nil node of a type
+// that fits the place of the original node.
+func Modify(root Node, modifier ModifierFunc) Node {
+	return Apply(root, nil, func(cursor *Cursor) bool {
+		if replacement := modifier(cursor.Node()); replacement != cursor.Node() {
+			cursor.Replace(replacement)
+		}
+
+		return true
+	})
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Cursor API Methods ]-----------------------------------------------------
+
+// Cursor describes the node currently being visited by Apply and the place
+// it occupies in its parent.
+type Cursor struct {
+	app    *application
+	parent Node
+	name   string     // field of parent holding the node
+	iter   *iterator  // non-nil if the node is an element of a slice field
+	node   Node
+}
+
+type iterator struct {
+	index int
+	step  int
+}
+
+func (cursor *Cursor) Node() Node {
+	return cursor.node
+}
+
+// Parent returns the node containing the current node, nil for the root.
+func (cursor *Cursor) Parent() Node {
+	return cursor.parent
+}
+
+// Name returns the parent's field holding the current node, e.g. "Left".
+func (cursor *Cursor) Name() string {
+	return cursor.name
+}
+
+// Index returns the position of the current node in its parent's slice
+// field, or -1 if it is not part of a slice.
+func (cursor *Cursor) Index() int {
+	if cursor.iter == nil {
+		return -1
+	}
+
+	return cursor.iter.index
+}
+
+// Replace swaps the current node for node. Panics if node has the wrong type
+// for the field it is stored in.
+func (cursor *Cursor) Replace(node Node) {
+	if cursor.parent == nil {
+		cursor.app.root = node
+		cursor.node     = node
+		return
+	}
+
+	if index := cursor.Index(); index >= 0 {
+		spliceField(cursor.parent, cursor.name, index, 1, node)
+	} else {
+		setField(cursor.parent, cursor.name, node)
+	}
+
+	cursor.node = node
+}
+
+// Delete removes the current node from its parent's slice field.
+func (cursor *Cursor) Delete() {
+	index := cursor.Index()
+	if index < 0 {
+		panic("ast: Delete of node not contained in slice")
+	}
+
+	spliceField(cursor.parent, cursor.name, index, 1, nil)
+	cursor.iter.step--
+}
+
+// InsertAfter places node right after the current node in its parent's
+// slice field. The inserted node is not walked by Apply.
+func (cursor *Cursor) InsertAfter(node Node) {
+	index := cursor.Index()
+	if index < 0 {
+		panic("ast: InsertAfter of node not contained in slice")
+	}
+
+	spliceField(cursor.parent, cursor.name, index+1, 0, node)
+	cursor.iter.step++
+}
+
+// InsertBefore places node right before the current node in its parent's
+// slice field. The inserted node is not walked by Apply.
+func (cursor *Cursor) InsertBefore(node Node) {
+	index := cursor.Index()
+	if index < 0 {
+		panic("ast: InsertBefore of node not contained in slice")
+	}
+
+	spliceField(cursor.parent, cursor.name, index, 0, node)
+	cursor.iter.index++
+}
+
+//---[ Cursor API Methods ]-----------------------------------------------------
+
+
+//---[ Apply Helper Methods ]---------------------------------------------------
+
+var errAbort = new(int)  // sentinel panic value used to stop the traversal
+
+type application struct {
+	pre    ApplyFunc
+	post   ApplyFunc
+	root   Node
+	cursor Cursor
+	iter   iterator
+}
+
+func (app *application) apply(parent Node, name string, iter *iterator, node Node) {
+	// the cursor is reused for every node -> restore the caller's on the way out
+	saved := app.cursor
+
+	app.cursor = Cursor{
+		app:    app,
+		parent: parent,
+		name:   name,
+		iter:   iter,
+		node:   node,
+	}
+
+	if app.pre != nil && !app.pre(&app.cursor) {
+		app.cursor = saved
+		return
+	}
+
+	// children of the (possibly replaced) node
+	switch current := app.cursor.node.(type) {
+	case *Program:
+		app.applyList(current, "Statements")
+
+	case *LetStatement:
+		app.applyField(current, "Name", current.Name)
+		app.applyField(current, "Pattern", current.Pattern)
+		app.applyField(current, "Value", current.Value)
+
+	case *ArrayPattern:
+		app.applyList(current, "Elements")
+		app.applyField(current, "Rest", current.Rest)
+
+	case *HashPattern:
+		app.applyList(current, "Keys")
+		app.applyList(current, "Values")
+
+	case *LiteralPattern:
+		app.applyField(current, "Value", current.Value)
+
+	case *MatchExpression:
+		app.applyField(current, "Subject", current.Subject)
+		app.applyList(current, "Arms")
+
+	case *MatchArm:
+		app.applyField(current, "Pattern", current.Pattern)
+		app.applyField(current, "Guard", current.Guard)
+		app.applyField(current, "Body", current.Body)
+
+	case *Comprehension:
+		app.applyField(current, "Key", current.Key)
+		app.applyField(current, "Value", current.Value)
+		app.applyList(current, "Generators")
+
+	case *Generator:
+		app.applyField(current, "Key", current.Key)
+		app.applyField(current, "Value", current.Value)
+		app.applyField(current, "Iterable", current.Iterable)
+		app.applyList(current, "Filters")
+
+	case *ReturnStatement:
+		app.applyField(current, "ReturnValue", current.ReturnValue)
+
+	case *ThrowStatement:
+		app.applyField(current, "Value", current.Value)
+
+	case *TryExpression:
+		app.applyField(current, "Body", current.Body)
+		app.applyField(current, "Parameter", current.Parameter)
+		app.applyField(current, "Handler", current.Handler)
+		app.applyField(current, "Finally", current.Finally)
+
+	case *ExpressionStatement:
+		app.applyField(current, "Expression", current.Expression)
+
+	case *BlockStatement:
+		app.applyList(current, "Statements")
+
+	case *PrefixExpression:
+		app.applyField(current, "Right", current.Right)
+
+	case *InfixExpression:
+		app.applyField(current, "Left", current.Left)
+		app.applyField(current, "Right", current.Right)
+
+	case *IfExpression:
+		app.applyField(current, "Condition", current.Condition)
+		app.applyField(current, "Consequence", current.Consequence)
+		app.applyField(current, "Alternative", current.Alternative)
+
+	case *WhileStatement:
+		app.applyField(current, "Condition", current.Condition)
+		app.applyField(current, "Body", current.Body)
+
+	case *ForInStatement:
+		app.applyField(current, "Key", current.Key)
+		app.applyField(current, "Value", current.Value)
+		app.applyField(current, "Iterable", current.Iterable)
+		app.applyField(current, "Body", current.Body)
+
+	case *FunctionDeclaration:
+		app.applyField(current, "Name", current.Name)
+		app.applyField(current, "Function", current.Function)
+
+	case *ImportStatement:
+		app.applyField(current, "Name", current.Name)
+
+	case *ExportStatement:
+		app.applyField(current, "Statement", current.Statement)
+
+	case *FunctionLiteral:
+		app.applyList(current, "Parameters")
+		app.applyList(current, "Defaults")
+		app.applyField(current, "ReturnType", current.ReturnType)
+		app.applyField(current, "Body", current.Body)
+
+	case *MacroLiteral:
+		app.applyList(current, "Parameters")
+		app.applyField(current, "Body", current.Body)
+
+	case *CallExpression:
+		app.applyField(current, "Function", current.Function)
+		app.applyList(current, "Arguments")
+
+	case *TemplateLiteral:
+		app.applyList(current, "Expressions")
+
+	case *ArrayLiteral:
+		app.applyList(current, "Elements")
+
+	case *HashLiteral:
+		app.applyList(current, "Keys")
+		app.applyList(current, "Values")
+
+	case *IndexExpression:
+		app.applyField(current, "Left", current.Left)
+		app.applyField(current, "Index", current.Index)
+
+	case *RangeExpression:
+		app.applyField(current, "Start", current.Start)
+		app.applyField(current, "End", current.End)
+
+	case *MemberExpression:
+		app.applyField(current, "Object", current.Object)
+		app.applyField(current, "Property", current.Property)
+
+	case *AssignExpression:
+		app.applyField(current, "Target", current.Target)
+		app.applyField(current, "Value", current.Value)
+
+	case *Identifier:
+		app.applyField(current, "Type", current.Type)
+
+	case *FunctionType:
+		app.applyList(current, "Parameters")
+		app.applyField(current, "Result", current.Result)
+
+	case *IntegerLiteral, *Boolean, *StringLiteral, *NullLiteral, *NamedType, *BreakStatement, *ContinueStatement, *WildcardPattern:
+		// leaves
+
+	case nil:
+		// nothing to do
+
+	default:
+		panic(fmt.Sprintf("ast: Apply: unexpected node type %T", current))
+	}
+
+	if app.post != nil && !app.post(&app.cursor) {
+		panic(errAbort)
+	}
+
+	app.cursor = saved
+}
+
+func (app *application) applyField(parent Node, name string, child Node) {
+	if isNil(child) {
+		return
+	}
+
+	app.apply(parent, name, nil, child)
+}
+
+func (app *application) applyList(parent Node, name string) {
+	// the list may grow or shrink while it is walked -> re-read it every step
+	saved := app.iter
+	app.iter.index = 0
+
+	for {
+		element, ok := listElement(parent, name, app.iter.index)
+		if !ok {
+			break
+		}
+
+		app.iter.step = 1
+		if !isNil(element) {
+			app.apply(parent, name, &app.iter, element)
+		}
+		app.iter.index += app.iter.step
+	}
+
+	app.iter = saved
+}
+
+
+// helpers for reading / writing the fields of a parent node
+
+func listElement(parent Node, name string, index int) (Node, bool) {
+	switch node := parent.(type) {
+	case *Program:
+		if index < len(node.Statements) {
+			return node.Statements[index], true
+		}
+	case *BlockStatement:
+		if index < len(node.Statements) {
+			return node.Statements[index], true
+		}
+	case *FunctionLiteral:
+		if name == "Defaults" {
+			if index < len(node.Defaults) {
+				return node.Defaults[index], true
+			}
+		} else if index < len(node.Parameters) {
+			return node.Parameters[index], true
+		}
+	case *CallExpression:
+		if index < len(node.Arguments) {
+			return node.Arguments[index], true
+		}
+	case *TemplateLiteral:
+		if index < len(node.Expressions) {
+			return node.Expressions[index], true
+		}
+	case *ArrayLiteral:
+		if index < len(node.Elements) {
+			return node.Elements[index], true
+		}
+	case *HashLiteral:
+		if name == "Values" {
+			if index < len(node.Values) {
+				return node.Values[index], true
+			}
+		} else if index < len(node.Keys) {
+			return node.Keys[index], true
+		}
+	case *Comprehension:
+		if index < len(node.Generators) {
+			return node.Generators[index], true
+		}
+	case *Generator:
+		if index < len(node.Filters) {
+			return node.Filters[index], true
+		}
+	case *MacroLiteral:
+		if index < len(node.Parameters) {
+			return node.Parameters[index], true
+		}
+	case *ArrayPattern:
+		if index < len(node.Elements) {
+			return node.Elements[index], true
+		}
+	case *HashPattern:
+		if name == "Values" {
+			if index < len(node.Values) {
+				return node.Values[index], true
+			}
+		} else if index < len(node.Keys) {
+			return node.Keys[index], true
+		}
+	case *MatchExpression:
+		if index < len(node.Arms) {
+			return node.Arms[index], true
+		}
+	case *FunctionType:
+		if index < len(node.Parameters) {
+			return node.Parameters[index], true
+		}
+	default:
+		panic(fmt.Sprintf("ast: %T has no slice field %s", parent, name))
+	}
+
+	return nil, false
+}
+
+func spliceField(parent Node, name string, index, remove int, node Node) {
+	switch parentNode := parent.(type) {
+	case *Program:
+		parentNode.Statements = splice(parentNode.Statements, index, remove, node)
+	case *BlockStatement:
+		parentNode.Statements = splice(parentNode.Statements, index, remove, node)
+	case *FunctionLiteral:
+		if name == "Defaults" {
+			parentNode.Defaults = splice(parentNode.Defaults, index, remove, node)
+		} else {
+			parentNode.Parameters = splice(parentNode.Parameters, index, remove, node)
+		}
+	case *CallExpression:
+		parentNode.Arguments = splice(parentNode.Arguments, index, remove, node)
+	case *TemplateLiteral:
+		parentNode.Expressions = splice(parentNode.Expressions, index, remove, node)
+	case *ArrayLiteral:
+		parentNode.Elements = splice(parentNode.Elements, index, remove, node)
+	case *HashLiteral:
+		if name == "Values" {
+			parentNode.Values = splice(parentNode.Values, index, remove, node)
+		} else {
+			parentNode.Keys = splice(parentNode.Keys, index, remove, node)
+		}
+	case *Comprehension:
+		parentNode.Generators = splice(parentNode.Generators, index, remove, node)
+	case *Generator:
+		parentNode.Filters = splice(parentNode.Filters, index, remove, node)
+	case *MacroLiteral:
+		parentNode.Parameters = splice(parentNode.Parameters, index, remove, node)
+	case *ArrayPattern:
+		parentNode.Elements = splice(parentNode.Elements, index, remove, node)
+	case *HashPattern:
+		if name == "Values" {
+			parentNode.Values = splice(parentNode.Values, index, remove, node)
+		} else {
+			parentNode.Keys = splice(parentNode.Keys, index, remove, node)
+		}
+	case *MatchExpression:
+		parentNode.Arms = splice(parentNode.Arms, index, remove, node)
+	case *FunctionType:
+		parentNode.Parameters = splice(parentNode.Parameters, index, remove, node)
+	default:
+		panic(fmt.Sprintf("ast: %T has no slice field %s", parent, name))
+	}
+}
+
+func splice[T Node](list []T, index, remove int, node Node) []T {
+	result := make([]T, 0, len(list)+1)
+	result  = append(result, list[:index]...)
+
+	if node != nil {
+		result = append(result, mustBe[T](node))
+	}
+
+	return append(result, list[index+remove:]...)
+}
+
+func setField(parent Node, name string, node Node) {
+	switch parentNode := parent.(type) {
+	case *LetStatement:
+		switch name {
+		case "Name":
+			parentNode.Name = mustBe[*Identifier](node)
+		case "Pattern":
+			parentNode.Pattern = mustBe[Pattern](node)
+		case "Value":
+			parentNode.Value = mustBe[Expression](node)
+		}
+	case *ArrayPattern:
+		parentNode.Rest = mustBe[*Identifier](node)
+	case *LiteralPattern:
+		parentNode.Value = mustBe[Expression](node)
+	case *MatchExpression:
+		parentNode.Subject = mustBe[Expression](node)
+	case *MatchArm:
+		switch name {
+		case "Pattern":
+			parentNode.Pattern = mustBe[Pattern](node)
+		case "Guard":
+			parentNode.Guard = mustBe[Expression](node)
+		case "Body":
+			parentNode.Body = mustBe[*BlockStatement](node)
+		}
+	case *Comprehension:
+		switch name {
+		case "Key":
+			parentNode.Key = mustBe[Expression](node)
+		case "Value":
+			parentNode.Value = mustBe[Expression](node)
+		}
+	case *Generator:
+		switch name {
+		case "Key":
+			parentNode.Key = mustBe[*Identifier](node)
+		case "Value":
+			parentNode.Value = mustBe[*Identifier](node)
+		case "Iterable":
+			parentNode.Iterable = mustBe[Expression](node)
+		}
+	case *ReturnStatement:
+		parentNode.ReturnValue = mustBe[Expression](node)
+	case *ThrowStatement:
+		parentNode.Value = mustBe[Expression](node)
+	case *TryExpression:
+		switch name {
+		case "Body":
+			parentNode.Body = mustBe[*BlockStatement](node)
+		case "Parameter":
+			parentNode.Parameter = mustBe[*Identifier](node)
+		case "Handler":
+			parentNode.Handler = mustBe[*BlockStatement](node)
+		case "Finally":
+			parentNode.Finally = mustBe[*BlockStatement](node)
+		}
+	case *ExpressionStatement:
+		parentNode.Expression = mustBe[Expression](node)
+	case *PrefixExpression:
+		parentNode.Right = mustBe[Expression](node)
+	case *InfixExpression:
+		switch name {
+		case "Left":
+			parentNode.Left = mustBe[Expression](node)
+		case "Right":
+			parentNode.Right = mustBe[Expression](node)
+		}
+	case *IfExpression:
+		switch name {
+		case "Condition":
+			parentNode.Condition = mustBe[Expression](node)
+		case "Consequence":
+			parentNode.Consequence = mustBe[*BlockStatement](node)
+		case "Alternative":
+			parentNode.Alternative = mustBe[*BlockStatement](node)
+		}
+	case *WhileStatement:
+		switch name {
+		case "Condition":
+			parentNode.Condition = mustBe[Expression](node)
+		case "Body":
+			parentNode.Body = mustBe[*BlockStatement](node)
+		}
+	case *ForInStatement:
+		switch name {
+		case "Key":
+			parentNode.Key = mustBe[*Identifier](node)
+		case "Value":
+			parentNode.Value = mustBe[*Identifier](node)
+		case "Iterable":
+			parentNode.Iterable = mustBe[Expression](node)
+		case "Body":
+			parentNode.Body = mustBe[*BlockStatement](node)
+		}
+	case *FunctionDeclaration:
+		switch name {
+		case "Name":
+			parentNode.Name = mustBe[*Identifier](node)
+		case "Function":
+			parentNode.Function = mustBe[*FunctionLiteral](node)
+		}
+	case *ImportStatement:
+		parentNode.Name = mustBe[*Identifier](node)
+	case *ExportStatement:
+		parentNode.Statement = mustBe[Statement](node)
+	case *Identifier:
+		parentNode.Type = mustBe[TypeExpression](node)
+	case *FunctionLiteral:
+		switch name {
+		case "ReturnType":
+			parentNode.ReturnType = mustBe[TypeExpression](node)
+		case "Body":
+			parentNode.Body = mustBe[*BlockStatement](node)
+		}
+	case *CallExpression:
+		parentNode.Function = mustBe[Expression](node)
+	case *MacroLiteral:
+		parentNode.Body = mustBe[*BlockStatement](node)
+	case *IndexExpression:
+		switch name {
+		case "Left":
+			parentNode.Left = mustBe[Expression](node)
+		case "Index":
+			parentNode.Index = mustBe[Expression](node)
+		}
+	case *RangeExpression:
+		switch name {
+		case "Start":
+			parentNode.Start = mustBe[Expression](node)
+		case "End":
+			parentNode.End = mustBe[Expression](node)
+		}
+	case *MemberExpression:
+		switch name {
+		case "Object":
+			parentNode.Object = mustBe[Expression](node)
+		case "Property":
+			parentNode.Property = mustBe[*Identifier](node)
+		}
+	case *AssignExpression:
+		switch name {
+		case "Target":
+			parentNode.Target = mustBe[Expression](node)
+		case "Value":
+			parentNode.Value = mustBe[Expression](node)
+		}
+	case *FunctionType:
+		parentNode.Result = mustBe[TypeExpression](node)
+	default:
+		panic(fmt.Sprintf("ast: cannot set field %s of %T", name, parent))
+	}
+}
+
+// converts node for storing in a field of type T (nil -> zero value)
+func mustBe[T Node](node Node) T {
+	var zero T
+	if node == nil {
+		return zero
+	}
+
+	converted, ok := node.(T)
+	if !ok {
+		fieldType := strings.TrimPrefix(fmt.Sprintf("%T", new(T)), "*")
+		panic(fmt.Sprintf("ast: cannot use %T as %s", node, fieldType))
+	}
+
+	return converted
+}
+
+// typed nil pointers stored in a Node interface are not == nil
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+
+	value := reflect.ValueOf(node)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
+
+//---[ Apply Helper Methods ]---------------------------------------------------
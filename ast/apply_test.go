@@ -0,0 +1,351 @@
+package ast
+
+import (
+	"fmt"
+	"testing"
+
+	"monkey/token"
+)
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return integer(1) }
+	two := func() Expression { return integer(2) }
+
+	turnOneIntoTwo := func(node Node) Node {
+		literal, ok := node.(*IntegerLiteral)
+		if !ok || literal.Value != 1 {
+			return node
+		}
+
+		return two()
+	}
+
+	tests := []struct{
+		input    Node
+		expected string
+	}{
+		{one(), "2"},
+		{
+			&Program{Statements: []Statement{expression(one())}},
+			"2",
+		},
+		{infix(one(), "+", two()), "(2 + 2)"},
+		{infix(two(), "+", one()), "(2 + 2)"},
+		{prefix("-", one()), "(-2)"},
+		{
+			&IfExpression{
+				Token:       token.Token{Type: token.IF, Literal: "if"},
+				Condition:   one(),
+				Consequence: block(expression(one())),
+				Alternative: block(expression(one())),
+			},
+			"if2 2else2",
+		},
+		{
+			&ReturnStatement{
+				Token:       token.Token{Type: token.RETURN, Literal: "return"},
+				ReturnValue: one(),
+			},
+			"return 2;",
+		},
+		{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name:  identifier("x"),
+				Value: one(),
+			},
+			"let x = 2;",
+		},
+		{
+			&FunctionLiteral{
+				Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+				Parameters: []*Identifier{},
+				Body:       block(expression(one())),
+			},
+			"fn()2",
+		},
+		{
+			&CallExpression{
+				Token:     token.Token{Type: token.LPAREN, Literal: "("},
+				Function:  identifier("f"),
+				Arguments: []Expression{one(), two(), one()},
+			},
+			"f(2, 2, 2)",
+		},
+	}
+
+	for _, test := range tests {
+		modified := Modify(test.input, turnOneIntoTwo)
+
+		if modified.String() != test.expected {
+			t.Errorf("Modify() wrong. want=%q, got=%q", test.expected, modified.String())
+		}
+	}
+}
+
+func TestApplyListEditing(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			expression(identifier("a")),
+			expression(identifier("drop")),
+			expression(identifier("b")),
+		},
+	}
+
+	Apply(program, func(cursor *Cursor) bool {
+		statement, ok := cursor.Node().(*ExpressionStatement)
+		if !ok {
+			return true
+		}
+
+		switch statement.String() {
+		case "drop":
+			cursor.Delete()
+		case "a":
+			cursor.InsertBefore(expression(identifier("before")))
+		case "b":
+			cursor.InsertAfter(expression(identifier("after")))
+		}
+
+		return false
+	}, nil)
+
+	expected := []string{"before", "a", "b", "after"}
+
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("wrong number of statements. want=%d, got=%d (%s)",
+			len(expected), len(program.Statements), program.String())
+	}
+
+	for i, name := range expected {
+		if program.Statements[i].String() != name {
+			t.Errorf("Statements[%d] wrong. want=%q, got=%q",
+				i, name, program.Statements[i].String())
+		}
+	}
+}
+
+func TestApplyVisitsNewNeighboursOnce(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			expression(identifier("a")),
+			expression(identifier("b")),
+		},
+	}
+
+	visited := []string{}
+
+	Apply(program, func(cursor *Cursor) bool {
+		if _, ok := cursor.Node().(*ExpressionStatement); !ok {
+			return true
+		}
+
+		visited = append(visited, cursor.Node().String())
+
+		if cursor.Node().String() == "a" {
+			cursor.InsertAfter(expression(identifier("inserted")))
+		}
+
+		return false
+	}, nil)
+
+	if len(visited) != 2 || visited[0] != "a" || visited[1] != "b" {
+		t.Errorf("inserted nodes should be skipped. got=%v", visited)
+	}
+}
+
+func TestApplyWalksReplacementsOfPre(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{expression(identifier("a"))},
+	}
+
+	pre, post := []string{}, []string{}
+
+	Apply(program, func(cursor *Cursor) bool {
+		if _, ok := cursor.Node().(*ExpressionStatement); ok {
+			return true
+		}
+		pre = append(pre, cursor.Node().String())
+
+		// a -> (b + c): b and c are walked, post sees the replacement
+		if node, ok := cursor.Node().(*Identifier); ok && node.Value == "a" {
+			cursor.Replace(infix(identifier("b"), "+", identifier("c")))
+		}
+
+		return true
+	}, func(cursor *Cursor) bool {
+		if _, ok := cursor.Node().(*ExpressionStatement); ok {
+			return true
+		}
+		post = append(post, cursor.Node().String())
+
+		// replaced in post -> not walked again
+		if node, ok := cursor.Node().(*Identifier); ok && node.Value == "b" {
+			cursor.Replace(infix(identifier("d"), "*", identifier("e")))
+		}
+
+		return true
+	})
+
+	expectedPre  := fmt.Sprint([]string{"a", "a", "b", "c"})
+	expectedPost := fmt.Sprint([]string{"b", "c", "((d * e) + c)", "((d * e) + c)"})
+
+	if fmt.Sprint(pre) != expectedPre {
+		t.Errorf("pre visits wrong. want=%s, got=%v", expectedPre, pre)
+	}
+
+	if fmt.Sprint(post) != expectedPost {
+		t.Errorf("post visits wrong. want=%s, got=%v", expectedPost, post)
+	}
+}
+
+func TestApplyCursorPosition(t *testing.T) {
+	left  := identifier("x")
+	node  := infix(left, "*", integer(3))
+	root  := &Program{Statements: []Statement{expression(node)}}
+
+	Apply(root, func(cursor *Cursor) bool {
+		switch cursor.Node() {
+		case root:
+			if cursor.Parent() != nil || cursor.Index() != -1 {
+				t.Errorf("root cursor wrong. parent=%v index=%d",
+					cursor.Parent(), cursor.Index())
+			}
+		case root.Statements[0]:
+			if cursor.Name() != "Statements" || cursor.Index() != 0 {
+				t.Errorf("statement cursor wrong. name=%q index=%d",
+					cursor.Name(), cursor.Index())
+			}
+		case left:
+			if cursor.Parent() != node || cursor.Name() != "Left" {
+				t.Errorf("left cursor wrong. parent=%v name=%q",
+					cursor.Parent(), cursor.Name())
+			}
+		}
+
+		return true
+	}, nil)
+}
+
+func TestApplyReplaceRoot(t *testing.T) {
+	result := Apply(integer(1), nil, func(cursor *Cursor) bool {
+		cursor.Replace(identifier("x"))
+		return true
+	})
+
+	if result.String() != "x" {
+		t.Errorf("root not replaced. got=%q", result.String())
+	}
+}
+
+func TestApplySkipAndAbort(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			expression(infix(identifier("a"), "+", identifier("b"))),
+			expression(identifier("c")),
+		},
+	}
+
+	// pre returning false -> children skipped
+	visited := []string{}
+	Apply(program, func(cursor *Cursor) bool {
+		if identifier, ok := cursor.Node().(*Identifier); ok {
+			visited = append(visited, identifier.Value)
+		}
+
+		_, isInfix := cursor.Node().(*InfixExpression)
+		return !isInfix
+	}, nil)
+
+	if len(visited) != 1 || visited[0] != "c" {
+		t.Errorf("children of skipped node visited. got=%v", visited)
+	}
+
+	// post returning false -> traversal stops
+	visited = []string{}
+	Apply(program, nil, func(cursor *Cursor) bool {
+		if identifier, ok := cursor.Node().(*Identifier); ok {
+			visited = append(visited, identifier.Value)
+		}
+
+		return len(visited) < 2
+	})
+
+	if len(visited) != 2 || visited[1] != "b" {
+		t.Errorf("traversal did not stop. got=%v", visited)
+	}
+}
+
+func TestApplyReplaceWrongType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic replacing *Identifier field with a statement")
+		}
+	}()
+
+	let := &LetStatement{
+		Token: token.Token{Type: token.LET, Literal: "let"},
+		Name:  identifier("x"),
+		Value: integer(1),
+	}
+
+	Apply(let, func(cursor *Cursor) bool {
+		if cursor.Name() == "Name" {
+			cursor.Replace(expression(integer(1)))
+		}
+
+		return true
+	}, nil)
+}
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func identifier(name string) *Identifier {
+	return &Identifier{
+		Token: token.Token{Type: token.IDENT, Literal: name},
+		Value: name,
+	}
+}
+
+func integer(value int64) *IntegerLiteral {
+	literal := token.Token{Type: token.INT, Literal: fmt.Sprintf("%d", value)}
+	return &IntegerLiteral{Token: literal, Value: value}
+}
+
+func prefix(operator string, right Expression) *PrefixExpression {
+	return &PrefixExpression{
+		Token:    token.Token{Type: token.TokenType(operator), Literal: operator},
+		Operator: operator,
+		Right:    right,
+	}
+}
+
+func infix(left Expression, operator string, right Expression) *InfixExpression {
+	return &InfixExpression{
+		Token:    token.Token{Type: token.TokenType(operator), Literal: operator},
+		Left:     left,
+		Operator: operator,
+		Right:    right,
+	}
+}
+
+func expression(exp Expression) *ExpressionStatement {
+	return &ExpressionStatement{
+		Token:      token.Token{Literal: exp.TokenLiteral()},
+		Expression: exp,
+	}
+}
+
+func block(statements ...Statement) *BlockStatement {
+	return &BlockStatement{
+		Token:      token.Token{Type: token.LBRACE, Literal: "{"},
+		Statements: statements,
+	}
+}
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
@@ -23,6 +23,20 @@ type Expression interface {
 	expressionNode()
 }
 
+// Pattern is the target of a destructuring let, e.g. the `[a, b]` of
+// `let [a, b] = xs;`, or what a match arm compares its subject against
+type Pattern interface {
+	Node
+	patternNode()
+	Names() []*Identifier  // every name bound by the pattern, in source order
+}
+
+// TypeExpression is a type annotation, e.g. the `int` of `let x: int = 5;`
+type TypeExpression interface {
+	Node
+	typeExpressionNode()
+}
+
 //---[ Node Interfaces ]--------------------------------------------------------
 
 
@@ -50,8 +64,9 @@ func (program *Program) String() string {
 
 
 type Identifier struct {
-	Token token.Token  // token.IDENT
+	Token token.Token     // token.IDENT
 	Value string
+	Type  TypeExpression  // optional annotation of let names & parameters
 }
 
 func (identifier *Identifier) expressionNode() {}  // simplifies using identifiers as RHS values
@@ -61,6 +76,10 @@ func (identifier *Identifier) TokenLiteral() string {
 }
 
 func (identifier *Identifier) String() string {
+	if identifier.Type != nil {
+		return identifier.Value + ": " + identifier.Type.String()
+	}
+
 	return identifier.Value
 }
 
@@ -97,10 +116,124 @@ func (b *Boolean) String() string {
 }
 
 
+// NullLiteral is `null`, the absence of a value
+type NullLiteral struct {
+	Token token.Token  // token.NULL
+}
+
+func (nl *NullLiteral) expressionNode() {}
+
+func (nl *NullLiteral) TokenLiteral() string {
+	return nl.Token.Literal
+}
+
+func (nl *NullLiteral) String() string {
+	return "null"
+}
+
+
+// StringLiteral is text between double quotes, Value is without the quotes.
+type StringLiteral struct {
+	Token token.Token  // token.STRING
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode() {}
+
+func (sl *StringLiteral) TokenLiteral() string {
+	return sl.Token.Literal
+}
+
+func (sl *StringLiteral) String() string {
+	return "\"" + sl.Value + "\""
+}
+
+
+// TemplateLiteral is `text ${expression} text`. Strings holds the text around
+// the expressions, so it has one element more than Expressions (possibly "").
+type TemplateLiteral struct {
+	Token       token.Token  // token.TEMPLATE
+	Strings     []string
+	Expressions []Expression
+}
+
+func (tl *TemplateLiteral) expressionNode() {}
+
+func (tl *TemplateLiteral) TokenLiteral() string {
+	return tl.Token.Literal
+}
+
+func (tl *TemplateLiteral) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("`")
+	for i, text := range tl.Strings {
+		buffer.WriteString(text)
+
+		if i < len(tl.Expressions) {
+			buffer.WriteString("${")
+			buffer.WriteString(tl.Expressions[i].String())
+			buffer.WriteString("}")
+		}
+	}
+	buffer.WriteString("`")
+
+	return buffer.String()
+}
+
+
+// ArrayLiteral is `[a, b, c]`.
+type ArrayLiteral struct {
+	Token    token.Token  // token.LBRACKET
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+
+func (al *ArrayLiteral) TokenLiteral() string {
+	return al.Token.Literal
+}
+
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, element := range al.Elements {
+		elements = append(elements, element.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+
+// HashLiteral is `{key: value, ...}`, Keys[i] maps to Values[i]. Keys are
+// expressions like any other: `{name: 1}` uses the value of name as the key,
+// `{"name": 1}` the string. Entries keep the order they are written in.
+type HashLiteral struct {
+	Token  token.Token  // token.LBRACE
+	Keys   []Expression
+	Values []Expression
+}
+
+func (hl *HashLiteral) expressionNode() {}
+
+func (hl *HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+
+func (hl *HashLiteral) String() string {
+	entries := []string{}
+	for i, key := range hl.Keys {
+		entries = append(entries, key.String()+": "+hl.Values[i].String())
+	}
+
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+
 type LetStatement struct {
-	Token  token.Token  // should always be the token.LET token
-	Name  *Identifier   // variable used in binding
-	Value  Expression   // RHS produces value -> bind to variable
+	Token    token.Token  // should always be the token.LET token
+	Name    *Identifier   // variable used in binding
+	Pattern  Pattern      // destructuring target instead of Name (Name is nil then)
+	Value    Expression   // RHS produces value -> bind to variable
 }
 
 func (let *LetStatement) statementNode() {}
@@ -109,11 +242,30 @@ func (let *LetStatement) TokenLiteral() string {
 	return let.Token.Literal
 }
 
+// Names returns the variables bound by the let: its Name or those of its
+// Pattern.
+func (let *LetStatement) Names() []*Identifier {
+	if let.Pattern != nil {
+		return let.Pattern.Names()
+	}
+
+	if let.Name != nil {
+		return []*Identifier{let.Name}
+	}
+
+	return nil
+}
+
 func (let *LetStatement) String() string {
 	var buffer bytes.Buffer
 
 	buffer.WriteString(let.TokenLiteral() + " ")
-	buffer.WriteString(let.Name.String())
+
+	if let.Pattern != nil {
+		buffer.WriteString(let.Pattern.String())
+	} else if let.Name != nil {
+		buffer.WriteString(let.Name.String())
+	}
 	buffer.WriteString(" = ")
 
 	if let.Value != nil {
@@ -139,7 +291,7 @@ func (ret *ReturnStatement) TokenLiteral() string {
 func (ret *ReturnStatement) String() string {
 	var buffer bytes.Buffer
 
-	buffer.WriteString(ret.String() + " ")
+	buffer.WriteString(ret.TokenLiteral() + " ")
 
 	if ret.ReturnValue != nil {
 		buffer.WriteString(ret.ReturnValue.String())
@@ -150,6 +302,33 @@ func (ret *ReturnStatement) String() string {
 }
 
 
+// ThrowStatement raises Value as an error, to be caught by the innermost
+// enclosing try (in the current or a calling function).
+type ThrowStatement struct {
+	Token token.Token  // token.THROW
+	Value Expression
+}
+
+func (throw *ThrowStatement) statementNode() {}
+
+func (throw *ThrowStatement) TokenLiteral() string {
+	return throw.Token.Literal
+}
+
+func (throw *ThrowStatement) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString(throw.TokenLiteral() + " ")
+
+	if throw.Value != nil {
+		buffer.WriteString(throw.Value.String())
+	}
+
+	buffer.WriteString(";")
+	return buffer.String()
+}
+
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -240,6 +419,8 @@ func (block *BlockStatement) String() string {
 }
 
 
+// IfExpression: in an `else if` chain, Alternative is a block built by ElseIf
+// holding just the nested IfExpression.
 type IfExpression struct {
 	Token       token.Token
 	Condition   Expression
@@ -247,6 +428,33 @@ type IfExpression struct {
 	Alternative *BlockStatement
 }
 
+// ElseIf wraps nested as the alternative of an `else if`. The block takes the
+// nested `if` token, which is how String tells it from `else { if ... }`.
+func ElseIf(nested *IfExpression) *BlockStatement {
+	return &BlockStatement{
+		Token: nested.Token,
+		Statements: []Statement{
+			&ExpressionStatement{Token: nested.Token, Expression: nested},
+		},
+	}
+}
+
+// NextIf returns the nested IfExpression if the alternative is an `else if`.
+func (ifExp *IfExpression) NextIf() *IfExpression {
+	alternative := ifExp.Alternative
+	if alternative == nil || alternative.Token.Type != token.IF || len(alternative.Statements) != 1 {
+		return nil
+	}
+
+	statement, ok := alternative.Statements[0].(*ExpressionStatement)
+	if !ok {
+		return nil
+	}
+
+	nested, _ := statement.Expression.(*IfExpression)
+	return nested
+}
+
 func (ifExp *IfExpression) expressionNode() {}
 
 func (ifExp *IfExpression) TokenLiteral() string {
@@ -261,7 +469,10 @@ func (ifExp *IfExpression) String() string {
 	buffer.WriteString(" ")
 	buffer.WriteString(ifExp.Consequence.String())
 
-	if ifExp.Alternative != nil {
+	if nested := ifExp.NextIf(); nested != nil {
+		buffer.WriteString("else ")
+		buffer.WriteString(nested.String())
+	} else if ifExp.Alternative != nil {
 		buffer.WriteString("else")
 		buffer.WriteString(ifExp.Alternative.String())
 	}
@@ -270,12 +481,153 @@ func (ifExp *IfExpression) String() string {
 }
 
 
+// TryExpression evaluates Body. An error thrown meanwhile is bound to
+// Parameter and Handler evaluates instead, Finally runs in any case. At least
+// one of Handler and Finally is present; the value is Body's or Handler's.
+type TryExpression struct {
+	Token     token.Token  // token.TRY
+	Body      *BlockStatement
+	Parameter *Identifier  // the caught error, nil without a catch clause
+	Handler   *BlockStatement
+	Finally   *BlockStatement
+}
+
+func (try *TryExpression) expressionNode() {}
+
+func (try *TryExpression) TokenLiteral() string {
+	return try.Token.Literal
+}
+
+func (try *TryExpression) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("try ")
+	buffer.WriteString(try.Body.String())
+
+	if try.Handler != nil {
+		buffer.WriteString(" catch(" + try.Parameter.String() + ") ")
+		buffer.WriteString(try.Handler.String())
+	}
+
+	if try.Finally != nil {
+		buffer.WriteString(" finally ")
+		buffer.WriteString(try.Finally.String())
+	}
+
+	return buffer.String()
+}
+
+
+type WhileStatement struct {
+	Token     token.Token  // token.WHILE
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (while *WhileStatement) statementNode() {}
+
+func (while *WhileStatement) TokenLiteral() string {
+	return while.Token.Literal
+}
+
+func (while *WhileStatement) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("while")
+	buffer.WriteString(while.Condition.String())
+	buffer.WriteString(" ")
+	buffer.WriteString(while.Body.String())
+
+	return buffer.String()
+}
+
+
+// ForInStatement runs Body once per element of Iterable: `for (x in xs)`
+// binds the elements, `for (k, v in xs)` indices / keys and elements.
+type ForInStatement struct {
+	Token    token.Token  // token.FOR
+	Key      *Identifier  // nil for the single variable form
+	Value    *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (forIn *ForInStatement) statementNode() {}
+
+func (forIn *ForInStatement) TokenLiteral() string {
+	return forIn.Token.Literal
+}
+
+func (forIn *ForInStatement) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("for(")
+
+	if forIn.Key != nil {
+		buffer.WriteString(forIn.Key.String() + ", ")
+	}
+
+	buffer.WriteString(forIn.Value.String())
+	buffer.WriteString(" in ")
+	buffer.WriteString(forIn.Iterable.String())
+	buffer.WriteString(") ")
+	buffer.WriteString(forIn.Body.String())
+
+	return buffer.String()
+}
+
+
+// BreakStatement leaves the innermost loop
+type BreakStatement struct {
+	Token token.Token  // token.BREAK
+}
+
+func (brk *BreakStatement) statementNode() {}
+
+func (brk *BreakStatement) TokenLiteral() string {
+	return brk.Token.Literal
+}
+
+func (brk *BreakStatement) String() string {
+	return brk.TokenLiteral() + ";"
+}
+
+
+// ContinueStatement skips to the next iteration of the innermost loop
+type ContinueStatement struct {
+	Token token.Token  // token.CONTINUE
+}
+
+func (cont *ContinueStatement) statementNode() {}
+
+func (cont *ContinueStatement) TokenLiteral() string {
+	return cont.Token.Literal
+}
+
+func (cont *ContinueStatement) String() string {
+	return cont.TokenLiteral() + ";"
+}
+
+
 type FunctionLiteral struct {
 	Token      token.Token
+	Name       string  // declared or let-bound name, "" if anonymous
 	Parameters []*Identifier
+	Defaults   []Expression    // default value per parameter (nil: required), empty if none has one
+	Variadic   bool            // last parameter is `...rest`, collecting the extra arguments
+	ReturnType TypeExpression  // optional `-> type` annotation
 	Body       *BlockStatement
 }
 
+// Default returns the default value of the i-th parameter, nil if it has none.
+func (fl *FunctionLiteral) Default(i int) Expression {
+	if i >= len(fl.Defaults) {
+		return nil
+	}
+
+	return fl.Defaults[i]
+}
+
 func (fl *FunctionLiteral) expressionNode() {}
 
 func (fl *FunctionLiteral) TokenLiteral() string {
@@ -286,16 +638,712 @@ func (fl *FunctionLiteral) String() string {
 	var buffer bytes.Buffer
 
 	parameters := []string{}
-	for _, param := range fl.Parameters {
-		parameters = append(parameters, param.String())
+	for i, param := range fl.Parameters {
+		parameter := param.String()
+
+		if value := fl.Default(i); value != nil {
+			parameter += " = " + value.String()
+		}
+
+		if fl.Variadic && i == len(fl.Parameters)-1 {
+			parameter = "..." + parameter
+		}
+
+		parameters = append(parameters, parameter)
 	}
 
 	buffer.WriteString(fl.TokenLiteral())
 	buffer.WriteString("(")
 	buffer.WriteString(strings.Join(parameters, ", "))
 	buffer.WriteString(")")
+
+	if fl.ReturnType != nil {
+		buffer.WriteString(" -> " + fl.ReturnType.String() + " ")
+	}
+
 	buffer.WriteString(fl.Body.String())
 
 	return buffer.String()
 }
 
+
+
+// MacroLiteral is `macro(params) { body }`. Its calls receive their arguments
+// unevaluated (as quoted ASTs) and are replaced by the AST the body returns,
+// see package macro
+type MacroLiteral struct {
+	Token      token.Token  // token.MACRO
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode() {}
+
+func (ml *MacroLiteral) TokenLiteral() string {
+	return ml.Token.Literal
+}
+
+func (ml *MacroLiteral) String() string {
+	var buffer bytes.Buffer
+
+	parameters := []string{}
+	for _, param := range ml.Parameters {
+		parameters = append(parameters, param.String())
+	}
+
+	buffer.WriteString(ml.TokenLiteral())
+	buffer.WriteString("(")
+	buffer.WriteString(strings.Join(parameters, ", "))
+	buffer.WriteString(")")
+	buffer.WriteString(ml.Body.String())
+
+	return buffer.String()
+}
+
+
+// FunctionDeclaration is `fn name(params) { body }` as a statement. The name
+// is hoisted: it is visible in the whole enclosing Program or BlockStatement.
+type FunctionDeclaration struct {
+	Token    token.Token  // token.FUNCTION
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (fd *FunctionDeclaration) statementNode() {}
+
+func (fd *FunctionDeclaration) TokenLiteral() string {
+	return fd.Token.Literal
+}
+
+func (fd *FunctionDeclaration) String() string {
+	// the literal without its leading `fn`
+	literal := strings.TrimPrefix(fd.Function.String(), fd.Function.TokenLiteral())
+
+	return fd.TokenLiteral() + " " + fd.Name.String() + literal
+}
+
+
+// ImportStatement binds the names exported by the module at Path to Name:
+// `import "lib/math.mk" as math;`
+type ImportStatement struct {
+	Token token.Token  // token.IMPORT
+	Path  string       // as written, without the quotes
+	Name  *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+
+func (is *ImportStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " \"" + is.Path + "\" as " + is.Name.String() + ";"
+}
+
+
+// ExportStatement makes the names declared by Statement (a LetStatement or
+// FunctionDeclaration) visible to modules importing this one.
+type ExportStatement struct {
+	Token     token.Token  // token.EXPORT
+	Statement Statement
+}
+
+func (es *ExportStatement) statementNode() {}
+
+func (es *ExportStatement) TokenLiteral() string {
+	return es.Token.Literal
+}
+
+// Names returns the exported names.
+func (es *ExportStatement) Names() []*Identifier {
+	switch declaration := es.Statement.(type) {
+	case *LetStatement:
+		return declaration.Names()
+	case *FunctionDeclaration:
+		return []*Identifier{declaration.Name}
+	}
+
+	return nil
+}
+
+func (es *ExportStatement) String() string {
+	return es.TokenLiteral() + " " + es.Statement.String()
+}
+
+// Unexported returns the statement wrapped by an ExportStatement, any other
+// statement as is.
+func Unexported(statement Statement) Statement {
+	if export, ok := statement.(*ExportStatement); ok {
+		return export.Statement
+	}
+
+	return statement
+}
+
+
+// CallTo returns the only argument of node if it is a call `name(argument)`,
+// e.g. the x of `unquote(x)`, nil for anything else
+func CallTo(node Node, name string) Expression {
+	arguments, ok := CallArguments(node, name)
+	if !ok || len(arguments) != 1 {
+		return nil
+	}
+
+	return arguments[0]
+}
+
+// CallArguments returns the arguments of node if it is a call of the
+// function called name.
+func CallArguments(node Node, name string) ([]Expression, bool) {
+	call, ok := node.(*CallExpression)
+	if !ok {
+		return nil, false
+	}
+
+	if function, ok := call.Function.(*Identifier); !ok || function.Value != name {
+		return nil, false
+	}
+
+	return call.Arguments, true
+}
+
+
+// CallExpression written as `x |> f(y)` holds x as its first argument and
+// the |> token, so it is still printed as a pipeline
+type CallExpression struct {
+	Token     token.Token  // token.LPAREN, token.PIPE for a piped call
+	Function  Expression   // Identifier or FunctionLiteral
+	Arguments []Expression
+}
+
+func (call *CallExpression) expressionNode() {}
+
+func (call *CallExpression) TokenLiteral() string {
+	return call.Token.Literal
+}
+
+// IsPiped reports whether the call was written as `first |> f(rest)`.
+func (call *CallExpression) IsPiped() bool {
+	return call.Token.Type == token.PIPE && len(call.Arguments) > 0
+}
+
+func (call *CallExpression) String() string {
+	var buffer bytes.Buffer
+
+	arguments := call.Arguments
+	if call.IsPiped() {
+		arguments = arguments[1:]
+
+		buffer.WriteString("(")
+		buffer.WriteString(call.Arguments[0].String())
+		buffer.WriteString(" |> ")
+	}
+
+	printed := []string{}
+	for _, argument := range arguments {
+		printed = append(printed, argument.String())
+	}
+
+	buffer.WriteString(call.Function.String())
+	buffer.WriteString("(")
+	buffer.WriteString(strings.Join(printed, ", "))
+	buffer.WriteString(")")
+
+	if call.IsPiped() {
+		buffer.WriteString(")")
+	}
+
+	return buffer.String()
+}
+
+
+
+// IndexExpression is `left[index]`, or `left?[index]` (Optional), which is
+// null if left is null.
+type IndexExpression struct {
+	Token    token.Token  // token.LBRACKET or token.OPTIONAL_LBRACKET
+	Left     Expression
+	Index    Expression
+	Optional bool
+}
+
+func (index *IndexExpression) expressionNode() {}
+
+func (index *IndexExpression) TokenLiteral() string {
+	return index.Token.Literal
+}
+
+func (index *IndexExpression) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("(")
+	buffer.WriteString(index.Left.String())
+	if index.Optional {
+		buffer.WriteString("?")
+	}
+	buffer.WriteString("[")
+	buffer.WriteString(index.Index.String())
+	buffer.WriteString("])")
+
+	return buffer.String()
+}
+
+
+// RangeExpression is `start..end` (end excluded) or `start..=end` (Inclusive).
+// Start or End is nil in an open slice bound like `xs[..5]` or `xs[1..]`.
+//
+// As an index it slices arrays and strings: a negative bound counts from the
+// end (-1 is the last element), a missing start is 0 and a missing end the
+// length, bounds beyond either end are clamped, and a start at or after the
+// end gives an empty slice.
+type RangeExpression struct {
+	Token     token.Token  // token.RANGE or token.RANGE_INCLUSIVE
+	Start     Expression
+	End       Expression
+	Inclusive bool
+}
+
+func (rng *RangeExpression) expressionNode() {}
+
+func (rng *RangeExpression) TokenLiteral() string {
+	return rng.Token.Literal
+}
+
+func (rng *RangeExpression) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("(")
+	if rng.Start != nil {
+		buffer.WriteString(rng.Start.String())
+	}
+
+	if rng.Inclusive {
+		buffer.WriteString("..=")
+	} else {
+		buffer.WriteString("..")
+	}
+
+	if rng.End != nil {
+		buffer.WriteString(rng.End.String())
+	}
+	buffer.WriteString(")")
+
+	return buffer.String()
+}
+
+
+// MemberExpression is `object.property`: a field of a hash (sugar for
+// object["property"]), a name exported by an imported module, or, when
+// called, a method of the object's type: `"abc".len()`. Written
+// `object?.property` (Optional), it is null if object is null.
+type MemberExpression struct {
+	Token    token.Token  // token.DOT or token.OPTIONAL_DOT
+	Object   Expression
+	Property *Identifier
+	Optional bool
+}
+
+func (member *MemberExpression) expressionNode() {}
+
+func (member *MemberExpression) TokenLiteral() string {
+	return member.Token.Literal
+}
+
+func (member *MemberExpression) String() string {
+	if member.Optional {
+		return member.Object.String() + "?." + member.Property.String()
+	}
+
+	return member.Object.String() + "." + member.Property.String()
+}
+
+
+// AssignExpression stores Value in Target (an Identifier, IndexExpression or
+// MemberExpression).
+// Compound operators (+=, -=, *=, /=) combine the old value with Value first.
+type AssignExpression struct {
+	Token    token.Token  // the operator token
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (assign *AssignExpression) expressionNode() {}
+
+func (assign *AssignExpression) TokenLiteral() string {
+	return assign.Token.Literal
+}
+
+func (assign *AssignExpression) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("(")
+	buffer.WriteString(assign.Target.String())
+	buffer.WriteString(" " + assign.Operator + " ")
+	buffer.WriteString(assign.Value.String())
+	buffer.WriteString(")")
+
+	return buffer.String()
+}
+
+
+// MatchExpression picks the first arm whose pattern matches the subject
+// (and whose guard holds) and evaluates to its body
+type MatchExpression struct {
+	Token   token.Token  // token.MATCH
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (match *MatchExpression) expressionNode() {}
+
+func (match *MatchExpression) TokenLiteral() string {
+	return match.Token.Literal
+}
+
+func (match *MatchExpression) String() string {
+	var buffer bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range match.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	buffer.WriteString("match")
+	buffer.WriteString(match.Subject.String())
+	buffer.WriteString(" { ")
+	buffer.WriteString(strings.Join(arms, ", "))
+	buffer.WriteString(" }")
+
+	return buffer.String()
+}
+
+// HasCatchAll reports whether some arm matches every value: an unguarded
+// wildcard or plain name.
+func (match *MatchExpression) HasCatchAll() bool {
+	for _, arm := range match.Arms {
+		if arm.Guard != nil {
+			continue
+		}
+
+		switch arm.Pattern.(type) {
+		case *WildcardPattern, *Identifier:
+			return true
+		}
+	}
+
+	return false
+}
+
+
+// MatchArm is `pattern if guard => body`, an expression body is wrapped in a
+// block holding a single ExpressionStatement
+type MatchArm struct {
+	Token   token.Token  // first token of the pattern
+	Pattern Pattern
+	Guard   Expression   // nil without `if`
+	Body    *BlockStatement
+}
+
+func (arm *MatchArm) TokenLiteral() string {
+	return arm.Token.Literal
+}
+
+func (arm *MatchArm) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString(arm.Pattern.String())
+
+	if arm.Guard != nil {
+		buffer.WriteString(" if " + arm.Guard.String())
+	}
+
+	buffer.WriteString(" => ")
+	buffer.WriteString(arm.Body.String())
+
+	return buffer.String()
+}
+
+
+// Comprehension is `[value for x in xs if filter]` or, with a Key, the hash
+// `{key: value for (k, v) in h}`. Generators nest left to right like loops,
+// each sees the variables of the ones before it.
+//
+// Elements are produced one at a time: an iterable is walked, never copied,
+// so `[x for x in 0..1000000 if x < 10]` holds no array of a million ints.
+type Comprehension struct {
+	Token      token.Token  // token.LBRACKET or token.LBRACE
+	Key        Expression   // nil for an array comprehension
+	Value      Expression
+	Generators []*Generator
+}
+
+func (comprehension *Comprehension) expressionNode() {}
+
+func (comprehension *Comprehension) TokenLiteral() string {
+	return comprehension.Token.Literal
+}
+
+func (comprehension *Comprehension) String() string {
+	var buffer bytes.Buffer
+
+	if comprehension.Key != nil {
+		buffer.WriteString("{" + comprehension.Key.String() + ": ")
+	} else {
+		buffer.WriteString("[")
+	}
+
+	buffer.WriteString(comprehension.Value.String())
+	for _, generator := range comprehension.Generators {
+		buffer.WriteString(" " + generator.String())
+	}
+
+	if comprehension.Key != nil {
+		buffer.WriteString("}")
+	} else {
+		buffer.WriteString("]")
+	}
+
+	return buffer.String()
+}
+
+
+// Generator is the `for x in xs if a if b` of a Comprehension: elements
+// failing one of the Filters are skipped.
+type Generator struct {
+	Token    token.Token  // token.FOR
+	Key      *Identifier  // nil for the single variable form
+	Value    *Identifier
+	Iterable Expression
+	Filters  []Expression
+}
+
+func (generator *Generator) TokenLiteral() string {
+	return generator.Token.Literal
+}
+
+func (generator *Generator) String() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("for ")
+	if generator.Key != nil {
+		buffer.WriteString("(" + generator.Key.String() + ", " + generator.Value.String() + ")")
+	} else {
+		buffer.WriteString(generator.Value.String())
+	}
+
+	buffer.WriteString(" in " + generator.Iterable.String())
+
+	for _, filter := range generator.Filters {
+		buffer.WriteString(" if " + filter.String())
+	}
+
+	return buffer.String()
+}
+
+
+//---[ Patterns ]---------------------------------------------------------------
+
+// a plain name matches anything and binds it
+func (identifier *Identifier) patternNode() {}
+
+func (identifier *Identifier) Names() []*Identifier {
+	return []*Identifier{identifier}
+}
+
+
+// WildcardPattern `_` matches anything without binding it
+type WildcardPattern struct {
+	Token token.Token  // token.IDENT `_`
+}
+
+func (wp *WildcardPattern) patternNode() {}
+
+func (wp *WildcardPattern) TokenLiteral() string {
+	return wp.Token.Literal
+}
+
+func (wp *WildcardPattern) Names() []*Identifier {
+	return nil
+}
+
+func (wp *WildcardPattern) String() string {
+	return "_"
+}
+
+
+// LiteralPattern matches values equal to an integer (possibly negated),
+// boolean, string or null literal
+type LiteralPattern struct {
+	Token token.Token  // first token of Value
+	Value Expression   // *IntegerLiteral, -*IntegerLiteral, *Boolean, *StringLiteral or *NullLiteral
+}
+
+func (lp *LiteralPattern) patternNode() {}
+
+func (lp *LiteralPattern) TokenLiteral() string {
+	return lp.Token.Literal
+}
+
+func (lp *LiteralPattern) Names() []*Identifier {
+	return nil
+}
+
+func (lp *LiteralPattern) String() string {
+	// -1 rather than the (-1) of a prefix expression
+	if prefix, ok := lp.Value.(*PrefixExpression); ok {
+		return prefix.Operator + prefix.Right.String()
+	}
+
+	return lp.Value.String()
+}
+
+
+// ArrayPattern matches arrays element by element, the Rest name gets the
+// remaining ones: [a, b, ...rest]. Without Rest the lengths must agree.
+type ArrayPattern struct {
+	Token    token.Token  // token.LBRACKET
+	Elements []Pattern
+	Rest     *Identifier  // nil without `...rest`
+}
+
+func (ap *ArrayPattern) patternNode() {}
+
+func (ap *ArrayPattern) TokenLiteral() string {
+	return ap.Token.Literal
+}
+
+func (ap *ArrayPattern) Names() []*Identifier {
+	names := []*Identifier{}
+	for _, element := range ap.Elements {
+		names = append(names, element.Names()...)
+	}
+
+	if ap.Rest != nil {
+		names = append(names, ap.Rest)
+	}
+
+	return names
+}
+
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, element := range ap.Elements {
+		elements = append(elements, element.String())
+	}
+
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+
+// HashPattern matches hashes having all of its (string) keys. A key without
+// value pattern binds the value to a variable of the same name: {name, age},
+// otherwise the value has to match: {kind: 1, name: n}. Keys that are no
+// names are written as strings: {"full name": n}
+type HashPattern struct {
+	Token  token.Token    // token.LBRACE
+	Keys   []*Identifier  // Token is a token.STRING for keys written as strings
+	Values []Pattern      // parallel to Keys, nil entries for `{name}` shorthand
+}
+
+func (hp *HashPattern) patternNode() {}
+
+func (hp *HashPattern) TokenLiteral() string {
+	return hp.Token.Literal
+}
+
+// Value returns the pattern for the i-th key, the key itself for shorthands.
+func (hp *HashPattern) Value(i int) Pattern {
+	if i < len(hp.Values) && hp.Values[i] != nil {
+		return hp.Values[i]
+	}
+
+	return hp.Keys[i]
+}
+
+func (hp *HashPattern) Names() []*Identifier {
+	names := []*Identifier{}
+	for i := range hp.Keys {
+		names = append(names, hp.Value(i).Names()...)
+	}
+
+	return names
+}
+
+func (hp *HashPattern) String() string {
+	entries := []string{}
+	for i, key := range hp.Keys {
+		if key.Token.Type == token.STRING {
+			entries = append(entries, "\""+key.Value+"\": "+hp.Value(i).String())
+		} else if value := hp.Value(i); value != Pattern(key) {
+			entries = append(entries, key.String()+": "+value.String())
+		} else {
+			entries = append(entries, key.String())
+		}
+	}
+
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+//---[ Patterns ]---------------------------------------------------------------
+
+
+//---[ Type Annotations ]-------------------------------------------------------
+
+// NamedType is a type referred to by name: int, bool, null
+type NamedType struct {
+	Token token.Token  // token.IDENT
+	Name  string
+}
+
+func (named *NamedType) typeExpressionNode() {}
+
+func (named *NamedType) TokenLiteral() string {
+	return named.Token.Literal
+}
+
+func (named *NamedType) String() string {
+	return named.Name
+}
+
+
+// FunctionType is written like a function literal without body and parameter
+// names, e.g. fn(int, bool) -> int
+type FunctionType struct {
+	Token      token.Token  // token.FUNCTION
+	Parameters []TypeExpression
+	Result     TypeExpression
+}
+
+func (ft *FunctionType) typeExpressionNode() {}
+
+func (ft *FunctionType) TokenLiteral() string {
+	return ft.Token.Literal
+}
+
+func (ft *FunctionType) String() string {
+	var buffer bytes.Buffer
+
+	parameters := []string{}
+	for _, param := range ft.Parameters {
+		parameters = append(parameters, param.String())
+	}
+
+	buffer.WriteString(ft.TokenLiteral())
+	buffer.WriteString("(")
+	buffer.WriteString(strings.Join(parameters, ", "))
+	buffer.WriteString(") -> ")
+
+	if ft.Result != nil {
+		buffer.WriteString(ft.Result.String())
+	}
+
+	return buffer.String()
+}
+
+//---[ Type Annotations ]-------------------------------------------------------
@@ -0,0 +1,620 @@
+package ast
+
+import (
+	"fmt"
+)
+
+//---[ Module API Functions ]---------------------------------------------------
+
+// Equal reports whether a and b are structurally identical. Tokens (and with
+// them source positions) are ignored, only node types and values count.
+func Equal(a, b Node) bool {
+	return len(Diff(a, b)) == 0
+}
+
+// Diff lists the structural differences between a and b, one entry per
+// mismatch, e.g. `Statements[2].Expression.Right.Operator: "+" vs "*"`.
+func Diff(a, b Node) []string {
+	differ := &differ{}
+	differ.node("", a, b)
+
+	return differ.diffs
+}
+
+// Clone returns a deep copy of node. The copy shares no nodes with the
+// original, so either may be rewritten without affecting the other.
+func Clone(node Node) Node {
+	if isNil(node) {
+		return nil
+	}
+
+	switch original := node.(type) {
+	case *Program:
+		return &Program{
+			Statements: cloneList(original.Statements),
+		}
+
+	case *Identifier:
+		copied     := *original
+		copied.Type = cloneAs[TypeExpression](original.Type)
+		return &copied
+
+	case *IntegerLiteral:
+		copied := *original
+		return &copied
+
+	case *Boolean:
+		copied := *original
+		return &copied
+
+	case *StringLiteral:
+		copied := *original
+		return &copied
+
+	case *NullLiteral:
+		copied := *original
+		return &copied
+
+	case *TemplateLiteral:
+		return &TemplateLiteral{
+			Token:       original.Token,
+			Strings:     append([]string{}, original.Strings...),
+			Expressions: cloneList(original.Expressions),
+		}
+
+	case *ArrayLiteral:
+		return &ArrayLiteral{
+			Token:    original.Token,
+			Elements: cloneList(original.Elements),
+		}
+
+	case *HashLiteral:
+		return &HashLiteral{
+			Token:  original.Token,
+			Keys:   cloneList(original.Keys),
+			Values: cloneList(original.Values),
+		}
+
+	case *LetStatement:
+		return &LetStatement{
+			Token:   original.Token,
+			Name:    cloneAs[*Identifier](original.Name),
+			Pattern: cloneAs[Pattern](original.Pattern),
+			Value:   cloneAs[Expression](original.Value),
+		}
+
+	case *ArrayPattern:
+		return &ArrayPattern{
+			Token:    original.Token,
+			Elements: cloneList(original.Elements),
+			Rest:     cloneAs[*Identifier](original.Rest),
+		}
+
+	case *HashPattern:
+		return &HashPattern{
+			Token:  original.Token,
+			Keys:   cloneList(original.Keys),
+			Values: cloneList(original.Values),
+		}
+
+	case *WildcardPattern:
+		copied := *original
+		return &copied
+
+	case *LiteralPattern:
+		return &LiteralPattern{
+			Token: original.Token,
+			Value: cloneAs[Expression](original.Value),
+		}
+
+	case *MatchExpression:
+		return &MatchExpression{
+			Token:   original.Token,
+			Subject: cloneAs[Expression](original.Subject),
+			Arms:    cloneList(original.Arms),
+		}
+
+	case *Comprehension:
+		return &Comprehension{
+			Token:      original.Token,
+			Key:        cloneAs[Expression](original.Key),
+			Value:      cloneAs[Expression](original.Value),
+			Generators: cloneList(original.Generators),
+		}
+
+	case *Generator:
+		return &Generator{
+			Token:    original.Token,
+			Key:      cloneAs[*Identifier](original.Key),
+			Value:    cloneAs[*Identifier](original.Value),
+			Iterable: cloneAs[Expression](original.Iterable),
+			Filters:  cloneList(original.Filters),
+		}
+
+	case *MatchArm:
+		return &MatchArm{
+			Token:   original.Token,
+			Pattern: cloneAs[Pattern](original.Pattern),
+			Guard:   cloneAs[Expression](original.Guard),
+			Body:    cloneAs[*BlockStatement](original.Body),
+		}
+
+	case *ReturnStatement:
+		return &ReturnStatement{
+			Token:       original.Token,
+			ReturnValue: cloneAs[Expression](original.ReturnValue),
+		}
+
+	case *ThrowStatement:
+		return &ThrowStatement{
+			Token: original.Token,
+			Value: cloneAs[Expression](original.Value),
+		}
+
+	case *TryExpression:
+		return &TryExpression{
+			Token:     original.Token,
+			Body:      cloneAs[*BlockStatement](original.Body),
+			Parameter: cloneAs[*Identifier](original.Parameter),
+			Handler:   cloneAs[*BlockStatement](original.Handler),
+			Finally:   cloneAs[*BlockStatement](original.Finally),
+		}
+
+	case *ExpressionStatement:
+		return &ExpressionStatement{
+			Token:      original.Token,
+			Expression: cloneAs[Expression](original.Expression),
+		}
+
+	case *BlockStatement:
+		return &BlockStatement{
+			Token:      original.Token,
+			Statements: cloneList(original.Statements),
+		}
+
+	case *PrefixExpression:
+		return &PrefixExpression{
+			Token:    original.Token,
+			Operator: original.Operator,
+			Right:    cloneAs[Expression](original.Right),
+		}
+
+	case *InfixExpression:
+		return &InfixExpression{
+			Token:    original.Token,
+			Left:     cloneAs[Expression](original.Left),
+			Operator: original.Operator,
+			Right:    cloneAs[Expression](original.Right),
+		}
+
+	case *IfExpression:
+		return &IfExpression{
+			Token:       original.Token,
+			Condition:   cloneAs[Expression](original.Condition),
+			Consequence: cloneAs[*BlockStatement](original.Consequence),
+			Alternative: cloneAs[*BlockStatement](original.Alternative),
+		}
+
+	case *WhileStatement:
+		return &WhileStatement{
+			Token:     original.Token,
+			Condition: cloneAs[Expression](original.Condition),
+			Body:      cloneAs[*BlockStatement](original.Body),
+		}
+
+	case *ForInStatement:
+		return &ForInStatement{
+			Token:    original.Token,
+			Key:      cloneAs[*Identifier](original.Key),
+			Value:    cloneAs[*Identifier](original.Value),
+			Iterable: cloneAs[Expression](original.Iterable),
+			Body:     cloneAs[*BlockStatement](original.Body),
+		}
+
+	case *BreakStatement:
+		copied := *original
+		return &copied
+
+	case *ContinueStatement:
+		copied := *original
+		return &copied
+
+	case *FunctionLiteral:
+		return &FunctionLiteral{
+			Token:      original.Token,
+			Name:       original.Name,
+			Parameters: cloneList(original.Parameters),
+			Defaults:   cloneList(original.Defaults),
+			Variadic:   original.Variadic,
+			ReturnType: cloneAs[TypeExpression](original.ReturnType),
+			Body:       cloneAs[*BlockStatement](original.Body),
+		}
+
+	case *FunctionDeclaration:
+		return &FunctionDeclaration{
+			Token:    original.Token,
+			Name:     cloneAs[*Identifier](original.Name),
+			Function: cloneAs[*FunctionLiteral](original.Function),
+		}
+
+	case *ImportStatement:
+		return &ImportStatement{
+			Token: original.Token,
+			Path:  original.Path,
+			Name:  cloneAs[*Identifier](original.Name),
+		}
+
+	case *ExportStatement:
+		return &ExportStatement{
+			Token:     original.Token,
+			Statement: cloneAs[Statement](original.Statement),
+		}
+
+	case *MacroLiteral:
+		return &MacroLiteral{
+			Token:      original.Token,
+			Parameters: cloneList(original.Parameters),
+			Body:       cloneAs[*BlockStatement](original.Body),
+		}
+
+	case *CallExpression:
+		return &CallExpression{
+			Token:     original.Token,
+			Function:  cloneAs[Expression](original.Function),
+			Arguments: cloneList(original.Arguments),
+		}
+
+	case *IndexExpression:
+		return &IndexExpression{
+			Token:    original.Token,
+			Left:     cloneAs[Expression](original.Left),
+			Index:    cloneAs[Expression](original.Index),
+			Optional: original.Optional,
+		}
+
+	case *RangeExpression:
+		return &RangeExpression{
+			Token:     original.Token,
+			Start:     cloneAs[Expression](original.Start),
+			End:       cloneAs[Expression](original.End),
+			Inclusive: original.Inclusive,
+		}
+
+	case *MemberExpression:
+		return &MemberExpression{
+			Token:    original.Token,
+			Object:   cloneAs[Expression](original.Object),
+			Property: cloneAs[*Identifier](original.Property),
+			Optional: original.Optional,
+		}
+
+	case *AssignExpression:
+		return &AssignExpression{
+			Token:    original.Token,
+			Target:   cloneAs[Expression](original.Target),
+			Operator: original.Operator,
+			Value:    cloneAs[Expression](original.Value),
+		}
+
+	case *NamedType:
+		copied := *original
+		return &copied
+
+	case *FunctionType:
+		return &FunctionType{
+			Token:      original.Token,
+			Parameters: cloneList(original.Parameters),
+			Result:     cloneAs[TypeExpression](original.Result),
+		}
+	}
+
+	panic(fmt.Sprintf("ast: Clone: unexpected node type %T", node))
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Clone Helper Functions ]-------------------------------------------------
+
+func cloneAs[T Node](node T) T {
+	return mustBe[T](Clone(node))
+}
+
+func cloneList[T Node](list []T) []T {
+	if list == nil {
+		return nil
+	}
+
+	copied := make([]T, len(list))
+	for i, element := range list {
+		copied[i] = cloneAs(element)
+	}
+
+	return copied
+}
+
+//---[ Clone Helper Functions ]-------------------------------------------------
+
+
+//---[ Diff Helper Methods ]----------------------------------------------------
+
+type differ struct {
+	diffs []string
+}
+
+func (differ *differ) report(path string, a, b string) {
+	if path == "" {
+		path = "(root)"
+	}
+
+	differ.diffs = append(differ.diffs, fmt.Sprintf("%s: %s vs %s", path, a, b))
+}
+
+func (differ *differ) value(path string, a, b any) {
+	if a != b {
+		differ.report(path, describeValue(a), describeValue(b))
+	}
+}
+
+func (differ *differ) node(path string, a, b Node) {
+	aNil, bNil := isNil(a), isNil(b)
+
+	switch {
+	case aNil && bNil:
+		return
+	case aNil || bNil:
+		differ.report(path, describeNode(a), describeNode(b))
+		return
+	case fmt.Sprintf("%T", a) != fmt.Sprintf("%T", b):
+		differ.report(path, fmt.Sprintf("%T", a), fmt.Sprintf("%T", b))
+		return
+	}
+
+	switch left := a.(type) {
+	case *Program:
+		right := b.(*Program)
+		diffList(differ, join(path, "Statements"), left.Statements, right.Statements)
+
+	case *Identifier:
+		right := b.(*Identifier)
+		differ.value(join(path, "Value"), left.Value, right.Value)
+		differ.node(join(path, "Type"), left.Type, right.Type)
+
+	case *IntegerLiteral:
+		differ.value(join(path, "Value"), left.Value, b.(*IntegerLiteral).Value)
+
+	case *Boolean:
+		differ.value(join(path, "Value"), left.Value, b.(*Boolean).Value)
+
+	case *StringLiteral:
+		differ.value(join(path, "Value"), left.Value, b.(*StringLiteral).Value)
+
+	case *NullLiteral:
+		// nothing but the type
+
+	case *TemplateLiteral:
+		right := b.(*TemplateLiteral)
+		differ.value(join(path, "Strings"), fmt.Sprintf("%q", left.Strings), fmt.Sprintf("%q", right.Strings))
+		diffList(differ, join(path, "Expressions"), left.Expressions, right.Expressions)
+
+	case *ArrayLiteral:
+		diffList(differ, join(path, "Elements"), left.Elements, b.(*ArrayLiteral).Elements)
+
+	case *HashLiteral:
+		right := b.(*HashLiteral)
+		diffList(differ, join(path, "Keys"), left.Keys, right.Keys)
+		diffList(differ, join(path, "Values"), left.Values, right.Values)
+
+	case *LetStatement:
+		right := b.(*LetStatement)
+		differ.node(join(path, "Name"), left.Name, right.Name)
+		differ.node(join(path, "Pattern"), left.Pattern, right.Pattern)
+		differ.node(join(path, "Value"), left.Value, right.Value)
+
+	case *ArrayPattern:
+		right := b.(*ArrayPattern)
+		diffList(differ, join(path, "Elements"), left.Elements, right.Elements)
+		differ.node(join(path, "Rest"), left.Rest, right.Rest)
+
+	case *HashPattern:
+		right := b.(*HashPattern)
+		diffList(differ, join(path, "Keys"), left.Keys, right.Keys)
+		diffList(differ, join(path, "Values"), left.Values, right.Values)
+
+	case *WildcardPattern:
+		// nothing but the type
+
+	case *LiteralPattern:
+		differ.node(join(path, "Value"), left.Value, b.(*LiteralPattern).Value)
+
+	case *MatchExpression:
+		right := b.(*MatchExpression)
+		differ.node(join(path, "Subject"), left.Subject, right.Subject)
+		diffList(differ, join(path, "Arms"), left.Arms, right.Arms)
+
+	case *Comprehension:
+		right := b.(*Comprehension)
+		differ.node(join(path, "Key"), left.Key, right.Key)
+		differ.node(join(path, "Value"), left.Value, right.Value)
+		diffList(differ, join(path, "Generators"), left.Generators, right.Generators)
+
+	case *Generator:
+		right := b.(*Generator)
+		differ.node(join(path, "Key"), left.Key, right.Key)
+		differ.node(join(path, "Value"), left.Value, right.Value)
+		differ.node(join(path, "Iterable"), left.Iterable, right.Iterable)
+		diffList(differ, join(path, "Filters"), left.Filters, right.Filters)
+
+	case *MatchArm:
+		right := b.(*MatchArm)
+		differ.node(join(path, "Pattern"), left.Pattern, right.Pattern)
+		differ.node(join(path, "Guard"), left.Guard, right.Guard)
+		differ.node(join(path, "Body"), left.Body, right.Body)
+
+	case *ReturnStatement:
+		right := b.(*ReturnStatement)
+		differ.node(join(path, "ReturnValue"), left.ReturnValue, right.ReturnValue)
+
+	case *ThrowStatement:
+		right := b.(*ThrowStatement)
+		differ.node(join(path, "Value"), left.Value, right.Value)
+
+	case *TryExpression:
+		right := b.(*TryExpression)
+		differ.node(join(path, "Body"), left.Body, right.Body)
+		differ.node(join(path, "Parameter"), left.Parameter, right.Parameter)
+		differ.node(join(path, "Handler"), left.Handler, right.Handler)
+		differ.node(join(path, "Finally"), left.Finally, right.Finally)
+
+	case *ExpressionStatement:
+		right := b.(*ExpressionStatement)
+		differ.node(join(path, "Expression"), left.Expression, right.Expression)
+
+	case *BlockStatement:
+		right := b.(*BlockStatement)
+		diffList(differ, join(path, "Statements"), left.Statements, right.Statements)
+
+	case *PrefixExpression:
+		right := b.(*PrefixExpression)
+		differ.value(join(path, "Operator"), left.Operator, right.Operator)
+		differ.node(join(path, "Right"), left.Right, right.Right)
+
+	case *InfixExpression:
+		right := b.(*InfixExpression)
+		differ.node(join(path, "Left"), left.Left, right.Left)
+		differ.value(join(path, "Operator"), left.Operator, right.Operator)
+		differ.node(join(path, "Right"), left.Right, right.Right)
+
+	case *IfExpression:
+		right := b.(*IfExpression)
+		differ.node(join(path, "Condition"), left.Condition, right.Condition)
+		differ.node(join(path, "Consequence"), left.Consequence, right.Consequence)
+		differ.node(join(path, "Alternative"), left.Alternative, right.Alternative)
+
+	case *WhileStatement:
+		right := b.(*WhileStatement)
+		differ.node(join(path, "Condition"), left.Condition, right.Condition)
+		differ.node(join(path, "Body"), left.Body, right.Body)
+
+	case *ForInStatement:
+		right := b.(*ForInStatement)
+		differ.node(join(path, "Key"), left.Key, right.Key)
+		differ.node(join(path, "Value"), left.Value, right.Value)
+		differ.node(join(path, "Iterable"), left.Iterable, right.Iterable)
+		differ.node(join(path, "Body"), left.Body, right.Body)
+
+	case *BreakStatement, *ContinueStatement:
+		// nothing but the type
+
+	case *FunctionLiteral:
+		right := b.(*FunctionLiteral)
+		differ.value(join(path, "Name"), left.Name, right.Name)
+		diffList(differ, join(path, "Parameters"), left.Parameters, right.Parameters)
+		diffList(differ, join(path, "Defaults"), left.Defaults, right.Defaults)
+		differ.value(join(path, "Variadic"), left.Variadic, right.Variadic)
+		differ.node(join(path, "ReturnType"), left.ReturnType, right.ReturnType)
+		differ.node(join(path, "Body"), left.Body, right.Body)
+
+	case *FunctionDeclaration:
+		right := b.(*FunctionDeclaration)
+		differ.node(join(path, "Name"), left.Name, right.Name)
+		differ.node(join(path, "Function"), left.Function, right.Function)
+
+	case *ImportStatement:
+		right := b.(*ImportStatement)
+		differ.value(join(path, "Path"), left.Path, right.Path)
+		differ.node(join(path, "Name"), left.Name, right.Name)
+
+	case *ExportStatement:
+		right := b.(*ExportStatement)
+		differ.node(join(path, "Statement"), left.Statement, right.Statement)
+
+	case *MacroLiteral:
+		right := b.(*MacroLiteral)
+		diffList(differ, join(path, "Parameters"), left.Parameters, right.Parameters)
+		differ.node(join(path, "Body"), left.Body, right.Body)
+
+	case *CallExpression:
+		right := b.(*CallExpression)
+		differ.node(join(path, "Function"), left.Function, right.Function)
+		diffList(differ, join(path, "Arguments"), left.Arguments, right.Arguments)
+
+	case *IndexExpression:
+		right := b.(*IndexExpression)
+		differ.node(join(path, "Left"), left.Left, right.Left)
+		differ.node(join(path, "Index"), left.Index, right.Index)
+		differ.value(join(path, "Optional"), left.Optional, right.Optional)
+
+	case *RangeExpression:
+		right := b.(*RangeExpression)
+		differ.node(join(path, "Start"), left.Start, right.Start)
+		differ.node(join(path, "End"), left.End, right.End)
+		differ.value(join(path, "Inclusive"), left.Inclusive, right.Inclusive)
+
+	case *MemberExpression:
+		right := b.(*MemberExpression)
+		differ.node(join(path, "Object"), left.Object, right.Object)
+		differ.node(join(path, "Property"), left.Property, right.Property)
+		differ.value(join(path, "Optional"), left.Optional, right.Optional)
+
+	case *AssignExpression:
+		right := b.(*AssignExpression)
+		differ.node(join(path, "Target"), left.Target, right.Target)
+		differ.value(join(path, "Operator"), left.Operator, right.Operator)
+		differ.node(join(path, "Value"), left.Value, right.Value)
+
+	case *NamedType:
+		differ.value(join(path, "Name"), left.Name, b.(*NamedType).Name)
+
+	case *FunctionType:
+		right := b.(*FunctionType)
+		diffList(differ, join(path, "Parameters"), left.Parameters, right.Parameters)
+		differ.node(join(path, "Result"), left.Result, right.Result)
+
+	default:
+		panic(fmt.Sprintf("ast: Diff: unexpected node type %T", a))
+	}
+}
+
+func diffList[T Node](differ *differ, path string, a, b []T) {
+	common := min(len(a), len(b))
+
+	for i := 0; i < common; i++ {
+		differ.node(fmt.Sprintf("%s[%d]", path, i), a[i], b[i])
+	}
+
+	if len(a) != len(b) {
+		differ.report(
+			path,
+			fmt.Sprintf("%d elements", len(a)),
+			fmt.Sprintf("%d elements", len(b)),
+		)
+	}
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+
+	return path + "." + field
+}
+
+// strings are quoted so "+" vs "*" reads unambiguously
+func describeValue(value any) string {
+	if text, ok := value.(string); ok {
+		return fmt.Sprintf("%q", text)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+func describeNode(node Node) string {
+	if isNil(node) {
+		return "nil"
+	}
+
+	return fmt.Sprintf("%T(%s)", node, node.String())
+}
+
+//---[ Diff Helper Methods ]----------------------------------------------------
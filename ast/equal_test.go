@@ -0,0 +1,144 @@
+package ast
+
+import (
+	"testing"
+
+	"monkey/token"
+)
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func TestEqualIgnoresTokens(t *testing.T) {
+	a := infix(identifier("x"), "+", integer(1))
+	b := infix(identifier("x"), "+", integer(1))
+	b.Token = token.Token{Type: token.PLUS, Literal: "+"}
+
+	if !Equal(a, b) {
+		t.Errorf("Equal() = false for structurally equal nodes. diff=%v", Diff(a, b))
+	}
+
+	if Equal(a, infix(identifier("x"), "-", integer(1))) {
+		t.Errorf("Equal() = true for different operators")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	program := func(last Expression) *Program {
+		return &Program{
+			Statements: []Statement{
+				expression(identifier("a")),
+				expression(identifier("b")),
+				expression(last),
+			},
+		}
+	}
+
+	tests := []struct{
+		a        Node
+		b        Node
+		expected []string
+	}{
+		{
+			program(infix(integer(1), "+", integer(2))),
+			program(infix(integer(1), "*", integer(2))),
+			[]string{`Statements[2].Expression.Operator: "+" vs "*"`},
+		},
+		{
+			program(infix(integer(1), "+", prefix("-", integer(2)))),
+			program(infix(integer(1), "+", prefix("!", integer(3)))),
+			[]string{
+				`Statements[2].Expression.Right.Operator: "-" vs "!"`,
+				`Statements[2].Expression.Right.Right.Value: 2 vs 3`,
+			},
+		},
+		{
+			program(identifier("c")),
+			program(integer(3)),
+			[]string{`Statements[2].Expression: *ast.Identifier vs *ast.IntegerLiteral`},
+		},
+		{
+			block(expression(identifier("a"))),
+			block(),
+			[]string{`Statements: 1 elements vs 0 elements`},
+		},
+		{
+			&IfExpression{Condition: identifier("x"), Consequence: block()},
+			&IfExpression{Condition: identifier("x"), Consequence: block(), Alternative: block()},
+			[]string{`Alternative: nil vs *ast.BlockStatement()`},
+		},
+		{
+			identifier("x"),
+			identifier("y"),
+			[]string{`Value: "x" vs "y"`},
+		},
+		{
+			&Identifier{Value: "f", Type: &FunctionType{Parameters: []TypeExpression{&NamedType{Name: "int"}}, Result: &NamedType{Name: "int"}}},
+			&Identifier{Value: "f", Type: &FunctionType{Parameters: []TypeExpression{&NamedType{Name: "bool"}}, Result: &NamedType{Name: "int"}}},
+			[]string{`Type.Parameters[0].Name: "int" vs "bool"`},
+		},
+	}
+
+	for i, test := range tests {
+		diffs := Diff(test.a, test.b)
+
+		if len(diffs) != len(test.expected) {
+			t.Errorf("tests[%d] - wrong number of diffs. want=%q, got=%q",
+				i, test.expected, diffs)
+			continue
+		}
+
+		for j, expected := range test.expected {
+			if diffs[j] != expected {
+				t.Errorf("tests[%d] - diffs[%d] wrong. want=%q, got=%q",
+					i, j, expected, diffs[j])
+			}
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	original := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name:  identifier("add"),
+				Value: &FunctionLiteral{
+					Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+					Parameters: []*Identifier{identifier("a"), identifier("b")},
+					Body:       block(expression(infix(identifier("a"), "+", identifier("b")))),
+				},
+			},
+			expression(&IfExpression{
+				Token:       token.Token{Type: token.IF, Literal: "if"},
+				Condition:   &Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true},
+				Consequence: block(expression(integer(1))),
+			}),
+		},
+	}
+
+	cloned := Clone(original).(*Program)
+
+	if diffs := Diff(original, cloned); len(diffs) != 0 {
+		t.Fatalf("clone differs from original: %v", diffs)
+	}
+
+	if cloned.String() != original.String() {
+		t.Errorf("clone.String() wrong. want=%q, got=%q", original.String(), cloned.String())
+	}
+
+	// rewriting the clone must leave the original untouched
+	Modify(cloned, func(node Node) Node {
+		if identifier, ok := node.(*Identifier); ok {
+			identifier.Value = "renamed"
+		}
+
+		return node
+	})
+
+	let := original.Statements[0].(*LetStatement)
+	if let.Name.Value != "add" || let.Value.(*FunctionLiteral).Parameters[0].Value != "a" {
+		t.Errorf("original modified through clone: %s", original.String())
+	}
+}
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
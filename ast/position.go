@@ -0,0 +1,110 @@
+package ast
+
+import (
+	"monkey/token"
+)
+
+// Pos returns where node starts in the source. Operators are stored as the
+// token of infix expressions, so their start is taken from the left operand.
+func Pos(node Node) token.Position {
+	if isNil(node) {
+		return token.Position{}
+	}
+
+	switch typed := node.(type) {
+	case *Program:
+		if len(typed.Statements) > 0 {
+			return Pos(typed.Statements[0])
+		}
+	case *Identifier:
+		return typed.Token.Position
+	case *IntegerLiteral:
+		return typed.Token.Position
+	case *Boolean:
+		return typed.Token.Position
+	case *StringLiteral:
+		return typed.Token.Position
+	case *NullLiteral:
+		return typed.Token.Position
+	case *TemplateLiteral:
+		return typed.Token.Position
+	case *ArrayLiteral:
+		return typed.Token.Position
+	case *HashLiteral:
+		return typed.Token.Position
+	case *LetStatement:
+		return typed.Token.Position
+	case *ArrayPattern:
+		return typed.Token.Position
+	case *HashPattern:
+		return typed.Token.Position
+	case *WildcardPattern:
+		return typed.Token.Position
+	case *LiteralPattern:
+		return typed.Token.Position
+	case *MatchExpression:
+		return typed.Token.Position
+	case *MatchArm:
+		return typed.Token.Position
+	case *Comprehension:
+		return typed.Token.Position
+	case *Generator:
+		return typed.Token.Position
+	case *ReturnStatement:
+		return typed.Token.Position
+	case *ThrowStatement:
+		return typed.Token.Position
+	case *TryExpression:
+		return typed.Token.Position
+	case *ExpressionStatement:
+		return typed.Token.Position
+	case *BlockStatement:
+		return typed.Token.Position
+	case *PrefixExpression:
+		return typed.Token.Position
+	case *InfixExpression:
+		return Pos(typed.Left)
+	case *IfExpression:
+		return typed.Token.Position
+	case *WhileStatement:
+		return typed.Token.Position
+	case *ForInStatement:
+		return typed.Token.Position
+	case *BreakStatement:
+		return typed.Token.Position
+	case *ContinueStatement:
+		return typed.Token.Position
+	case *FunctionLiteral:
+		return typed.Token.Position
+	case *FunctionDeclaration:
+		return typed.Token.Position
+	case *ImportStatement:
+		return typed.Token.Position
+	case *ExportStatement:
+		return typed.Token.Position
+	case *MacroLiteral:
+		return typed.Token.Position
+	case *CallExpression:
+		if typed.IsPiped() {
+			return Pos(typed.Arguments[0])
+		}
+		return Pos(typed.Function)
+	case *IndexExpression:
+		return Pos(typed.Left)
+	case *RangeExpression:
+		if typed.Start != nil {
+			return Pos(typed.Start)
+		}
+		return typed.Token.Position
+	case *MemberExpression:
+		return Pos(typed.Object)
+	case *AssignExpression:
+		return Pos(typed.Target)
+	case *NamedType:
+		return typed.Token.Position
+	case *FunctionType:
+		return typed.Token.Position
+	}
+
+	return token.Position{}
+}
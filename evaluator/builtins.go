@@ -0,0 +1,104 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"monkey/object"
+	"monkey/typecheck"
+)
+
+// builtin is a function every program can call without declaring it,
+// together with its type for the typechecker
+type builtin struct {
+	function *object.Builtin
+	scheme   *typecheck.Scheme
+}
+
+// BuiltinTypes returns the types of the builtins, the predeclared names of
+// typecheck.Check for programs run by the evaluator.
+func BuiltinTypes() map[string]*typecheck.Scheme {
+	types := map[string]*typecheck.Scheme{}
+	for name, builtin := range builtins {
+		types[name] = builtin.scheme
+	}
+
+	return types
+}
+
+var builtins = map[string]builtin{
+	// puts(a, b) -> prints a and b (strings without quotes) separated by a space
+	"puts": {
+		scheme: generic(func(a typecheck.Type) typecheck.Type {
+			return typecheck.VariadicFunction(nil, a, typecheck.Null)
+		}),
+		function: &object.Builtin{
+			Name: "puts",
+			Fn: func(args ...object.Object) (object.Object, error) {
+				texts := []string{}
+				for _, arg := range args {
+					texts = append(texts, object.Text(arg))
+				}
+
+				fmt.Println(strings.Join(texts, " "))
+				return NULL, nil
+			},
+		},
+	},
+	// len(x) -> number of bytes of a string, elements of an array / range, entries of a hash
+	"len": {
+		scheme: generic(func(a typecheck.Type) typecheck.Type {
+			return typecheck.Function([]typecheck.Type{a}, typecheck.Int)
+		}),
+		function: &object.Builtin{
+			Name: "len",
+			Fn: func(args ...object.Object) (object.Object, error) {
+				if len(args) != 1 {
+					return nil, fmt.Errorf("wrong number of arguments to len: want 1, got %d", len(args))
+				}
+
+				switch arg := args[0].(type) {
+				case *object.String:
+					return &object.Integer{Value: int64(len(arg.Value))}, nil
+				case *object.Array:
+					return &object.Integer{Value: int64(len(arg.Elements))}, nil
+				case *object.Hash:
+					return &object.Integer{Value: int64(len(arg.Order))}, nil
+				case *object.Range:
+					return &object.Integer{Value: arg.Len()}, nil
+				}
+
+				return nil, fmt.Errorf("len: unsupported argument %s", args[0].Type())
+			},
+		},
+	},
+}
+
+// generic returns the Scheme of the type build makes from a type variable a,
+// generalized over a: forall a. build(a)
+func generic(build func(a typecheck.Type) typecheck.Type) *typecheck.Scheme {
+	a := typecheck.NewVariable()
+	return &typecheck.Scheme{Generic: []*typecheck.TypeVariable{a}, Type: build(a)}
+}
+
+// method is a builtin called as receiver.name(args)
+type method struct {
+	parameters int  // not counting the receiver
+	fn         func(receiver object.Object, args []object.Object) object.Object
+}
+
+// methods by the type of their receiver (see the typechecker's Methods)
+var methods = map[object.ObjectType]map[string]method{
+	object.STRING_OBJ: {
+		// s.len() -> number of bytes
+		"len": {fn: func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Integer{Value: int64(len(receiver.(*object.String).Value))}
+		}},
+	},
+	object.ARRAY_OBJ: {
+		// xs.len() -> number of elements
+		"len": {fn: func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Integer{Value: int64(len(receiver.(*object.Array).Elements))}
+		}},
+	},
+}
@@ -0,0 +1,1311 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"monkey/ast"
+	"monkey/module"
+	"monkey/object"
+	"monkey/token"
+)
+
+var (
+	NULL  = &object.Null{}
+	TRUE  = &object.Boolean{Value: true}
+	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
+)
+
+// calls nested deeper than this are a runtime error rather than a crash
+const maxCallDepth = 10000
+
+
+//---[ Module API Functions ]---------------------------------------------------
+
+// Eval runs program in env and returns the value of its last statement. A
+// runtime error stops the program, the result is an *object.Exception then.
+//
+// Values other than false and null are truthy. Every block, loop iteration
+// and call runs in an environment of its own: names declared inside are gone
+// afterwards.
+func Eval(program *ast.Program, env *object.Environment) object.Object {
+	evaluator := &evaluator{}
+
+	result := evaluator.statements(env, program.Statements)
+	if returned, ok := result.(*object.ReturnValue); ok {
+		return returned.Value
+	}
+
+	return result
+}
+
+// EvalModule runs the top level of mod, after the modules it imports (each
+// only once), and returns the value of its last statement as Eval does.
+// Runtime errors carry the path of the module they happened in.
+//
+// An import binds the *object.Module of the imported module, whose exported
+// names are read as members: `import "math.mk" as math; math.square(2)`.
+// Macros have to be expanded before, see package macro.
+func EvalModule(mod *module.Module) object.Object {
+	evaluator := &evaluator{modules: make(map[*module.Module]*object.Module)}
+
+	_, result := evaluator.evalModule(mod)
+	if returned, ok := result.(*object.ReturnValue); ok {
+		return returned.Value
+	}
+
+	return result
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Evaluator Helper Methods ]-----------------------------------------------
+
+type evaluator struct {
+	frames  []object.Frame                     // calls being evaluated, outermost first
+	path    string                             // file of the code being evaluated, "" outside modules
+	module  *module.Module                     // whose top level is being evaluated, nil outside modules
+	modules map[*module.Module]*object.Module  // evaluated so far
+}
+
+// runs the top level of mod unless that happened before: its module object,
+// and the value of its last statement (or the signal stopping it)
+func (evaluator *evaluator) evalModule(mod *module.Module) (*object.Module, object.Object) {
+	if evaluated, ok := evaluator.modules[mod]; ok {
+		return evaluated, NULL
+	}
+
+	evaluated := &object.Module{
+		Path:    mod.Path,
+		Env:     object.NewEnvironment(),
+		Exports: make(map[string]bool),
+	}
+
+	for name := range mod.Exports {
+		evaluated.Exports[name] = true
+	}
+
+	outerPath, outerModule := evaluator.path, evaluator.module
+	evaluator.path, evaluator.module = mod.Path, mod
+
+	result := evaluator.statements(evaluated.Env, mod.Program.Statements)
+
+	evaluator.path, evaluator.module = outerPath, outerModule
+	evaluator.modules[mod] = evaluated
+
+	return evaluated, result
+}
+
+// runs statements in env, stopping at the first signal. Function
+// declarations are bound first, so they can be called from anywhere in env
+func (evaluator *evaluator) statements(env *object.Environment, statements []ast.Statement) object.Object {
+	for _, statement := range statements {
+		if declaration := declaredFunction(statement); declaration != nil {
+			env.Set(declaration.Name.Value, &object.Function{Literal: declaration.Function, Env: env, Path: evaluator.path})
+		}
+	}
+
+	var result object.Object = NULL
+
+	for _, statement := range statements {
+		result = evaluator.statement(env, statement)
+
+		if isSignal(result) {
+			return result
+		}
+	}
+
+	return result
+}
+
+func (evaluator *evaluator) block(env *object.Environment, block *ast.BlockStatement) object.Object {
+	return evaluator.statements(object.NewEnclosedEnvironment(env), block.Statements)
+}
+
+func (evaluator *evaluator) statement(env *object.Environment, statement ast.Statement) object.Object {
+	switch node := statement.(type) {
+	case *ast.ExpressionStatement:
+		return evaluator.expression(env, node.Expression)
+
+	case *ast.LetStatement:
+		value := evaluator.expression(env, node.Value)
+		if isSignal(value) {
+			return value
+		}
+
+		if node.Pattern != nil {
+			if !evaluator.matchPattern(env, node.Pattern, value) {
+				return evaluator.errorAt(ast.Pos(node.Pattern), "pattern %s does not match %s", node.Pattern, value.Inspect())
+			}
+
+			return NULL
+		}
+
+		env.Set(node.Name.Value, value)
+		return NULL
+
+	case *ast.FunctionDeclaration:
+		// bound when the statements around it started
+		return NULL
+
+	case *ast.ImportStatement:
+		if evaluator.module == nil {
+			return evaluator.errorAt(node.Token.Position, "cannot import %q outside of a module", node.Path)
+		}
+
+		imported, result := evaluator.evalModule(evaluator.module.Imports[node.Name.Value])
+		if exception, ok := result.(*object.Exception); ok {
+			return exception
+		}
+
+		env.Set(node.Name.Value, imported)
+		return NULL
+
+	case *ast.ExportStatement:
+		return evaluator.statement(env, node.Statement)
+
+	case *ast.ReturnStatement:
+		value := evaluator.expression(env, node.ReturnValue)
+		if isSignal(value) {
+			return value
+		}
+
+		return &object.ReturnValue{Value: value}
+
+	case *ast.BlockStatement:
+		return evaluator.block(env, node)
+
+	case *ast.WhileStatement:
+		return evaluator.while(env, node)
+
+	case *ast.ForInStatement:
+		return evaluator.forIn(env, node)
+
+	case *ast.ThrowStatement:
+		value := evaluator.expression(env, node.Value)
+		if isSignal(value) {
+			return value
+		}
+
+		// rethrowing a caught error keeps where it came from
+		if err, ok := value.(*object.Error); ok {
+			return &object.Exception{Error: err}
+		}
+
+		return evaluator.throw(node.Token.Position, object.Text(value), value)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+	}
+
+	return evaluator.errorAt(ast.Pos(statement), "cannot evaluate %s", statement)
+}
+
+func (evaluator *evaluator) expression(env *object.Environment, expression ast.Expression) object.Object {
+	switch node := expression.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: node.Value}
+
+	case *ast.Boolean:
+		return nativeBool(node.Value)
+
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+
+	case *ast.NullLiteral:
+		return NULL
+
+	case *ast.TemplateLiteral:
+		return evaluator.template(env, node)
+
+	case *ast.ArrayLiteral:
+		elements, signal := evaluator.expressions(env, node.Elements)
+		if signal != nil {
+			return signal
+		}
+
+		return &object.Array{Elements: elements}
+
+	case *ast.HashLiteral:
+		return evaluator.hash(env, node)
+
+	case *ast.Comprehension:
+		return evaluator.comprehension(env, node)
+
+	case *ast.Identifier:
+		if value, ok := env.Get(node.Value); ok {
+			return value
+		}
+
+		if builtin, ok := builtins[node.Value]; ok {
+			return builtin.function
+		}
+
+		return evaluator.errorAt(node.Token.Position, "undefined: %s", node.Value)
+
+	case *ast.PrefixExpression:
+		right := evaluator.expression(env, node.Right)
+		if isSignal(right) {
+			return right
+		}
+
+		return evaluator.prefix(node, right)
+
+	case *ast.InfixExpression:
+		left := evaluator.expression(env, node.Left)
+		if isSignal(left) {
+			return left
+		}
+
+		// a ?? b: b is only evaluated if a is null
+		if node.Operator == "??" {
+			if left != NULL {
+				return left
+			}
+
+			return evaluator.expression(env, node.Right)
+		}
+
+		right := evaluator.expression(env, node.Right)
+		if isSignal(right) {
+			return right
+		}
+
+		return evaluator.infix(node.Token.Position, node.Operator, left, right)
+
+	case *ast.IfExpression:
+		condition := evaluator.expression(env, node.Condition)
+		if isSignal(condition) {
+			return condition
+		}
+
+		switch {
+		case isTruthy(condition):
+			return evaluator.block(env, node.Consequence)
+		case node.Alternative != nil:
+			return evaluator.block(env, node.Alternative)
+		}
+
+		return NULL
+
+	case *ast.FunctionLiteral:
+		return &object.Function{Literal: node, Env: env, Path: evaluator.path}
+
+	case *ast.CallExpression:
+		if arguments, ok := ast.CallArguments(node, "quote"); ok && len(arguments) > 0 {
+			return evaluator.quote(env, arguments[0])
+		}
+
+		if ast.CallTo(node, "unquote") != nil {
+			return evaluator.errorAt(ast.Pos(node), "unquote outside of quote")
+		}
+
+		if member, ok := node.Function.(*ast.MemberExpression); ok {
+			return evaluator.callMember(env, node, member)
+		}
+
+		function := evaluator.expression(env, node.Function)
+		if isSignal(function) {
+			return function
+		}
+
+		arguments, signal := evaluator.expressions(env, node.Arguments)
+		if signal != nil {
+			return signal
+		}
+
+		return evaluator.apply(node, function, arguments)
+
+	case *ast.IndexExpression:
+		left := evaluator.expression(env, node.Left)
+		if isSignal(left) || (node.Optional && left == NULL) {
+			return left
+		}
+
+		if rng, ok := node.Index.(*ast.RangeExpression); ok {
+			return evaluator.slice(env, node, left, rng)
+		}
+
+		index := evaluator.expression(env, node.Index)
+		if isSignal(index) {
+			return index
+		}
+
+		return evaluator.index(node, left, index)
+
+	case *ast.MemberExpression:
+		receiver := evaluator.expression(env, node.Object)
+		if isSignal(receiver) || (node.Optional && receiver == NULL) {
+			return receiver
+		}
+
+		return evaluator.member(node, receiver)
+
+	case *ast.RangeExpression:
+		bounds, signal := evaluator.bounds(env, node, 0)
+		if signal != nil {
+			return signal
+		}
+
+		return &object.Range{Start: bounds[0], End: bounds[1], Inclusive: node.Inclusive}
+
+	case *ast.AssignExpression:
+		return evaluator.assign(env, node)
+
+	case *ast.MatchExpression:
+		return evaluator.match(env, node)
+
+	case *ast.TryExpression:
+		return evaluator.try(env, node)
+
+	case *ast.MacroLiteral:
+		// the ones bound by top-level lets were removed by macro.DefineMacros
+		return evaluator.errorAt(node.Token.Position, "macro must be bound by a top-level let")
+	}
+
+	return evaluator.errorAt(ast.Pos(expression), "cannot evaluate %s", expression)
+}
+
+// the values of expressions in order, or the signal stopping one of them
+func (evaluator *evaluator) expressions(env *object.Environment, expressions []ast.Expression) ([]object.Object, object.Object) {
+	values := []object.Object{}
+
+	for _, expression := range expressions {
+		value := evaluator.expression(env, expression)
+		if isSignal(value) {
+			return nil, value
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// the text of a template, with each expression's value as puts prints it
+func (evaluator *evaluator) template(env *object.Environment, template *ast.TemplateLiteral) object.Object {
+	var text strings.Builder
+
+	for i, expression := range template.Expressions {
+		value := evaluator.expression(env, expression)
+		if isSignal(value) {
+			return value
+		}
+
+		text.WriteString(template.Strings[i])
+		text.WriteString(object.Text(value))
+	}
+
+	text.WriteString(template.Strings[len(template.Strings)-1])
+
+	return &object.String{Value: text.String()}
+}
+
+func (evaluator *evaluator) hash(env *object.Environment, node *ast.HashLiteral) object.Object {
+	hash := object.NewHash()
+
+	for i, keyNode := range node.Keys {
+		key := evaluator.expression(env, keyNode)
+		if isSignal(key) {
+			return key
+		}
+
+		hashable, ok := key.(object.Hashable)
+		if !ok {
+			return evaluator.errorAt(ast.Pos(keyNode), "unusable as hash key: %s", key.Type())
+		}
+
+		value := evaluator.expression(env, node.Values[i])
+		if isSignal(value) {
+			return value
+		}
+
+		hash.Set(hashable, value)
+	}
+
+	return hash
+}
+
+// xs[i] / s[i] -> the element (a one byte string), null if i is out of
+// range; a negative i counts from the end. xs[r] / s[r] for a range r -> the
+// slice. h[key] -> the value of key, null if h has none
+func (evaluator *evaluator) index(node *ast.IndexExpression, left, index object.Object) object.Object {
+	if rng, ok := index.(*object.Range); ok {
+		if slice := sliceOf(left, rng.Start, rng.End, rng.Inclusive); slice != nil {
+			return slice
+		}
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		if i, ok := index.(*object.Integer); ok {
+			position, ok := elementIndex(i.Value, len(left.Elements))
+			if !ok {
+				return NULL
+			}
+
+			return left.Elements[position]
+		}
+
+	case *object.String:
+		if i, ok := index.(*object.Integer); ok {
+			position, ok := elementIndex(i.Value, len(left.Value))
+			if !ok {
+				return NULL
+			}
+
+			return &object.String{Value: left.Value[position : position+1]}
+		}
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return evaluator.errorAt(ast.Pos(node.Index), "unusable as hash key: %s", index.Type())
+		}
+
+		if value, ok := left.Get(key); ok {
+			return value
+		}
+
+		return NULL
+	}
+
+	return evaluator.errorAt(node.Token.Position, "cannot index %s with %s", left.Type(), index.Type())
+}
+
+// x = v updates the nearest binding of x, xs[i] = v an element of an array
+// (in range) or an entry of a hash. The result is the stored value
+func (evaluator *evaluator) assign(env *object.Environment, assign *ast.AssignExpression) object.Object {
+	switch target := assign.Target.(type) {
+	case *ast.Identifier:
+		current, ok := env.Get(target.Value)
+		if !ok {
+			return evaluator.errorAt(target.Token.Position, "undefined: %s", target.Value)
+		}
+
+		value := evaluator.assignedValue(env, assign, current)
+		if isSignal(value) {
+			return value
+		}
+
+		env.Assign(target.Value, value)
+		return value
+
+	case *ast.IndexExpression:
+		left := evaluator.expression(env, target.Left)
+		if isSignal(left) {
+			return left
+		}
+
+		index := evaluator.expression(env, target.Index)
+		if isSignal(index) {
+			return index
+		}
+
+		var current object.Object
+		if assign.Operator != "=" {
+			current = evaluator.index(target, left, index)
+			if isSignal(current) {
+				return current
+			}
+		}
+
+		value := evaluator.assignedValue(env, assign, current)
+		if isSignal(value) {
+			return value
+		}
+
+		return evaluator.setIndex(target, left, index, value)
+
+	case *ast.MemberExpression:
+		receiver := evaluator.expression(env, target.Object)
+		if isSignal(receiver) {
+			return receiver
+		}
+
+		hash, ok := receiver.(*object.Hash)
+		if !ok {
+			return evaluator.errorAt(target.Property.Token.Position, "cannot assign to field %s of %s",
+				target.Property.Value, receiver.Type())
+		}
+
+		var current object.Object
+		if assign.Operator != "=" {
+			current = evaluator.member(target, hash)
+		}
+
+		value := evaluator.assignedValue(env, assign, current)
+		if isSignal(value) {
+			return value
+		}
+
+		hash.Set(&object.String{Value: target.Property.Value}, value)
+		return value
+	}
+
+	return evaluator.errorAt(ast.Pos(assign.Target), "cannot assign to %s", assign.Target)
+}
+
+// the value assign stores: its Value, for compound operators combined with
+// the current value of the target first
+func (evaluator *evaluator) assignedValue(env *object.Environment, assign *ast.AssignExpression, current object.Object) object.Object {
+	value := evaluator.expression(env, assign.Value)
+	if isSignal(value) || assign.Operator == "=" {
+		return value
+	}
+
+	return evaluator.infix(assign.Token.Position, strings.TrimSuffix(assign.Operator, "="), current, value)
+}
+
+func (evaluator *evaluator) setIndex(node *ast.IndexExpression, left, index, value object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		if i, ok := index.(*object.Integer); ok {
+			position, ok := elementIndex(i.Value, len(left.Elements))
+			if !ok {
+				return evaluator.errorAt(ast.Pos(node.Index), "index %d out of range for length %d", i.Value, len(left.Elements))
+			}
+
+			left.Elements[position] = value
+			return value
+		}
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return evaluator.errorAt(ast.Pos(node.Index), "unusable as hash key: %s", index.Type())
+		}
+
+		left.Set(key, value)
+		return value
+	}
+
+	return evaluator.errorAt(node.Token.Position, "cannot assign to %s index %s", left.Type(), index.Type())
+}
+
+// h.name -> h["name"] (null if h has no such entry), m.name -> the value
+// module m exports as name, e.message / e.value / e.stack of an error (the
+// stack as "f at line:col" strings)
+func (evaluator *evaluator) member(member *ast.MemberExpression, receiver object.Object) object.Object {
+	name := member.Property.Value
+
+	switch receiver := receiver.(type) {
+	case *object.Hash:
+		if value, ok := receiver.Get(&object.String{Value: name}); ok {
+			return value
+		}
+
+		return NULL
+
+	case *object.Module:
+		if value, ok := receiver.Export(name); ok {
+			return value
+		}
+
+		return evaluator.errorAt(member.Property.Token.Position, "%s does not export %s", receiver.Inspect(), name)
+
+	case *object.Error:
+		switch name {
+		case "message":
+			return &object.String{Value: receiver.Message}
+		case "value":
+			return receiver.Value
+		case "stack":
+			frames := []object.Object{}
+			for _, frame := range receiver.Stack {
+				frames = append(frames, &object.String{Value: frame.String()})
+			}
+
+			return &object.Array{Elements: frames}
+		}
+	}
+
+	return evaluator.errorAt(member.Property.Token.Position, "%s has no field %s", receiver.Type(), name)
+}
+
+// receiver.name(args): calls the function stored in a hash / exported by a
+// module, or else the method name of the receiver's type
+func (evaluator *evaluator) callMember(env *object.Environment, call *ast.CallExpression, member *ast.MemberExpression) object.Object {
+	// x?.f(args) is null without evaluating args if x is null
+	receiver := evaluator.expression(env, member.Object)
+	if isSignal(receiver) || (member.Optional && receiver == NULL) {
+		return receiver
+	}
+
+	var function object.Object
+
+	switch receiver.(type) {
+	case *object.Hash, *object.Module:
+		function = evaluator.member(member, receiver)
+		if isSignal(function) {
+			return function
+		}
+	}
+
+	arguments, signal := evaluator.expressions(env, call.Arguments)
+	if signal != nil {
+		return signal
+	}
+
+	if function != nil {
+		return evaluator.apply(call, function, arguments)
+	}
+
+	method, ok := methods[receiver.Type()][member.Property.Value]
+	if !ok {
+		return evaluator.errorAt(member.Property.Token.Position, "%s has no method %s", receiver.Type(), member.Property.Value)
+	}
+
+	if len(arguments) != method.parameters {
+		return evaluator.errorAt(ast.Pos(call), "wrong number of arguments to %s: want %d, got %d",
+			call.Function, method.parameters, len(arguments))
+	}
+
+	return method.fn(receiver, arguments)
+}
+
+// xs[start..end] / s[start..end], see ast.RangeExpression: a missing start
+// is 0, a missing end the length
+func (evaluator *evaluator) slice(env *object.Environment, node *ast.IndexExpression, left object.Object, rng *ast.RangeExpression) object.Object {
+	bounds, signal := evaluator.bounds(env, rng, math.MaxInt64)
+	if signal != nil {
+		return signal
+	}
+
+	if slice := sliceOf(left, bounds[0], bounds[1], rng.Inclusive && rng.End != nil); slice != nil {
+		return slice
+	}
+
+	return evaluator.errorAt(node.Token.Position, "cannot slice %s", left.Type())
+}
+
+// the start and end of rng (ints): a missing start is 0, a missing end openEnd
+func (evaluator *evaluator) bounds(env *object.Environment, rng *ast.RangeExpression, openEnd int64) ([2]int64, object.Object) {
+	bounds := [2]int64{0, openEnd}
+
+	for i, bound := range []ast.Expression{rng.Start, rng.End} {
+		if bound == nil {
+			continue
+		}
+
+		value := evaluator.expression(env, bound)
+		if isSignal(value) {
+			return bounds, value
+		}
+
+		integer, ok := value.(*object.Integer)
+		if !ok {
+			return bounds, evaluator.errorAt(ast.Pos(bound), "range bound must be an int, got %s", value.Type())
+		}
+
+		bounds[i] = integer.Value
+	}
+
+	return bounds, nil
+}
+
+func (evaluator *evaluator) prefix(node *ast.PrefixExpression, right object.Object) object.Object {
+	switch node.Operator {
+	case "!":
+		return nativeBool(!isTruthy(right))
+
+	case "-":
+		if integer, ok := right.(*object.Integer); ok {
+			return &object.Integer{Value: -integer.Value}
+		}
+	}
+
+	return evaluator.errorAt(node.Token.Position, "unknown operator: %s%s", node.Operator, right.Type())
+}
+
+// left operator right, reported at position (the operator's)
+func (evaluator *evaluator) infix(position token.Position, operator string, left, right object.Object) object.Object {
+	leftInt, leftIsInt   := left.(*object.Integer)
+	rightInt, rightIsInt := right.(*object.Integer)
+
+	if leftIsInt && rightIsInt {
+		a, b := leftInt.Value, rightInt.Value
+
+		switch operator {
+		case "+":
+			return &object.Integer{Value: a + b}
+		case "-":
+			return &object.Integer{Value: a - b}
+		case "*":
+			return &object.Integer{Value: a * b}
+		case "/":
+			if b == 0 {
+				return evaluator.errorAt(position, "division by zero")
+			}
+			return &object.Integer{Value: a / b}
+		case "<":
+			return nativeBool(a < b)
+		case ">":
+			return nativeBool(a > b)
+		}
+	}
+
+	leftString, leftIsString   := left.(*object.String)
+	rightString, rightIsString := right.(*object.String)
+
+	if leftIsString && rightIsString && operator == "+" {
+		return &object.String{Value: leftString.Value + rightString.Value}
+	}
+
+	switch {
+	case operator == "==":
+		return nativeBool(equal(left, right))
+	case operator == "!=":
+		return nativeBool(!equal(left, right))
+	case left.Type() != right.Type():
+		return evaluator.errorAt(position, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	return evaluator.errorAt(position, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+func (evaluator *evaluator) while(env *object.Environment, loop *ast.WhileStatement) object.Object {
+	for {
+		condition := evaluator.expression(env, loop.Condition)
+		if isSignal(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		switch result := evaluator.block(env, loop.Body).(type) {
+		case *object.Break:
+			return NULL
+		case *object.ReturnValue, *object.Exception:
+			return result
+		}
+	}
+}
+
+func (evaluator *evaluator) forIn(env *object.Environment, loop *ast.ForInStatement) object.Object {
+	iterable := evaluator.expression(env, loop.Iterable)
+	if isSignal(iterable) {
+		return iterable
+	}
+
+	var result object.Object = NULL
+
+	failure := evaluator.each(ast.Pos(loop.Iterable), iterable, func(key, element object.Object) bool {
+		variables := iteration(env, loop.Key, loop.Value, iterable, key, element)
+
+		switch signal := evaluator.block(variables, loop.Body).(type) {
+		case *object.Break:
+			return false
+		case *object.ReturnValue, *object.Exception:
+			result = signal
+			return false
+		}
+
+		return true
+	})
+
+	if failure != nil {
+		return failure
+	}
+
+	return result
+}
+
+// the array (or, with a Key, hash) of the values a comprehension produces.
+// Generators are walked one element at a time, see generate
+func (evaluator *evaluator) comprehension(env *object.Environment, node *ast.Comprehension) object.Object {
+	elements := []object.Object{}
+	hash     := object.NewHash()
+
+	signal := evaluator.generate(env, node.Generators, func(scope *object.Environment) object.Object {
+		if node.Key == nil {
+			value := evaluator.expression(scope, node.Value)
+			if isSignal(value) {
+				return value
+			}
+
+			elements = append(elements, value)
+			return nil
+		}
+
+		key := evaluator.expression(scope, node.Key)
+		if isSignal(key) {
+			return key
+		}
+
+		hashable, ok := key.(object.Hashable)
+		if !ok {
+			return evaluator.errorAt(ast.Pos(node.Key), "unusable as hash key: %s", key.Type())
+		}
+
+		value := evaluator.expression(scope, node.Value)
+		if isSignal(value) {
+			return value
+		}
+
+		hash.Set(hashable, value)
+		return nil
+	})
+
+	switch {
+	case signal != nil:
+		return signal
+	case node.Key != nil:
+		return hash
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// calls produce with the environment of every combination of the variables
+// of generators (nested left to right) that passes their filters. Iterables
+// are walked by each, so no intermediate arrays are built. Stops at the first
+// signal of produce, a filter or an iterable and returns it, nil otherwise
+func (evaluator *evaluator) generate(
+	env        *object.Environment,
+	generators []*ast.Generator,
+	produce    func(scope *object.Environment) object.Object,
+) object.Object {
+	if len(generators) == 0 {
+		return produce(env)
+	}
+
+	generator := generators[0]
+
+	iterable := evaluator.expression(env, generator.Iterable)
+	if isSignal(iterable) {
+		return iterable
+	}
+
+	var signal object.Object
+
+	failure := evaluator.each(ast.Pos(generator.Iterable), iterable, func(key, element object.Object) bool {
+		scope := iteration(env, generator.Key, generator.Value, iterable, key, element)
+
+		for _, filter := range generator.Filters {
+			passed := evaluator.expression(scope, filter)
+			if isSignal(passed) {
+				signal = passed
+				return false
+			}
+
+			if !isTruthy(passed) {
+				return true
+			}
+		}
+
+		signal = evaluator.generate(scope, generators[1:], produce)
+		return signal == nil
+	})
+
+	if failure != nil {
+		return failure
+	}
+
+	return signal
+}
+
+// calls visit with the index / key and the element of each entry of iterable
+// until it returns false: arrays and strings (by byte) in index order,
+// hashes in the order of their keys. Anything else is an error at position
+func (evaluator *evaluator) each(
+	position token.Position,
+	iterable object.Object,
+	visit    func(key, element object.Object) bool,
+) *object.Exception {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		// elements appended by the loop body are visited too
+		for i := 0; i < len(iterable.Elements); i++ {
+			if !visit(&object.Integer{Value: int64(i)}, iterable.Elements[i]) {
+				break
+			}
+		}
+
+	case *object.String:
+		for i := range len(iterable.Value) {
+			if !visit(&object.Integer{Value: int64(i)}, &object.String{Value: iterable.Value[i : i+1]}) {
+				break
+			}
+		}
+
+	case *object.Hash:
+		for _, pair := range iterable.Entries() {
+			if !visit(pair.Key, pair.Value) {
+				break
+			}
+		}
+
+	case *object.Range:
+		// counts without allocating the elements
+		for i := range iterable.Len() {
+			if !visit(&object.Integer{Value: i}, &object.Integer{Value: iterable.Start + i}) {
+				break
+			}
+		}
+
+	default:
+		return evaluator.errorAt(position, "cannot iterate over %s", iterable.Type())
+	}
+
+	return nil
+}
+
+// the value of the body, or of the handler if the body throws. Finally runs
+// in any case: it only changes the outcome if it throws, returns or breaks
+// itself
+func (evaluator *evaluator) try(env *object.Environment, try *ast.TryExpression) object.Object {
+	result := evaluator.block(env, try.Body)
+
+	if exception, ok := result.(*object.Exception); ok && try.Handler != nil {
+		scope := object.NewEnclosedEnvironment(env)
+		scope.Set(try.Parameter.Value, exception.Error)
+
+		result = evaluator.block(scope, try.Handler)
+	}
+
+	if try.Finally != nil {
+		if final := evaluator.block(env, try.Finally); isSignal(final) {
+			return final
+		}
+	}
+
+	return result
+}
+
+// the body of the first arm matching the subject, null if none does
+func (evaluator *evaluator) match(env *object.Environment, match *ast.MatchExpression) object.Object {
+	subject := evaluator.expression(env, match.Subject)
+	if isSignal(subject) {
+		return subject
+	}
+
+	for _, arm := range match.Arms {
+		// the names bound by the pattern are visible to the guard and body
+		scope := object.NewEnclosedEnvironment(env)
+		if !evaluator.matchPattern(scope, arm.Pattern, subject) {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := evaluator.expression(scope, arm.Guard)
+			if isSignal(guard) {
+				return guard
+			}
+
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return evaluator.block(scope, arm.Body)
+	}
+
+	return NULL
+}
+
+// binds the names of pattern in env to the parts of value they stand for,
+// false if value does not have the shape of pattern (some names may be bound
+// then)
+func (evaluator *evaluator) matchPattern(env *object.Environment, pattern ast.Pattern, value object.Object) bool {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		env.Set(pattern.Value, value)
+		return true
+
+	case *ast.WildcardPattern:
+		return true
+
+	case *ast.LiteralPattern:
+		return equal(evaluator.expression(env, pattern.Value), value)
+
+	case *ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok || len(array.Elements) < len(pattern.Elements) {
+			return false
+		}
+
+		if pattern.Rest == nil && len(array.Elements) != len(pattern.Elements) {
+			return false
+		}
+
+		for i, element := range pattern.Elements {
+			if !evaluator.matchPattern(env, element, array.Elements[i]) {
+				return false
+			}
+		}
+
+		if pattern.Rest != nil {
+			rest := append([]object.Object{}, array.Elements[len(pattern.Elements):]...)
+			env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+		}
+
+		return true
+
+	case *ast.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return false
+		}
+
+		for i, key := range pattern.Keys {
+			entry, ok := hash.Get(&object.String{Value: key.Value})
+			if !ok || !evaluator.matchPattern(env, pattern.Value(i), entry) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// calls function with arguments, errors are reported at call
+func (evaluator *evaluator) apply(call *ast.CallExpression, function object.Object, arguments []object.Object) object.Object {
+	switch function := function.(type) {
+	case *object.Function:
+		required, fixed := parameterCounts(function.Literal)
+		if len(arguments) < required || (!function.Literal.Variadic && len(arguments) > fixed) {
+			return evaluator.errorAt(ast.Pos(call), "wrong number of arguments to %s: want %s, got %d",
+				function.Name(), arity(function.Literal), len(arguments))
+		}
+
+		if len(evaluator.frames) == maxCallDepth {
+			return evaluator.errorAt(ast.Pos(call), "stack overflow: more than %d nested calls", maxCallDepth)
+		}
+
+		// defaults and body run in the module the function was defined in
+		callerPath := evaluator.path
+		evaluator.frames = append(evaluator.frames, object.Frame{
+			Function: function.Name(),
+			Position: ast.Pos(call),
+			Path:     callerPath,
+		})
+		evaluator.path = function.Path
+
+		defer func() {
+			evaluator.frames = evaluator.frames[:len(evaluator.frames)-1]
+			evaluator.path   = callerPath
+		}()
+
+		env, signal := evaluator.bindArguments(function, arguments)
+		if signal != nil {
+			return signal
+		}
+
+		result := evaluator.statements(env, function.Literal.Body.Statements)
+
+		if returned, ok := result.(*object.ReturnValue); ok {
+			return returned.Value
+		}
+
+		return result
+
+	case *object.Builtin:
+		result, err := function.Fn(arguments...)
+		if err != nil {
+			return evaluator.errorAt(ast.Pos(call), "%s", err)
+		}
+
+		return result
+	}
+
+	return evaluator.errorAt(ast.Pos(call), "not a function: %s", function.Type())
+}
+
+// the environment of a call to function: parameters share the scope of the
+// body's top level. Missing arguments take their defaults, evaluated there in
+// order (so they see the parameters before them); a rest parameter collects
+// the extra arguments into an array
+func (evaluator *evaluator) bindArguments(function *object.Function, arguments []object.Object) (*object.Environment, object.Object) {
+	literal  := function.Literal
+	_, fixed := parameterCounts(literal)
+	env      := object.NewEnclosedEnvironment(function.Env)
+
+	for i, parameter := range literal.Parameters[:fixed] {
+		if i < len(arguments) {
+			env.Set(parameter.Value, arguments[i])
+			continue
+		}
+
+		value := evaluator.expression(env, literal.Default(i))
+		if isSignal(value) {
+			return nil, value
+		}
+
+		env.Set(parameter.Value, value)
+	}
+
+	if literal.Variadic {
+		rest := []object.Object{}
+		if len(arguments) > fixed {
+			rest = append(rest, arguments[fixed:]...)
+		}
+
+		env.Set(literal.Parameters[fixed].Value, &object.Array{Elements: rest})
+	}
+
+	return env, nil
+}
+
+func (evaluator *evaluator) errorAt(position token.Position, format string, args ...any) *object.Exception {
+	message := fmt.Sprintf(format, args...)
+	return evaluator.throw(position, message, &object.String{Value: message})
+}
+
+// an exception for value thrown at position, with the calls in progress
+func (evaluator *evaluator) throw(position token.Position, message string, value object.Object) *object.Exception {
+	stack := []object.Frame{}
+	for i := len(evaluator.frames) - 1; i >= 0; i-- {
+		stack = append(stack, evaluator.frames[i])
+	}
+
+	return &object.Exception{
+		Error: &object.Error{
+			Message:  message,
+			Value:    value,
+			Position: position,
+			Path:     evaluator.path,
+			Stack:    stack,
+		},
+	}
+}
+
+//---[ Evaluator Helper Methods ]-----------------------------------------------
+
+
+// whether value is passed on instead of being used as a value
+func isSignal(value object.Object) bool {
+	switch value.(type) {
+	case *object.ReturnValue, *object.Break, *object.Continue, *object.Exception:
+		return true
+	}
+
+	return false
+}
+
+// the position of the i-th element of a sequence of length elements, with a
+// negative i counting from the end; false if there is no such element
+func elementIndex(i int64, length int) (int64, bool) {
+	if i < 0 {
+		i += int64(length)
+	}
+
+	return i, i >= 0 && i < int64(length)
+}
+
+// the elements start..end of an array or string (see object.SliceBounds),
+// nil for other values
+func sliceOf(sequence object.Object, start, end int64, inclusive bool) object.Object {
+	switch sequence := sequence.(type) {
+	case *object.Array:
+		from, to := object.SliceBounds(int64(len(sequence.Elements)), start, end, inclusive)
+		return &object.Array{Elements: append([]object.Object{}, sequence.Elements[from:to]...)}
+
+	case *object.String:
+		from, to := object.SliceBounds(int64(len(sequence.Value)), start, end, inclusive)
+		return &object.String{Value: sequence.Value[from:to]}
+	}
+
+	return nil
+}
+
+// the environment of one iteration over iterable: `(k, v in xs)` binds the
+// index / key and the element, `(x in xs)` the element, or the key of a hash
+func iteration(
+	env        *object.Environment,
+	key, value *ast.Identifier,
+	iterable   object.Object,
+	entryKey   object.Object,
+	element    object.Object,
+) *object.Environment {
+	variables := object.NewEnclosedEnvironment(env)
+
+	if _, isHash := iterable.(*object.Hash); isHash && key == nil {
+		element = entryKey
+	}
+
+	if key != nil {
+		variables.Set(key.Value, entryKey)
+	}
+	variables.Set(value.Value, element)
+
+	return variables
+}
+
+// the number of parameters without a default, and of those before a rest
+// parameter
+func parameterCounts(function *ast.FunctionLiteral) (required, fixed int) {
+	fixed = len(function.Parameters)
+	if function.Variadic {
+		fixed--
+	}
+
+	for required < fixed && function.Default(required) == nil {
+		required++
+	}
+
+	return required, fixed
+}
+
+// 2, 1 to 2, at least 1 (as the typechecker spells it)
+func arity(function *ast.FunctionLiteral) string {
+	required, fixed := parameterCounts(function)
+
+	switch {
+	case function.Variadic:
+		return fmt.Sprintf("at least %d", required)
+	case required < fixed:
+		return fmt.Sprintf("%d to %d", required, fixed)
+	}
+
+	return fmt.Sprintf("%d", fixed)
+}
+
+// the function statement declares (possibly exported), nil if none
+func declaredFunction(statement ast.Statement) *ast.FunctionDeclaration {
+	if export, ok := statement.(*ast.ExportStatement); ok {
+		statement = export.Statement
+	}
+
+	declaration, _ := statement.(*ast.FunctionDeclaration)
+	return declaration
+}
+
+func isTruthy(value object.Object) bool {
+	return value != NULL && value != FALSE
+}
+
+func nativeBool(value bool) *object.Boolean {
+	if value {
+		return TRUE
+	}
+
+	return FALSE
+}
+
+// booleans and null are singletons, other values without a Value are
+// only equal to themselves
+func equal(left, right object.Object) bool {
+	switch left := left.(type) {
+	case *object.Integer:
+		right, ok := right.(*object.Integer)
+		return ok && left.Value == right.Value
+	case *object.String:
+		right, ok := right.(*object.String)
+		return ok && left.Value == right.Value
+	}
+
+	return left == right
+}
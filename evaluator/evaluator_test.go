@@ -0,0 +1,684 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"monkey/lexer"
+	"monkey/module"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/typecheck"
+)
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func TestEvalExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"5", 5},
+		{"-5 + 10 * 2", 15},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"!true", false},
+		{"!!5", true},
+		{"1 < 2 == true", true},
+		{"1 == true", false},
+		{`"Hello" + " " + "World!"`, "Hello World!"},
+		{`"a" != "b"`, true},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (1) { 10 } else { 20 }", 10},
+		{"if (false) { 1 } else if (1 < 2) { 2 } else { 3 }", 2},
+		{`len("four")`, 4},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let double = fn(x) { return x * 2; 0 }; double(5);", 10},
+		{"fn(x, y) { x + y }(5, 5)", 10},
+		{"let adder = fn(x) { fn(y) { x + y } }; let addTwo = adder(2); addTwo(3);", 5},
+		{"let f = fn(n) { if (n < 1) { return 0; }; n + f(n - 1) }; f(10)", 55},
+		{"if (true) { if (true) { return 10; } return 1; }", 10},
+		{"let x = 1; if (true) { let x = 2; }; x", 1},
+		{"fn add(a, b) { a + b } add(1, 2)", 3},
+		{"let r = even(10); fn even(n) { if (n == 0) { true } else { odd(n - 1) } } fn odd(n) { if (n == 0) { false } else { even(n - 1) } } r", true},
+		{"let f = fn() { g() }; fn g() { 7 } f()", 7},
+		{"fn inc(n, by = 1) { n + by } inc(1) + inc(1, 10)", 13},
+		{"fn f(a, b = a * 2) { a + b } f(3)", 9},
+		{"let n = 0; fn f(x = n) { x } n = 5; f()", 5},
+		{"fn count(...xs) { len(xs) } count() + count(1, 2, 3)", 3},
+		{"fn rest(a, ...xs) { xs } rest(1, 2, 3)", inspected("[2, 3]")},
+		{"fn f(a = 1, ...xs) { a + len(xs) } f()", 1},
+		{"let f = fn() { return h(); fn h() { 4 } }; f()", 4},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestWhileLoops(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"let f = fn(n) { while (n > 0) { return n; } }; f(3)", 3},
+		{"while (false) { 1 }", nil},
+		{"let i = 0; let sum = 0; while (i < 5) { i += 1; if (i == 3) { continue; } sum += i; }; sum", 12},
+		{"let i = 0; while (true) { i = i + 1; if (i > 3) { break; } }; i", 4},
+		{
+			"let f = fn() { while (true) { while (true) { break; }; return 7; } }; f()",
+			7,
+		},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestArraysAndHashes(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"[1, 2 * 2, 3 + 3]", inspected("[1, 4, 6]")},
+		{"[1, 2, 3][1]", 2},
+		{"let i = 0; [1, 2, 3][i]", 1},
+		{"[1, 2, 3][3]", nil},
+		{`"abc"[2]`, "c"},
+		{`{"one": 1, true: 2, 3: "three"}`, inspected(`{"one": 1, true: 2, 3: "three"}`)},
+		{`let key = "b"; {"a": 1, key: 2}["b"]`, 2},
+		{`{"a": 1, "b": 2, "a": 3}`, inspected(`{"a": 3, "b": 2}`)},
+		{`{"a": 1}["z"]`, nil},
+		{`len([1, 2]) + len({"a": 1})`, 3},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestForInLoops(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"let f = fn(xs) { for (x in xs) { if (x > 2) { return x; } } }; f([1, 2, 3, 4])", 3},
+		{"let f = fn(xs) { for (i, x in xs) { if (x == 30) { return i; } } }; f([10, 20, 30])", 2},
+		{`let f = fn(h) { for (k in h) { return k; } }; f({"b": 1, "a": 2})`, "b"},
+		{`let f = fn(h) { for (k, v in h) { if (k == "a") { return v; } } }; f({"b": 1, "a": 2})`, 2},
+		{`let f = fn(s) { for (c in s) { if (c != "x") { return c; } } }; f("xxyx")`, "y"},
+		{"let f = fn() { for (x in [1, 2]) { break; }; for (x in [1, 2]) { continue; }; 5 }; f()", 5},
+		{"for (x in []) { x }", nil},
+		{"for (x in [1]) { 1 + true }; 2", "1:20: type mismatch: int + bool"},
+	}
+
+	for _, test := range tests {
+		result := testEval(t, test.input)
+
+		if exception, ok := result.(*object.Exception); ok {
+			result = &object.String{Value: exception.Error.String()}
+		}
+
+		testObject(t, test.input, result, test.expected)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; let y = 0; y = x = 5; x + y", 10},
+		{"let x = 10; x -= 3; x *= 2; x /= 7; x", 2},
+		{`let s = "a"; s += "b"; s`, "ab"},
+		{"let x = 1; let f = fn() { x = 2; }; f(); x", 2},
+		{"let x = 1; if (true) { let x = 5; x = 6; }; x", 1},
+		{"let xs = [1, 2]; xs[1] = 5; xs", inspected("[1, 5]")},
+		{"let xs = [1, 2]; xs[0] += 10; xs[0]", 11},
+		{`let h = {"a": 1}; h["b"] = 2; h["a"] *= 3; h`, inspected(`{"a": 3, "b": 2}`)},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, ...rest] = [1, 2, 3]; rest", inspected("[2, 3]")},
+		{"let [_, [x, _]] = [1, [2, 3]]; x", 2},
+		{"let [...all] = []; all", inspected("[]")},
+		{`let {name, age} = {"name": "Ann", "age": 30, "x": 1}; len(name) + age`, 33},
+		{`let {name: n, "full name": f} = {"name": "a", "full name": "b"}; n + f`, "ab"},
+		{`let {items: [first, ...others]} = {"items": [1, 2, 3]}; first + len(others)`, 3},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" }`, "two"},
+		{`match (-1) { -1 => "minus one", _ => "other" }`, "minus one"},
+		{`match ("b") { "a" => 1, "b" => 2 }`, 2},
+		{`match (true) { false => 0, true => 1 }`, 1},
+		{"match (5) { 1 => 1 }", nil},
+		{"match ([1, 2]) { [x] => x, [x, y] => x + y }", 3},
+		{"match ([1, 2, 3]) { [1, ...rest] => len(rest), _ => 0 }", 2},
+		{`match ({"kind": "a", "n": 4}) { {kind: "b"} => 0, {kind: "a", n} => n }`, 4},
+		{"match (7) { n if n > 10 => 1, n if n > 5 => 2, _ => 3 }", 2},
+		{"let n = 1; match (5) { n => n }; n", 1},
+		{"let f = fn(x) { match (x) { 0 => { return 10; }, _ => 20 }; 30 }; f(0) + f(1)", 40},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"quote(5)", inspected("quote(5)")},
+		{"quote(foobar + barfoo)", inspected("quote((foobar + barfoo))")},
+		{"quote(8 + unquote(4 + 4))", inspected("quote((8 + 8))")},
+		{"let x = 8; quote(unquote(x) + x)", inspected("quote((8 + x))")},
+		{"quote(unquote(true == false))", inspected("quote(false)")},
+		{`quote(unquote("a") + unquote(-2))`, inspected(`quote(("a" + -2))`)},
+		{"let q = quote(4 + 4); quote(unquote(q) * unquote(q))", inspected("quote(((4 + 4) * (4 + 4)))")},
+		{"quote(x, x)", inspected("quote(x)")},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestMemberAccess(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{`let person = {"name": "Ann", "age": 30}; person.name`, "Ann"},
+		{`{"a": {"b": 2}}.a.b`, 2},
+		{`{"a": 1}.missing`, nil},
+		{`let counter = {"step": fn(n) { n + 1 }}; counter.step(1)`, 2},
+		{`"abc".len()`, 3},
+		{"[1, 2].len() + [].len()", 2},
+		{`let h = {"n": 1}; h.n = 5; h.m = 2; h.n += 1; h`, inspected(`{"n": 6, "m": 2}`)},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{`try { throw "boom"; 1 } catch (e) { e.message }`, "boom"},
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{"try { throw 42; } catch (e) { e.value + 1 }", 43},
+		{`try { throw {"code": 7}; } catch (e) { e.value.code }`, 7},
+		{"try { 1 + true } catch (e) { e.message }", "type mismatch: int + bool"},
+		{"try { 1 / 0 } catch (e) { e.value }", "division by zero"},
+		{"let f = fn() { throw 1; }; try { f() } catch (e) { 2 }", 2},
+		{"let f = fn(n) { f(n + 1) }; try { f(0) } catch (e) { -1 }", -1},
+		{`try { try { throw "a"; } catch (e) { throw e; } } catch (e) { e.message + "!" }`, "a!"},
+		{`try { try { throw "a"; } finally { 1 } } catch (e) { e.message }`, "a"},
+		{"let x = 0; try { x = 1; } finally { x = x + 10; }; x", 11},
+		{"try { 1 } finally { 2 }", 1},
+		{`try { throw "a"; } catch (e) { 1 } finally { 2 }`, 1},
+		{"let f = fn() { try { return 1; } finally { 2 } }; f()", 1},
+		{"let f = fn() { try { return 1; } finally { return 2; } }; f()", 2},
+		{`try { 1 } finally { throw "late"; }`, "late"},
+		{"let i = 0; while (true) { try { i += 1; break; } finally { i += 10; } }; i", 11},
+		{`let f = fn() { throw "x"; }; let g = fn() { f() }; try { g() } catch (e) { e.stack }`, inspected(`["f at 1:45", "g at 1:58"]`)},
+	}
+
+	for _, test := range tests {
+		result := testEval(t, test.input)
+
+		if exception, ok := result.(*object.Exception); ok {
+			result = &object.String{Value: exception.Error.Message}
+		}
+
+		testObject(t, test.input, result, test.expected)
+	}
+}
+
+func TestNullHandling(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"null", nil},
+		{"null == null", true},
+		{"null == false", false},
+		{`let h = {"a": {"b": 1}}; h.a?.b`, 1},
+		{`let h = {}; h.a?.b`, nil},
+		{`let h = {}; h.a?["b"]`, nil},
+		{`let xs = null; xs?[1 + true]`, nil},
+		{`let h = {}; h.a?.f(1 + true)`, nil},
+		{`let h = {}; h.a ?? "default"`, "default"},
+		{"0 ?? 1", 0},
+		{"false ?? 1 + true", false},
+		{"null ?? null ?? 3", 3},
+		{`match (null) { null => 1, _ => 2 }`, 1},
+		{`match ({"a": null}) { {a: null} => 1, _ => 2 }`, 1},
+		{"quote(unquote(null))", inspected("quote(null)")},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"`plain`", "plain"},
+		{"``", ""},
+		{"let n = 2; `you have ${n + 1} items`", "you have 3 items"},
+		{"let user = {\"name\": \"Ann\"}; `Hello ${user.name}!`", "Hello Ann!"},
+		{"`${1}${true}${null}`", "1truenull"},
+		{"`list: ${[1, \"a\"]}`", `list: [1, "a"]`},
+		{"let who = \"x\"; `a ${`b ${who}`} c`", "a b x c"},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestRangesAndSlices(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"1..4", inspected("1..4")},
+		{"len(1..4) + len(1..=4) + len(4..1)", 7},
+		{"let sum = 0; for (i in 1..=4) { sum += i; }; sum", 10},
+		{"let sum = 0; for (i, x in 10..12) { sum += i * x; }; sum", 11},
+		{"let f = fn() { for (i in 0..1000000000) { if (i == 3) { return i; } } }; f()", 3},
+		{"len(0..=9223372036854775807)", 9223372036854775807},
+		{"len(-1..9223372036854775807)", 9223372036854775807},
+		{"len((-9223372036854775807 - 1)..=9223372036854775807)", 9223372036854775807},
+		{"len(9223372036854775807..=9223372036854775807)", 1},
+		{"len(5..5) + len(5..=4)", 0},
+		{"let f = fn() { for (i in 9223372036854775806..=9223372036854775807) { if (i < 0) { return i; } }; 0 }; f()", 0},
+		{"let f = fn() { for (i in 0..=9223372036854775807) { if (i == 3) { return i; } } }; f()", 3},
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-4]", nil},
+		{`"abc"[-2]`, "b"},
+		{"let xs = [1, 2, 3]; xs[-1] = 9; xs", inspected("[1, 2, 9]")},
+		{"[1, 2, 3, 4][1..3]", inspected("[2, 3]")},
+		{"[1, 2, 3, 4][1..=2]", inspected("[2, 3]")},
+		{"[1, 2, 3, 4][..2]", inspected("[1, 2]")},
+		{"[1, 2, 3, 4][2..]", inspected("[3, 4]")},
+		{"[1, 2, 3, 4][-2..]", inspected("[3, 4]")},
+		{"[1, 2, 3, 4][..=-1]", inspected("[1, 2, 3, 4]")},
+		{"[1, 2, 3][5..9]", inspected("[]")},
+		{"[1, 2, 3][2..1]", inspected("[]")},
+		{"[1, 2, 3][1..=9223372036854775807]", inspected("[2, 3]")},
+		{`"hello"[1..3]`, "el"},
+		{`"hello"[..-1]`, "hell"},
+		{"let r = 0..2; [1, 2, 3][r]", inspected("[1, 2]")},
+		{"let xs = [1, 2]; let ys = xs[..]; ys[0] = 5; xs", inspected("[1, 2]")},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestComprehensions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected any
+	}{
+		{"[x * 2 for x in [1, -2, 3] if x > 0]", inspected("[2, 6]")},
+		{"[x for x in 0..1000000 if x < 3]", inspected("[0, 1, 2]")},
+		{"[i for (i, x) in [5, 6]]", inspected("[0, 1]")},
+		{"[[x, y] for x in 1..=2 for y in x..=2]", inspected("[[1, 1], [1, 2], [2, 2]]")},
+		{"[x for x in 1..10 if x > 2 if x < 5]", inspected("[3, 4]")},
+		{`{k: v * 10 for (k, v) in {"a": 1, "b": 2}}`, inspected(`{"a": 10, "b": 20}`)},
+		{`{x: x * x for x in 1..=3}`, inspected("{1: 1, 2: 4, 3: 9}")},
+		{`[c for c in "abc" if c != "b"]`, inspected(`["a", "c"]`)},
+		{"let f = fn(xs) { [match (x) { 0 => { return -1; }, _ => x } for x in xs] }; f([1, 0, 2])", -1},
+	}
+
+	for _, test := range tests {
+		testObject(t, test.input, testEval(t, test.input), test.expected)
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk":     `import "lib/math.mk" as math; import "lib/util.mk" as util; [math, math.double(util.id(2)), math.util == util]`,
+		"lib/math.mk": `import "util.mk" as util; export fn double(x) { x * 2 } export let [one] = [1]; let hidden = 1; export let util = util;`,
+		"lib/util.mk": `export let id = fn(x) { x };`,
+	})
+
+	result := testEvalModule(t, filepath.Join(dir, "main.mk"))
+
+	results, ok := result.(*object.Array)
+	if !ok {
+		t.Fatalf("result is not an array. got=%T (%+v)", result, result)
+	}
+
+	// lib/util.mk is imported by lib/math.mk and main.mk, but evaluated once
+	testObject(t, "math.double(util.id(2))", results.Elements[1], 4)
+	testObject(t, "math.util == util", results.Elements[2], true)
+
+	math, ok := results.Elements[0].(*object.Module)
+	if !ok {
+		t.Fatalf("math is not a module. got=%T (%+v)", results.Elements[0], results.Elements[0])
+	}
+
+	if double, ok := math.Export("double"); !ok || double.Type() != object.FUNCTION_OBJ {
+		t.Errorf("double not exported. got=%v", double)
+	}
+
+	if one, ok := math.Export("one"); !ok || one.Inspect() != "1" {
+		t.Errorf("one not exported. got=%v", one)
+	}
+
+	if _, ok := math.Export("hidden"); ok {
+		t.Errorf("hidden should not be exported")
+	}
+}
+
+func TestModuleErrors(t *testing.T) {
+	tests := []struct{
+		files    map[string]string
+		expected string
+	}{
+		{
+			map[string]string{"main.mk": "let x = 1;\nx + true"},
+			"main.mk:2:3: type mismatch: int + bool",
+		},
+		{
+			map[string]string{
+				"main.mk": `import "lib.mk" as lib; 1`,
+				"lib.mk":  "export let x = 1;\nlet y = -true;",
+			},
+			"lib.mk:2:9: unknown operator: -bool",
+		},
+		{
+			map[string]string{
+				"main.mk": `import "lib.mk" as lib;` + "\n" + `lib.f(1)`,
+				"lib.mk":  "export fn f(n) {\n  n + true\n}",
+			},
+			"lib.mk:2:5: type mismatch: int + bool",
+		},
+		{
+			map[string]string{
+				"main.mk": `import "lib.mk" as lib;` + "\n" + `lib.f(1, 2)`,
+				"lib.mk":  "export fn f(n) { n }",
+			},
+			"main.mk:2:1: wrong number of arguments to f: want 1, got 2",
+		},
+		{
+			map[string]string{
+				"main.mk": `import "lib.mk" as lib; lib.hidden`,
+				"lib.mk":  "let hidden = 1;",
+			},
+			`main.mk:1:29: module "lib.mk" does not export hidden`,
+		},
+	}
+
+	for _, test := range tests {
+		dir    := writeFiles(t, test.files)
+		result := testEvalModule(t, filepath.Join(dir, "main.mk"))
+
+		exception, ok := result.(*object.Exception)
+		if !ok {
+			t.Errorf("%v - no error. got=%T (%+v)", test.files, result, result)
+			continue
+		}
+
+		// paths in messages are relative to the temporary directory
+		actual := strings.ReplaceAll(exception.Error.String(), dir+string(filepath.Separator), "")
+		if actual != test.expected {
+			t.Errorf("%v - wrong error. want=%q, got=%q", test.files, test.expected, actual)
+		}
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"5 + true;", "1:3: type mismatch: int + bool"},
+		{"5; -true; 5", "1:4: unknown operator: -bool"},
+		{"true + false", "1:6: unknown operator: bool + bool"},
+		{`"a" - "b"`, "1:5: unknown operator: string - string"},
+		{"if (10 > 1) { if (true) { return true + false; } return 1; }", "1:39: unknown operator: bool + bool"},
+		{"foobar", "1:1: undefined: foobar"},
+		{"let x = 1;\nx / 0", "2:3: division by zero"},
+		{"let f = fn(a) { a }; f(1, 2)", "1:22: wrong number of arguments to f: want 1, got 2"},
+		{"fn g(a) { a } g()", "1:15: wrong number of arguments to g: want 1, got 0"},
+		{"fn g(a, b = 1) { a } g(1, 2, 3)", "1:22: wrong number of arguments to g: want 1 to 2, got 3"},
+		{"fn g(a, ...b) { a } g()", "1:21: wrong number of arguments to g: want at least 1, got 0"},
+		{"fn g(a = 1 + true) { a } g()", "1:12: type mismatch: int + bool"},
+		{"if (true) { fn h() { 1 } }; h()", "1:29: undefined: h"},
+		{"5(1)", "1:1: not a function: int"},
+		{"len(1)", "1:1: len: unsupported argument int"},
+		{"let f = fn(n) { f(n + 1) }; f(0)", "1:17: stack overflow: more than 10000 nested calls"},
+		{"while (true) { 1 + true }", "1:18: type mismatch: int + bool"},
+		{"{[1]: 2}", "1:2: unusable as hash key: array"},
+		{`{"a": 1}[fn() {}]`, "1:10: unusable as hash key: fn"},
+		{`[1]["a"]`, "1:4: cannot index array with string"},
+		{"1[0]", "1:2: cannot index int with int"},
+		{"for (x in 5) { x }", "1:11: cannot iterate over int"},
+		{"y = 1", "1:1: undefined: y"},
+		{"let x = true; x += 1", "1:17: type mismatch: bool + int"},
+		{"let xs = [1]; xs[1] = 2", "1:18: index 1 out of range for length 1"},
+		{`let s = "a"; s[0] = "b"`, "1:15: cannot assign to string index int"},
+		{"let [a, b] = [1];", "1:5: pattern [a, b] does not match [1]"},
+		{"let [a] = 1;", "1:5: pattern [a] does not match 1"},
+		{`let {name} = {"age": 1};`, `1:5: pattern {name} does not match {"age": 1}`},
+		{"match (1) { n if n + true => 1 }", "1:20: type mismatch: int + bool"},
+		{"unquote(1)", "1:1: unquote outside of quote"},
+		{`import "lib.mk" as lib;`, `1:1: cannot import "lib.mk" outside of a module`},
+		{"1.x", "1:3: int has no field x"},
+		{"1..true", "1:4: range bound must be an int, got bool"},
+		{`[1][..""]`, "1:7: range bound must be an int, got string"},
+		{"5[1..2]", "1:2: cannot slice int"},
+		{"[x for x in 5]", "1:13: cannot iterate over int"},
+		{"[x for x in [1] if x + true]", "1:22: type mismatch: int + bool"},
+		{"{[x]: x for x in [1]}", "1:2: unusable as hash key: array"},
+		{"`a\n${1 + true}`", "2:5: type mismatch: int + bool"},
+		{"let x = null; x.y", "1:17: null has no field y"},
+		{"let x = null; x?.y.z", "1:20: null has no field z"},
+		{`throw "boom";`, "1:1: boom"},
+		{"let f = fn() {\n  throw [1];\n}; f()", "2:3: [1]"},
+		{`let e = try { 1 + true } catch (e) { e }; e.nope`, "1:45: error has no field nope"},
+		{`let e = try { 1 + true } catch (e) { e };` + "\nthrow e;", "1:17: type mismatch: int + bool"},
+		{`"abc".size()`, "1:7: string has no method size"},
+		{`"abc".len(1)`, `1:1: wrong number of arguments to "abc".len: want 0, got 1`},
+		{`let s = "a"; s.n = 1`, "1:16: cannot assign to field n of string"},
+		{`let h = {}; h.f()`, "1:13: not a function: null"},
+		{"quote(unquote(fn() { 1 }))", "1:15: cannot unquote fn"},
+		{"quote(unquote(1 + true))", "1:17: type mismatch: int + bool"},
+		{"let f = fn() { let m = macro(x) { x }; 1 }; f()", "1:24: macro must be bound by a top-level let"},
+	}
+
+	for _, test := range tests {
+		result := testEval(t, test.input)
+
+		exception, ok := result.(*object.Exception)
+		if !ok {
+			t.Errorf("%q - no error. got=%T (%+v)", test.input, result, result)
+			continue
+		}
+
+		if actual := exception.Error.String(); actual != test.expected {
+			t.Errorf("%q - wrong error. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestBuiltinTypes(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string
+	}{
+		{`puts(); puts(1); puts("a", 1, true); puts([1], {"a": 1}, null)`, nil},
+		{`let n = len("abc") + len([1]) + len(1..3);`, nil},
+		{`puts("a") + 1`, []string{"1:1: type mismatch: expected int, got null"}},
+		{`len("a", "b")`, []string{"1:1: wrong number of arguments to len: want 1, got 2"}},
+		{`len("a") + "b"`, []string{"1:12: type mismatch: expected int, got string"}},
+		{`let len = fn(a, b) { a }; len(1, 2)`, nil},
+	}
+
+	for _, test := range tests {
+		p       := parser.New(lexer.New(test.input))
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", test.input, p.Errors())
+		}
+
+		actual := []string{}
+		for _, err := range typecheck.Check(program, BuiltinTypes()).Errors {
+			actual = append(actual, err.String())
+		}
+
+		if strings.Join(actual, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%q - wrong errors. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestMethodTypes(t *testing.T) {
+	for receiver, named := range methods {
+		for name, method := range named {
+			function, ok := typecheck.Methods[string(receiver)][name]
+			if !ok {
+				t.Errorf("method %s.%s has no type", receiver, name)
+				continue
+			}
+
+			// Args are the receiver, the parameters and the result
+			if parameters := len(function.Args) - 2; parameters != method.parameters {
+				t.Errorf("method %s.%s wrong number of parameters. runtime=%d, type=%d",
+					receiver, name, method.parameters, parameters)
+			}
+		}
+	}
+}
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func testEval(t *testing.T, input string) object.Object {
+	p       := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return Eval(program, object.NewEnvironment())
+}
+
+func testEvalModule(t *testing.T, path string) object.Object {
+	mod, err := module.NewLoader().Load(path)
+	if err != nil {
+		t.Fatalf("cannot load %s: %s", path, err)
+	}
+
+	return EvalModule(mod)
+}
+
+// writes files (path -> source) to a new temporary directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+// the Inspect of the expected object, for arrays and hashes
+type inspected string
+
+// expected is an int, bool or string for the value of that type, nil for
+// null, or inspected
+func testObject(t *testing.T, input string, actual object.Object, expected any) {
+	switch expected := expected.(type) {
+	case int:
+		integer, ok := actual.(*object.Integer)
+		if !ok || integer.Value != int64(expected) {
+			t.Errorf("%q - wrong result. want=%d, got=%s", input, expected, actual.Inspect())
+		}
+
+	case bool:
+		if actual != nativeBool(expected) {
+			t.Errorf("%q - wrong result. want=%t, got=%s", input, expected, actual.Inspect())
+		}
+
+	case string:
+		str, ok := actual.(*object.String)
+		if !ok || str.Value != expected {
+			t.Errorf("%q - wrong result. want=%q, got=%s", input, expected, actual.Inspect())
+		}
+
+	case inspected:
+		if actual.Inspect() != string(expected) {
+			t.Errorf("%q - wrong result. want=%s, got=%s", input, expected, actual.Inspect())
+		}
+
+	case nil:
+		if actual != NULL {
+			t.Errorf("%q - wrong result. want=null, got=%s", input, actual.Inspect())
+		}
+	}
+}
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
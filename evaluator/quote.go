@@ -0,0 +1,86 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+)
+
+// quote(x) and unquote(y) at runtime, outside of macros: quote(x) evaluates
+// to the AST of x (an *object.Quote) in which every unquote(y) is replaced
+// by the value of y. Names captured with quote(x, names...) only matter at
+// macro expansion (see package macro), they are ignored here.
+
+//---[ Quote Helper Methods ]---------------------------------------------------
+
+func (evaluator *evaluator) quote(env *object.Environment, quoted ast.Expression) object.Object {
+	var failure object.Object
+
+	copied := ast.Clone(quoted).(ast.Expression)
+
+	result := ast.Apply(copied, nil, func(cursor *ast.Cursor) bool {
+		unquoted := ast.CallTo(cursor.Node(), "unquote")
+		if unquoted == nil {
+			return true
+		}
+
+		value := evaluator.expression(env, unquoted)
+		if isSignal(value) {
+			failure = value
+			return false
+		}
+
+		node := toNode(ast.Pos(cursor.Node()), value)
+		if node == nil {
+			failure = evaluator.errorAt(ast.Pos(unquoted), "cannot unquote %s", value.Type())
+			return false
+		}
+
+		cursor.Replace(node)
+		return true
+	})
+
+	if failure != nil {
+		return failure
+	}
+
+	return &object.Quote{Node: result.(ast.Expression)}
+}
+
+// the AST of value at position: a literal, or a copy of a quoted AST. Nil
+// for values no literal can be written for
+func toNode(position token.Position, value object.Object) ast.Expression {
+	switch value := value.(type) {
+	case *object.Integer:
+		return &ast.IntegerLiteral{
+			Token: token.Token{Type: token.INT, Literal: fmt.Sprintf("%d", value.Value), Position: position},
+			Value: value.Value,
+		}
+
+	case *object.Boolean:
+		if value.Value {
+			return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true", Position: position}, Value: true}
+		}
+
+		return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false", Position: position}}
+
+	case *object.String:
+		return &ast.StringLiteral{
+			Token: token.Token{Type: token.STRING, Literal: value.Value, Position: position},
+			Value: value.Value,
+		}
+
+	case *object.Null:
+		return &ast.NullLiteral{Token: token.Token{Type: token.NULL, Literal: "null", Position: position}}
+
+	case *object.Quote:
+		// the same quote may be unquoted more than once
+		return ast.Clone(value.Node).(ast.Expression)
+	}
+
+	return nil
+}
+
+//---[ Quote Helper Methods ]---------------------------------------------------
@@ -9,6 +9,19 @@ type Lexer struct {
 	position     int     // cursor's current index -> char's index
 	readPosition int     // index after cursor's current index
 	char         byte    // current byte examined (pointed to by position)
+
+	line         int     // 1-based line of char
+	column       int     // 1-based column of char
+
+	comments     []token.Token  // `// ...` comments skipped so far
+}
+
+// TemplateSegment is a piece of a template literal's text: either plain text
+// or the source of an embedded ${} expression.
+type TemplateSegment struct {
+	Text       string
+	Expression bool            // Text is the source between ${ and }
+	Position   token.Position  // where Text starts in the source
 }
 
 //---[ Public Package Methods ]-------------------------------------------------
@@ -16,6 +29,7 @@ type Lexer struct {
 func New(input string) (newLexer *Lexer) {
 	newLexer = &Lexer{
 		input: input,
+		line:  1,
 	}
 
 	// everything set to 0
@@ -29,17 +43,81 @@ func New(input string) (newLexer *Lexer) {
 	return newLexer
 }
 
+// NewAt returns a lexer for input that starts at position of a larger source,
+// so its tokens carry positions in that source (e.g. the expressions inside a
+// template literal).
+func NewAt(input string, position token.Position) *Lexer {
+	newLexer := &Lexer{
+		input:  input,
+		line:   position.Line,
+		column: position.Column - 1,
+	}
+
+	newLexer.readChar()
+
+	return newLexer
+}
+
+// SplitTemplate splits the Literal of a TEMPLATE token into its text and ${}
+// expression segments, in source order. Empty text between two expressions
+// is left out.
+func SplitTemplate(template token.Token) []TemplateSegment {
+	lex := NewAt(template.Literal, token.Position{
+		Line:   template.Position.Line,
+		Column: template.Position.Column + 1,  // after the opening backtick
+	})
+
+	segments := []TemplateSegment{}
+	addSegment := func(start int, position token.Position, expression bool) {
+		if text := lex.input[start:lex.position]; text != "" || expression {
+			segments = append(segments, TemplateSegment{Text: text, Expression: expression, Position: position})
+		}
+	}
+
+	start, position := 0, lex.currPosition()
+
+	for lex.char != 0 {
+		if lex.char != '$' || lex.peekChar() != '{' {
+			lex.readChar()
+			continue
+		}
+
+		addSegment(start, position, false)
+
+		lex.readChar()
+		lex.readChar()
+
+		start, position = lex.position, lex.currPosition()
+		lex.skipTemplateExpression()
+		addSegment(start, position, true)
+
+		lex.readChar()
+		start, position = lex.position, lex.currPosition()
+	}
+
+	addSegment(start, position, false)
+
+	return segments
+}
+
 //---[ Public Package Methods ]-------------------------------------------------
 
 
 //---[ Lexer API Methods ]------------------------------------------------------
 
 func (lex *Lexer) NextToken() (nextToken token.Token) {
-	// Ignore whitespace
+	// Ignore whitespace + comments
 	lex.skipWhitespace()
 
+	for lex.char == '/' && lex.peekChar() == '/' {
+		lex.readComment()
+		lex.skipWhitespace()
+	}
+
 	// invariant: char: input[position] is an alphanum character
 
+	position := lex.currPosition()
+
 	// Decide next token
 	switch lex.char {
 	case '=':
@@ -49,13 +127,27 @@ func (lex *Lexer) NextToken() (nextToken token.Token) {
 
 			nextToken.Type    = token.EQ
 			nextToken.Literal = string(char) + string(lex.char)
+		} else if lex.peekChar() == '>' {
+			char := lex.char
+			lex.readChar()
+
+			nextToken.Type    = token.FAT_ARROW
+			nextToken.Literal = string(char) + string(lex.char)
 		} else {
 			nextToken = newToken(token.ASSIGN, lex.char)
 		}
 	case '+':
-		nextToken = newToken(token.PLUS, lex.char)
+		nextToken = lex.newCompoundToken(token.PLUS, token.PLUS_ASSIGN)
 	case '-':
-		nextToken = newToken(token.MINUS, lex.char)
+		if lex.peekChar() == '>' {
+			char := lex.char
+			lex.readChar()
+
+			nextToken.Type    = token.ARROW
+			nextToken.Literal = string(char) + string(lex.char)
+		} else {
+			nextToken = lex.newCompoundToken(token.MINUS, token.MINUS_ASSIGN)
+		}
 	case '!':
 		if lex.peekChar() == '=' {
 			currChar := lex.char
@@ -66,16 +158,78 @@ func (lex *Lexer) NextToken() (nextToken token.Token) {
 		} else {
 			nextToken = newToken(token.BANG, lex.char)
 		}
+	case '|':
+		if lex.peekChar() == '>' {
+			char := lex.char
+			lex.readChar()
+
+			nextToken.Type    = token.PIPE
+			nextToken.Literal = string(char) + string(lex.char)
+		} else {
+			nextToken = newToken(token.ILLEGAL, lex.char)
+		}
+	case '?':
+		// ?. ?[ ?? -> a lone ? is no operator
+		var operator token.TokenType = token.ILLEGAL
+
+		switch lex.peekChar() {
+		case '.':
+			operator = token.OPTIONAL_DOT
+		case '[':
+			operator = token.OPTIONAL_LBRACKET
+		case '?':
+			operator = token.NULLISH
+		}
+
+		if operator != token.ILLEGAL {
+			char := lex.char
+			lex.readChar()
+
+			nextToken.Type    = operator
+			nextToken.Literal = string(char) + string(lex.char)
+		} else {
+			nextToken = newToken(token.ILLEGAL, lex.char)
+		}
 	case '/':
-		nextToken = newToken(token.SLASH, lex.char)
+		nextToken = lex.newCompoundToken(token.SLASH, token.SLASH_ASSIGN)
 	case '*':
-		nextToken = newToken(token.ASTERISK, lex.char)
+		nextToken = lex.newCompoundToken(token.ASTERISK, token.ASTERISK_ASSIGN)
 	case '<':
 		nextToken = newToken(token.LT, lex.char)
 	case '>':
 		nextToken = newToken(token.GT, lex.char)
 	case ';':
 		nextToken = newToken(token.SEMICOLON, lex.char)
+	case ':':
+		nextToken = newToken(token.COLON, lex.char)
+	case '.':
+		if lex.peekChar() != '.' {
+			nextToken = newToken(token.DOT, lex.char)
+			break
+		}
+
+		// .. -> ..., ..= or ..
+		lex.readChar()
+
+		switch lex.peekChar() {
+		case '.':
+			lex.readChar()
+
+			nextToken.Type    = token.ELLIPSIS
+			nextToken.Literal = "..."
+		case '=':
+			lex.readChar()
+
+			nextToken.Type    = token.RANGE_INCLUSIVE
+			nextToken.Literal = "..="
+		default:
+			nextToken.Type    = token.RANGE
+			nextToken.Literal = ".."
+		}
+	case '"':
+		nextToken = lex.readString()
+	case '`':
+		nextToken = lex.readTemplate()
 	case '(':
 		nextToken = newToken(token.LPAREN, lex.char)
 	case ')':
@@ -96,8 +250,9 @@ func (lex *Lexer) NextToken() (nextToken token.Token) {
 	default:
 		if isLetter(lex.char) {
 			// char is letter / _ -> identifier (variable) or keyword
-			nextToken.Literal = lex.readIdentifier()
-			nextToken.Type    = token.LookupIdentifier(nextToken.Literal)
+			nextToken.Literal  = lex.readIdentifier()
+			nextToken.Type     = token.LookupIdentifier(nextToken.Literal)
+			nextToken.Position = position
 
 			// invariant: Token either a:
 			// - variable (Type: IDENT)
@@ -107,8 +262,9 @@ func (lex *Lexer) NextToken() (nextToken token.Token) {
 			return nextToken
 		} else if isDigit(lex.char) {
 			// char is number -> automatically an int value
-			nextToken.Literal = lex.readInt()
-			nextToken.Type    = token.INT
+			nextToken.Literal  = lex.readInt()
+			nextToken.Type     = token.INT
+			nextToken.Position = position
 
 			// invariant: Token is a:
 			// - integer token (Type: INT)
@@ -125,17 +281,31 @@ func (lex *Lexer) NextToken() (nextToken token.Token) {
 		}
 	}
 
+	nextToken.Position = position
+
 	// Move the cursor beyond end of current token
 	lex.readChar()
 	return nextToken
 }
 
+// Comments returns the comments skipped by NextToken so far, in source order.
+func (lex *Lexer) Comments() []token.Token {
+	return lex.comments
+}
+
 //---[ Lexer API Methods ]------------------------------------------------------
 
 
 //---[ Lexer Helper Methods ]---------------------------------------------------
 
 func (lex *Lexer) readChar() {
+	// leaving a newline -> next char starts a new line
+	if lex.char == '\n' {
+		lex.line++
+		lex.column = 0
+	}
+	lex.column++
+
 	// EOF / char harvesting control flow
 	if lex.readPosition >= len(lex.input) {
 		lex.char = 0
@@ -170,6 +340,112 @@ func (lex *Lexer) readInt() string {
 	return lex.input[start:until]
 }
 
+// "text" -> STRING token holding text, ILLEGAL if the input ends before the
+// closing quote. Leaves the cursor on the closing quote
+func (lex *Lexer) readString() token.Token {
+	start := lex.position + 1
+
+	for {
+		lex.readChar()
+
+		if lex.char == '"' {
+			return token.Token{Type: token.STRING, Literal: lex.input[start:lex.position]}
+		}
+
+		if lex.char == 0 {
+			return token.Token{Type: token.ILLEGAL, Literal: lex.input[start-1:]}
+		}
+	}
+}
+
+// `text ${expression} text` -> TEMPLATE token holding everything between the
+// backticks, ILLEGAL if the input ends before the closing backtick. Leaves the
+// cursor on the closing backtick
+func (lex *Lexer) readTemplate() token.Token {
+	start := lex.position + 1
+
+	if !lex.skipTemplate() {
+		return token.Token{Type: token.ILLEGAL, Literal: lex.input[start-1:]}
+	}
+
+	return token.Token{Type: token.TEMPLATE, Literal: lex.input[start:lex.position]}
+}
+
+// moves the cursor from an opening backtick onto the closing one, false if
+// the input ends first. Backticks inside ${} start nested templates
+func (lex *Lexer) skipTemplate() bool {
+	for {
+		lex.readChar()
+
+		switch {
+		case lex.char == 0:
+			return false
+		case lex.char == '`':
+			return true
+		case lex.char == '$' && lex.peekChar() == '{':
+			lex.readChar()
+			lex.readChar()
+
+			if !lex.skipTemplateExpression() {
+				return false
+			}
+		}
+	}
+}
+
+// moves the cursor from the start of a ${} expression onto its closing brace,
+// false if the input ends first. Braces, strings and templates inside the
+// expression are skipped as a whole, so `${ {a: "}"} }` ends at the last brace
+func (lex *Lexer) skipTemplateExpression() bool {
+	depth := 0
+
+	for ; lex.char != 0; lex.readChar() {
+		switch lex.char {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return true
+			}
+			depth--
+		case '"':
+			if lex.readString().Type == token.ILLEGAL {
+				return false
+			}
+		case '`':
+			if !lex.skipTemplate() {
+				return false
+			}
+		}
+	}
+
+	return false
+}
+
+func (lex *Lexer) readComment() {
+	comment := token.Token{
+		Type:     token.COMMENT,
+		Position: lex.currPosition(),
+	}
+
+	start := lex.position
+
+	for lex.char != '\n' && lex.char != 0 {
+		lex.readChar()
+	}
+	until := lex.position
+
+	comment.Literal = lex.input[start:until]
+	lex.comments = append(lex.comments, comment)
+}
+
+func (lex *Lexer) currPosition() token.Position {
+	return token.Position{
+		Line:   lex.line,
+		Column: lex.column,
+	}
+}
+
 func (lex *Lexer) skipWhitespace() {
 	for lex.char == ' ' || lex.char == '\t' || lex.char == '\n' || lex.char == '\r' {
 		lex.readChar()
@@ -182,6 +458,21 @@ func (lex *Lexer) peekChar() byte {
 	return lex.input[lex.readPosition]
 }
 
+// operator followed by `=` -> compound assignment (+=), else the operator (+)
+func (lex *Lexer) newCompoundToken(operator, compound token.TokenType) token.Token {
+	if lex.peekChar() != '=' {
+		return newToken(operator, lex.char)
+	}
+
+	char := lex.char
+	lex.readChar()
+
+	return token.Token{
+		Type:    compound,
+		Literal: string(char) + string(lex.char),
+	}
+}
+
 //---[ Lexer Helper Methods ]---------------------------------------------------
 
 
@@ -426,3 +426,365 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestNextTokenPositionsAndComments(t *testing.T) {
+	input := `let x = 5; // five
+// a whole line
+	x + 10;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1, 1},
+		{token.IDENT, "x", 1, 5},
+		{token.ASSIGN, "=", 1, 7},
+		{token.INT, "5", 1, 9},
+		{token.SEMICOLON, ";", 1, 10},
+		{token.IDENT, "x", 3, 2},
+		{token.PLUS, "+", 3, 4},
+		{token.INT, "10", 3, 6},
+		{token.SEMICOLON, ";", 3, 8},
+		{token.EOF, "", 3, 9},
+	}
+	lex := New(input)
+
+	for index, test := range tests {
+		testToken := lex.NextToken()
+
+		if test.expectedType != testToken.Type || testToken.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - incorrect token. expected=%q (%v), got=%q (%v)",
+				index, test.expectedLiteral, test.expectedType, testToken.Literal, testToken.Type,
+			)
+		}
+
+		position := testToken.Position
+		if position.Line != test.expectedLine || position.Column != test.expectedColumn {
+			t.Errorf("tests[%d] - incorrect position. expected=%d:%d, got=%s",
+				index, test.expectedLine, test.expectedColumn, position,
+			)
+		}
+	}
+
+	comments := lex.Comments()
+	if len(comments) != 2 {
+		t.Fatalf("expected 2 comments. got=%d", len(comments))
+	}
+
+	if comments[0].Literal != "// five" || comments[0].Position.String() != "1:12" {
+		t.Errorf("comments[0] wrong. got=%q at %s", comments[0].Literal, comments[0].Position)
+	}
+
+	if comments[1].Literal != "// a whole line" || comments[1].Position.String() != "2:1" {
+		t.Errorf("comments[1] wrong. got=%q at %s", comments[1].Literal, comments[1].Position)
+	}
+}
+
+func TestNextTokenTypeAnnotations(t *testing.T) {
+	input := `let x: int = 5;
+fn(a: int) -> bool { a - 1 }`
+
+	tests := []struct{
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "bool"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "a"},
+		{token.MINUS, "-"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+	lex := New(input)
+
+	for index, test := range tests {
+		testToken := lex.NextToken()
+
+		if test.expectedType != testToken.Type || testToken.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - incorrect token. expected=%q (%v), got=%q (%v)",
+				index, test.expectedLiteral, test.expectedType, testToken.Literal, testToken.Type,
+			)
+		}
+	}
+}
+
+func TestNextTokenAssignments(t *testing.T) {
+	input := `x = 1; x += 2; x -= 3; x *= 4; x /= 5; x->y`
+
+	expected := []token.TokenType{
+		token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.PLUS_ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.MINUS_ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.ASTERISK_ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.SLASH_ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENT, token.ARROW, token.IDENT,
+		token.EOF,
+	}
+	lex := New(input)
+
+	for index, expectedType := range expected {
+		testToken := lex.NextToken()
+
+		if testToken.Type != expectedType {
+			t.Fatalf("tests[%d] - incorrect token type. expected=%q, got=%q (%q)",
+				index, expectedType, testToken.Type, testToken.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenEllipsis(t *testing.T) {
+	input := `fn(a, ...rest) .. . 1..=2 1..n [..5] ....`
+
+	expected := []token.TokenType{
+		token.FUNCTION, token.LPAREN, token.IDENT, token.COMMA,
+		token.ELLIPSIS, token.IDENT, token.RPAREN,
+		token.RANGE, token.DOT,
+		token.INT, token.RANGE_INCLUSIVE, token.INT,
+		token.INT, token.RANGE, token.IDENT,
+		token.LBRACKET, token.RANGE, token.INT, token.RBRACKET,
+		token.ELLIPSIS, token.DOT,
+		token.EOF,
+	}
+	lex := New(input)
+
+	for index, expectedType := range expected {
+		testToken := lex.NextToken()
+
+		if testToken.Type != expectedType {
+			t.Fatalf("tests[%d] - incorrect token type. expected=%q, got=%q (%q)",
+				index, expectedType, testToken.Type, testToken.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenMember(t *testing.T) {
+	input := `lib.f(x).y "abc".len() 1.x`
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "lib"}, {token.DOT, "."}, {token.IDENT, "f"},
+		{token.LPAREN, "("}, {token.IDENT, "x"}, {token.RPAREN, ")"}, {token.DOT, "."}, {token.IDENT, "y"},
+		{token.STRING, "abc"}, {token.DOT, "."}, {token.IDENT, "len"}, {token.LPAREN, "("}, {token.RPAREN, ")"},
+		{token.INT, "1"}, {token.DOT, "."}, {token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+	lex := New(input)
+
+	for index, test := range expected {
+		testToken := lex.NextToken()
+
+		if testToken.Type != test.expectedType || testToken.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - incorrect token. expected=%q %q, got=%q %q",
+				index, test.expectedType, test.expectedLiteral, testToken.Type, testToken.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenExceptions(t *testing.T) {
+	input := `try { throw e } catch (e) { } finally { }`
+
+	expected := []token.TokenType{
+		token.TRY, token.LBRACE, token.THROW, token.IDENT, token.RBRACE,
+		token.CATCH, token.LPAREN, token.IDENT, token.RPAREN, token.LBRACE, token.RBRACE,
+		token.FINALLY, token.LBRACE, token.RBRACE,
+		token.EOF,
+	}
+	lex := New(input)
+
+	for index, expectedType := range expected {
+		testToken := lex.NextToken()
+
+		if testToken.Type != expectedType {
+			t.Fatalf("tests[%d] - incorrect token type. expected=%q, got=%q (%q)",
+				index, expectedType, testToken.Type, testToken.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenOptional(t *testing.T) {
+	input := `a?.b?[0] ?? null ? x`
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"}, {token.OPTIONAL_DOT, "?."}, {token.IDENT, "b"},
+		{token.OPTIONAL_LBRACKET, "?["}, {token.INT, "0"}, {token.RBRACKET, "]"},
+		{token.NULLISH, "??"}, {token.NULL, "null"},
+		{token.ILLEGAL, "?"}, {token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+	lex := New(input)
+
+	for index, test := range expected {
+		testToken := lex.NextToken()
+
+		if testToken.Type != test.expectedType || testToken.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - incorrect token. expected=%q %q, got=%q %q",
+				index, test.expectedType, test.expectedLiteral, testToken.Type, testToken.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenTemplates(t *testing.T) {
+	input := "`a ${b} c` `${ {k: \"}\"} } ${`x${y}`}` + `open ${"
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.TEMPLATE, "a ${b} c"},
+		{token.TEMPLATE, "${ {k: \"}\"} } ${`x${y}`}"},
+		{token.PLUS, "+"},
+		{token.ILLEGAL, "`open ${"},
+		{token.EOF, ""},
+	}
+	lex := New(input)
+
+	for index, test := range expected {
+		testToken := lex.NextToken()
+
+		if testToken.Type != test.expectedType || testToken.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - incorrect token. expected=%q %q, got=%q %q",
+				index, test.expectedType, test.expectedLiteral, testToken.Type, testToken.Literal,
+			)
+		}
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	lex      := New("x\n  `Hi ${user.name},\n${n + 1}${m}!`")
+	template := lex.NextToken()
+	template  = lex.NextToken()
+
+	expected := []TemplateSegment{
+		{Text: "Hi ", Position: token.Position{Line: 2, Column: 4}},
+		{Text: "user.name", Expression: true, Position: token.Position{Line: 2, Column: 9}},
+		{Text: ",\n", Position: token.Position{Line: 2, Column: 19}},
+		{Text: "n + 1", Expression: true, Position: token.Position{Line: 3, Column: 3}},
+		{Text: "m", Expression: true, Position: token.Position{Line: 3, Column: 11}},
+		{Text: "!", Position: token.Position{Line: 3, Column: 13}},
+	}
+
+	segments := SplitTemplate(template)
+	if len(segments) != len(expected) {
+		t.Fatalf("wrong number of segments. want=%d, got=%d (%+v)", len(expected), len(segments), segments)
+	}
+
+	for i, segment := range segments {
+		if segment != expected[i] {
+			t.Errorf("segments[%d] wrong. want=%+v, got=%+v", i, expected[i], segment)
+		}
+	}
+
+	// tokens of an embedded expression carry their position in the whole source
+	nested := NewAt(segments[3].Text, segments[3].Position)
+	if n := nested.NextToken(); n.Position != (token.Position{Line: 3, Column: 3}) {
+		t.Errorf("wrong position of n. got=%s", n.Position)
+	}
+	if plus := nested.NextToken(); plus.Position != (token.Position{Line: 3, Column: 5}) {
+		t.Errorf("wrong position of +. got=%s", plus.Position)
+	}
+}
+
+func TestNextTokenPipe(t *testing.T) {
+	input := `xs |> f() | >`
+
+	expected := []token.TokenType{
+		token.IDENT, token.PIPE, token.IDENT, token.LPAREN, token.RPAREN,
+		token.ILLEGAL, token.GT,
+		token.EOF,
+	}
+	lex := New(input)
+
+	for index, expectedType := range expected {
+		testToken := lex.NextToken()
+
+		if testToken.Type != expectedType {
+			t.Fatalf("tests[%d] - incorrect token type. expected=%q, got=%q (%q)",
+				index, expectedType, testToken.Type, testToken.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenMatch(t *testing.T) {
+	input := `match (x) { 1 => a, _ if a >= b => c }`
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.MATCH, "match"}, {token.LPAREN, "("}, {token.IDENT, "x"}, {token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.INT, "1"}, {token.FAT_ARROW, "=>"}, {token.IDENT, "a"}, {token.COMMA, ","},
+		{token.IDENT, "_"}, {token.IF, "if"}, {token.IDENT, "a"}, {token.GT, ">"},
+		{token.ASSIGN, "="}, {token.IDENT, "b"}, {token.FAT_ARROW, "=>"}, {token.IDENT, "c"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+	lex := New(input)
+
+	for index, test := range expected {
+		testToken := lex.NextToken()
+
+		if testToken.Type != test.expectedType || testToken.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - incorrect token. expected=%q %q, got=%q %q",
+				index, test.expectedType, test.expectedLiteral, testToken.Type, testToken.Literal,
+			)
+		}
+	}
+}
+
+func TestNextTokenModules(t *testing.T) {
+	input := `import "lib/math.mk" as math;
+export let x = "";
+"unterminated`
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IMPORT, "import"}, {token.STRING, "lib/math.mk"}, {token.AS, "as"},
+		{token.IDENT, "math"}, {token.SEMICOLON, ";"},
+		{token.EXPORT, "export"}, {token.LET, "let"}, {token.IDENT, "x"}, {token.ASSIGN, "="},
+		{token.STRING, ""}, {token.SEMICOLON, ";"},
+		{token.ILLEGAL, `"unterminated`},
+		{token.EOF, ""},
+	}
+	lex := New(input)
+
+	for index, test := range expected {
+		testToken := lex.NextToken()
+
+		if testToken.Type != test.expectedType || testToken.Literal != test.expectedLiteral {
+			t.Fatalf("tests[%d] - incorrect token. expected=%q %q, got=%q %q",
+				index, test.expectedType, test.expectedLiteral, testToken.Type, testToken.Literal,
+			)
+		}
+	}
+}
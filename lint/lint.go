@@ -0,0 +1,205 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/macro"
+	"monkey/parser"
+	"monkey/resolver"
+	"monkey/token"
+)
+
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (severity Severity) String() string {
+	switch severity {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(severity))
+}
+
+// Rule is a single check. Check walks pass.Program and calls pass.Report for
+// every problem it finds.
+type Rule struct {
+	ID       string
+	Severity Severity
+	Doc      string
+	Check    func(pass *Pass)
+}
+
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Position token.Position
+	Message  string
+}
+
+func (finding Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", finding.Position, finding.Severity, finding.Message, finding.Rule)
+}
+
+// Pass is handed to Rule.Check: the parsed program plus shared analyses.
+type Pass struct {
+	Program    *ast.Program
+	Resolution *resolver.Result
+
+	rule     *Rule
+	findings []Finding
+}
+
+// Report records a finding for the current rule at the start of node.
+func (pass *Pass) Report(node ast.Node, format string, args ...any) {
+	pass.findings = append(pass.findings, Finding{
+		Rule:     pass.rule.ID,
+		Severity: pass.rule.Severity,
+		Position: ast.Pos(node),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// SyntaxRule is the rule ID of findings produced for parser errors.
+const SyntaxRule = "syntax"
+
+// MacroRule is the rule ID of findings produced for macro expansion errors.
+const MacroRule = "macro"
+
+// comment prefix that silences findings on its own line and the line below,
+// e.g. `// lint:ignore unused-let, shadowed-parameter`
+const ignoreDirective = "lint:ignore"
+
+type Linter struct {
+	rules []*Rule
+}
+
+
+//---[ Module API Functions ]---------------------------------------------------
+
+// New creates a Linter running rules, or DefaultRules if none are given.
+func New(rules ...*Rule) *Linter {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+
+	return &Linter{
+		rules: rules,
+	}
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Linter API Methods ]-----------------------------------------------------
+
+// Lint parses input, expands its macros and runs every rule over the
+// expanded program. Findings are sorted by position. If input does not parse
+// or its macros do not expand, only those errors are returned.
+func (linter *Linter) Lint(input string, predeclared ...string) []Finding {
+	lex     := lexer.New(input)
+	parse   := parser.New(lex)
+	program := parse.ParseProgram()
+
+	if errors := parse.Diagnostics(); len(errors) > 0 {
+		findings := []Finding{}
+		for _, err := range errors {
+			findings = append(findings, Finding{
+				Rule:     SyntaxRule,
+				Severity: Error,
+				Position: err.Position,
+				Message:  err.Message,
+			})
+		}
+
+		return findings
+	}
+
+	if _, errors := macro.ExpandMacros(program, macro.DefineMacros(program)); len(errors) > 0 {
+		findings := []Finding{}
+		for _, err := range errors {
+			findings = append(findings, Finding{
+				Rule:     MacroRule,
+				Severity: Error,
+				Position: err.Position,
+				Message:  err.Message,
+			})
+		}
+
+		return findings
+	}
+
+	pass := &Pass{
+		Program:    program,
+		Resolution: resolver.Resolve(program, predeclared...),
+	}
+
+	for _, rule := range linter.rules {
+		pass.rule = rule
+		rule.Check(pass)
+	}
+
+	findings := filterIgnored(pass.findings, lex.Comments())
+
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		if a.Position.Line != b.Position.Line {
+			return a.Position.Line - b.Position.Line
+		}
+
+		return a.Position.Column - b.Position.Column
+	})
+
+	return findings
+}
+
+//---[ Linter API Methods ]-----------------------------------------------------
+
+
+//---[ Linter Helper Functions ]------------------------------------------------
+
+func filterIgnored(findings []Finding, comments []token.Token) []Finding {
+	// line -> rule IDs silenced on that line
+	ignored := map[int][]string{}
+
+	for _, comment := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Literal, "//"))
+		if !strings.HasPrefix(text, ignoreDirective) {
+			continue
+		}
+
+		ids := []string{}
+		for _, id := range strings.Split(strings.TrimPrefix(text, ignoreDirective), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+
+		line := comment.Position.Line
+		ignored[line]   = append(ignored[line], ids...)
+		ignored[line+1] = append(ignored[line+1], ids...)
+	}
+
+	kept := []Finding{}
+	for _, finding := range findings {
+		if !slices.Contains(ignored[finding.Position.Line], finding.Rule) {
+			kept = append(kept, finding)
+		}
+	}
+
+	return kept
+}
+
+//---[ Linter Helper Functions ]------------------------------------------------
@@ -0,0 +1,187 @@
+package lint
+
+import (
+	"testing"
+)
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func TestRules(t *testing.T) {
+	tests := []struct{
+		rule     *Rule
+		input    string
+		expected []string
+	}{
+		{
+			UnusedLet,
+			"let x = 1; let y = 2; let _z = 3; y;",
+			[]string{"1:5: warning: x declared and not used (unused-let)"},
+		},
+		{
+			UnusedLet,
+			"let x = 1; x = 2; let y = 1; y += 1; y;",
+			[]string{"1:5: warning: x declared and not used (unused-let)"},
+		},
+		{
+			UnusedLet,
+			"let [a, _b, ...rest] = xs; let {name, age} = p; a + age;",
+			[]string{
+				"1:16: warning: rest declared and not used (unused-let)",
+				"1:33: warning: name declared and not used (unused-let)",
+			},
+		},
+		{
+			UnusedLet,
+			"export let x = fn() { let y = 1; 2 }; export let [a, b] = xs;",
+			[]string{"1:27: warning: y declared and not used (unused-let)"},
+		},
+		{
+			ShadowedParameter,
+			"let f = fn(a, b) { let a = 1; fn(b) { b } };",
+			[]string{
+				"1:24: warning: let a shadows a parameter (shadowed-parameter)",
+				"1:34: warning: parameter b shadows a parameter of an enclosing function (shadowed-parameter)",
+			},
+		},
+		{
+			NonExhaustiveMatch,
+			"match (x) { 1 => 2, n if n > 0 => n };\nmatch (x) { 1 => 2, _ => 3 };\nmatch (x) { 1 => 2, n => n };",
+			[]string{"1:1: warning: match has no wildcard arm (non-exhaustive-match)"},
+		},
+		{
+			DuplicateParameter,
+			"fn(a, b, a) { a + b };",
+			[]string{"1:10: error: duplicate parameter a (duplicate-parameter)"},
+		},
+		{
+			UnreachableCode,
+			"fn(a) { return a; a + 1; a + 2 };\nreturn 1;\n2;",
+			[]string{
+				"1:19: warning: unreachable code (unreachable-code)",
+				"3:1: warning: unreachable code (unreachable-code)",
+			},
+		},
+		{
+			UnreachableCode,
+			"fn(a) { throw a; a };\ntry { throw 1 } catch (e) { 2 };",
+			[]string{"1:18: warning: unreachable code (unreachable-code)"},
+		},
+		{
+			UnreachableCode,
+			"while (x) { if (y) { break; x } continue; y }",
+			[]string{
+				"1:29: warning: unreachable code (unreachable-code)",
+				"1:43: warning: unreachable code (unreachable-code)",
+			},
+		},
+		{
+			UnreachableCode,
+			"fn f(a) { return g(a); fn g(b) { b } a }",
+			[]string{"1:38: warning: unreachable code (unreachable-code)"},
+		},
+		{
+			ConstantCondition,
+			"if (1 < 2) { 1 }; if (!true) { 2 }; if (x < 2) { 3 }; if (`a${1}` == \"a1\") { 4 }; if (`${x}` == \"\") { 5 }",
+			[]string{
+				"1:5: warning: condition (1 < 2) is constant (constant-condition)",
+				"1:23: warning: condition (!true) is constant (constant-condition)",
+				"1:59: warning: condition (`a${1}` == \"a1\") is constant (constant-condition)",
+			},
+		},
+		{
+			BooleanComparison,
+			"x == true; false != y; x != true; true == false;",
+			[]string{
+				"1:1: info: comparison with true: use x (boolean-comparison)",
+				"1:12: info: comparison with false: use y (boolean-comparison)",
+				"1:24: info: comparison with true: use !x (boolean-comparison)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		findings := New(test.rule).Lint(test.input, "x", "y")
+		testFindings(t, test.input, findings, test.expected)
+	}
+}
+
+func TestIgnoreComments(t *testing.T) {
+	input := `
+let unused = 1;   // lint:ignore unused-let
+// lint:ignore unused-let, constant-condition
+let alsoUnused = if (true) { 1 };
+let reported = 2; // lint:ignore constant-condition
+`
+	findings := New().Lint(input)
+
+	testFindings(t, input, findings, []string{
+		"5:5: warning: reported declared and not used (unused-let)",
+	})
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	findings := New().Lint("let = 5;")
+
+	if len(findings) == 0 {
+		t.Fatalf("expected syntax findings")
+	}
+
+	for _, finding := range findings {
+		if finding.Rule != SyntaxRule || finding.Severity != Error {
+			t.Errorf("expected syntax error finding. got=%s", finding)
+		}
+	}
+
+	// syntax findings point at the offending token like every other finding
+	testFindings(t, "let x = 1;\n  let y = ;", New().Lint("let x = 1;\n  let y = ;"), []string{
+		"2:11: error: no prefix parse function for ; found (syntax)",
+	})
+}
+
+func TestMacros(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string
+	}{
+		// the rules see the expanded program: y is dropped by the expansion
+		{
+			"let ignore = macro(x) { quote(null) }; let y = 1; ignore(y);",
+			[]string{"1:44: warning: y declared and not used (unused-let)"},
+		},
+		{
+			"let withIt = macro(value, body) { quote(fn() { let it = unquote(value); unquote(body) }(), it) }; withIt(1, it + 1);",
+			[]string{},
+		},
+		{
+			"let m = macro(a) { quote(a) }; let y = 1; m();",
+			[]string{"1:43: error: wrong number of arguments to macro m: want 1, got 0 (macro)"},
+		},
+	}
+
+	for _, test := range tests {
+		testFindings(t, test.input, New().Lint(test.input), test.expected)
+	}
+}
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func testFindings(t *testing.T, input string, findings []Finding, expected []string) {
+	if len(findings) != len(expected) {
+		t.Errorf("%q - wrong number of findings. want=%d, got=%d", input, len(expected), len(findings))
+		for _, finding := range findings {
+			t.Errorf("  got: %s", finding)
+		}
+		return
+	}
+
+	for i, finding := range findings {
+		if finding.String() != expected[i] {
+			t.Errorf("%q - findings[%d] wrong.\n want=%s\n  got=%s", input, i, expected[i], finding)
+		}
+	}
+}
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
@@ -0,0 +1,284 @@
+package lint
+
+import (
+	"strings"
+
+	"monkey/ast"
+	"monkey/resolver"
+)
+
+var (
+	UnusedLet = &Rule{
+		ID:       "unused-let",
+		Severity: Warning,
+		Doc:      "let binding is never used (prefix the name with _ to keep it)",
+		Check:    checkUnusedLet,
+	}
+
+	ShadowedParameter = &Rule{
+		ID:       "shadowed-parameter",
+		Severity: Warning,
+		Doc:      "let binding or inner parameter hides a function parameter",
+		Check:    checkShadowedParameter,
+	}
+
+	DuplicateParameter = &Rule{
+		ID:       "duplicate-parameter",
+		Severity: Error,
+		Doc:      "function declares the same parameter name twice",
+		Check:    checkDuplicateParameter,
+	}
+
+	UnreachableCode = &Rule{
+		ID:       "unreachable-code",
+		Severity: Warning,
+		Doc:      "statement follows a return, throw, break or continue in the same block",
+		Check:    checkUnreachableCode,
+	}
+
+	ConstantCondition = &Rule{
+		ID:       "constant-condition",
+		Severity: Warning,
+		Doc:      "if condition does not depend on any variable",
+		Check:    checkConstantCondition,
+	}
+
+	NonExhaustiveMatch = &Rule{
+		ID:       "non-exhaustive-match",
+		Severity: Warning,
+		Doc:      "match has no wildcard (or plain name) arm, so it may produce null",
+		Check:    checkNonExhaustiveMatch,
+	}
+
+	BooleanComparison = &Rule{
+		ID:       "boolean-comparison",
+		Severity: Info,
+		Doc:      "comparison against true / false literal",
+		Check:    checkBooleanComparison,
+	}
+)
+
+var DefaultRules = []*Rule{
+	UnusedLet,
+	ShadowedParameter,
+	DuplicateParameter,
+	UnreachableCode,
+	ConstantCondition,
+	NonExhaustiveMatch,
+	BooleanComparison,
+}
+
+
+//---[ Rule Check Functions ]---------------------------------------------------
+
+func checkUnusedLet(pass *Pass) {
+	// exported names are used by the importing modules
+	exported := map[*ast.LetStatement]bool{}
+
+	ast.Apply(pass.Program, func(cursor *ast.Cursor) bool {
+		if export, ok := cursor.Node().(*ast.ExportStatement); ok {
+			if let, ok := export.Statement.(*ast.LetStatement); ok {
+				exported[let] = true
+			}
+		}
+
+		let, ok := cursor.Node().(*ast.LetStatement)
+		if !ok || exported[let] {
+			return true
+		}
+
+		for _, name := range let.Names() {
+			if strings.HasPrefix(name.Value, "_") {
+				continue
+			}
+
+			binding := pass.Resolution.Definitions[name]
+			if binding != nil && binding.Kind == resolver.LetBinding && len(binding.Uses) == 0 {
+				pass.Report(name, "%s declared and not used", name.Value)
+			}
+		}
+
+		return true
+	}, nil)
+}
+
+func checkShadowedParameter(pass *Pass) {
+	// parameters of every enclosing function, innermost last
+	visible := []map[string]bool{}
+
+	isParameter := func(name string) bool {
+		for _, parameters := range visible {
+			if parameters[name] {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	pre := func(cursor *ast.Cursor) bool {
+		switch node := cursor.Node().(type) {
+		case *ast.LetStatement:
+			for _, name := range node.Names() {
+				if isParameter(name.Value) {
+					pass.Report(name, "let %s shadows a parameter", name.Value)
+				}
+			}
+
+		case *ast.FunctionLiteral:
+			parameters := map[string]bool{}
+			for _, parameter := range node.Parameters {
+				if isParameter(parameter.Value) {
+					pass.Report(parameter, "parameter %s shadows a parameter of an enclosing function", parameter.Value)
+				}
+
+				parameters[parameter.Value] = true
+			}
+
+			visible = append(visible, parameters)
+		}
+
+		return true
+	}
+
+	post := func(cursor *ast.Cursor) bool {
+		if _, ok := cursor.Node().(*ast.FunctionLiteral); ok {
+			visible = visible[:len(visible)-1]
+		}
+
+		return true
+	}
+
+	ast.Apply(pass.Program, pre, post)
+}
+
+func checkDuplicateParameter(pass *Pass) {
+	ast.Apply(pass.Program, func(cursor *ast.Cursor) bool {
+		function, ok := cursor.Node().(*ast.FunctionLiteral)
+		if !ok {
+			return true
+		}
+
+		seen := map[string]bool{}
+		for _, parameter := range function.Parameters {
+			if seen[parameter.Value] {
+				pass.Report(parameter, "duplicate parameter %s", parameter.Value)
+			}
+
+			seen[parameter.Value] = true
+		}
+
+		return true
+	}, nil)
+}
+
+func checkUnreachableCode(pass *Pass) {
+	check := func(statements []ast.Statement) {
+		terminated := false
+
+		for _, statement := range statements {
+			// declared functions are hoisted -> usable no matter where they are written
+			if _, hoisted := ast.Unexported(statement).(*ast.FunctionDeclaration); terminated && !hoisted {
+				pass.Report(statement, "unreachable code")
+				return
+			}
+
+			switch statement.(type) {
+			case *ast.ReturnStatement, *ast.ThrowStatement, *ast.BreakStatement, *ast.ContinueStatement:
+				terminated = true
+			}
+		}
+	}
+
+	ast.Apply(pass.Program, func(cursor *ast.Cursor) bool {
+		switch node := cursor.Node().(type) {
+		case *ast.Program:
+			check(node.Statements)
+		case *ast.BlockStatement:
+			check(node.Statements)
+		}
+
+		return true
+	}, nil)
+}
+
+func checkConstantCondition(pass *Pass) {
+	ast.Apply(pass.Program, func(cursor *ast.Cursor) bool {
+		ifExp, ok := cursor.Node().(*ast.IfExpression)
+		if ok && isConstant(ifExp.Condition) {
+			pass.Report(ifExp.Condition, "condition %s is constant", ifExp.Condition)
+		}
+
+		return true
+	}, nil)
+}
+
+func checkNonExhaustiveMatch(pass *Pass) {
+	ast.Apply(pass.Program, func(cursor *ast.Cursor) bool {
+		match, ok := cursor.Node().(*ast.MatchExpression)
+		if ok && !match.HasCatchAll() {
+			pass.Report(match, "match has no wildcard arm")
+		}
+
+		return true
+	}, nil)
+}
+
+func checkBooleanComparison(pass *Pass) {
+	ast.Apply(pass.Program, func(cursor *ast.Cursor) bool {
+		infix, ok := cursor.Node().(*ast.InfixExpression)
+		if !ok || (infix.Operator != "==" && infix.Operator != "!=") {
+			return true
+		}
+
+		// both sides literal -> already a constant, nothing to simplify
+		left, leftIsBool   := infix.Left.(*ast.Boolean)
+		right, rightIsBool := infix.Right.(*ast.Boolean)
+
+		switch {
+		case leftIsBool && !rightIsBool:
+			pass.Report(infix, "comparison with %s: use %s", left, suggestion(infix.Right, infix.Operator, left.Value))
+		case rightIsBool && !leftIsBool:
+			pass.Report(infix, "comparison with %s: use %s", right, suggestion(infix.Left, infix.Operator, right.Value))
+		}
+
+		return true
+	}, nil)
+}
+
+//---[ Rule Check Functions ]---------------------------------------------------
+
+
+//---[ Rule Helper Functions ]--------------------------------------------------
+
+// literals combined by operators only -> same value on every run
+func isConstant(expression ast.Expression) bool {
+	switch node := expression.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean, *ast.StringLiteral, *ast.NullLiteral:
+		return true
+	case *ast.PrefixExpression:
+		return isConstant(node.Right)
+	case *ast.InfixExpression:
+		return isConstant(node.Left) && isConstant(node.Right)
+	case *ast.TemplateLiteral:
+		for _, embedded := range node.Expressions {
+			if !isConstant(embedded) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// `x == true` -> x, `x == false` -> !x (and the inverse for !=)
+func suggestion(operand ast.Expression, operator string, literal bool) string {
+	if literal == (operator == "==") {
+		return operand.String()
+	}
+
+	return "!" + operand.String()
+}
+
+//---[ Rule Helper Functions ]--------------------------------------------------
@@ -0,0 +1,113 @@
+package macro
+
+import (
+	"fmt"
+
+	"monkey/ast"
+	"monkey/resolver"
+)
+
+// Names bound by quoted code (lets, parameters, loop variables, pattern names,
+// declared functions) would capture or shadow the variables of the code
+// around a macro call. Every expansion renames them to names used nowhere in
+// the program (and which cannot be written in source: identifiers have no
+// digits), except for the names listed after the code in
+// quote(code, names...), which are captured on purpose.
+
+//---[ Hygiene Helper Methods ]-------------------------------------------------
+
+// every name appearing in program or the bodies of its macros
+func usedNames(program *ast.Program, definitions Definitions) map[string]bool {
+	used    := map[string]bool{}
+	collect := func(cursor *ast.Cursor) bool {
+		if identifier, ok := cursor.Node().(*ast.Identifier); ok {
+			used[identifier.Value] = true
+		}
+
+		return true
+	}
+
+	ast.Apply(program, collect, nil)
+	for _, macro := range definitions {
+		ast.Apply(macro, collect, nil)
+	}
+
+	return used
+}
+
+// name_1, name_2, ... whichever is not used yet
+func (expander *expander) fresh(name string) string {
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d", name, i)
+
+		if !expander.used[candidate] {
+			expander.used[candidate] = true
+			return candidate
+		}
+	}
+}
+
+// renames the names bound by template (in place), apart from captured ones.
+// Only identifiers resolving to a binding inside template are renamed: the
+// others refer to the code around the macro call (or to builtins).
+func (expander *expander) rename(template ast.Expression, captured map[string]bool) {
+	// on its own, template declares nothing but its own bindings
+	resolved := resolver.Resolve(&ast.Program{
+		Statements: []ast.Statement{&ast.ExpressionStatement{Expression: template}},
+	})
+
+	renamed := map[*resolver.Binding]string{}
+	newName := func(identifier *ast.Identifier) (string, bool) {
+		binding, ok := resolved.Definitions[identifier]
+		if !ok || captured[binding.Name] {
+			return "", false
+		}
+
+		if _, done := renamed[binding]; !done {
+			renamed[binding] = expander.fresh(binding.Name)
+		}
+
+		return renamed[binding], true
+	}
+
+	// the keys of hash patterns name entries, not variables
+	keys := map[*ast.Identifier]bool{}
+
+	visitTemplate(template, func(node ast.Node) {
+		switch node := node.(type) {
+		case *ast.HashPattern:
+			for i, key := range node.Keys {
+				keys[key] = true
+
+				// {name} -> {name: name_1}
+				name, ok := newName(key)
+				if ok && i < len(node.Values) && node.Values[i] == nil {
+					variable := *key
+					variable.Value, variable.Token.Literal = name, name
+
+					node.Values[i] = &variable
+				}
+			}
+
+		case *ast.Identifier:
+			if name, ok := newName(node); ok && !keys[node] {
+				node.Value, node.Token.Literal = name, name
+			}
+		}
+	})
+}
+
+// calls visit for the nodes of template outside of unquote(...): only those
+// come from the macro, the rest is spliced in from the call site
+func visitTemplate(template ast.Node, visit func(node ast.Node)) {
+	ast.Apply(template, func(cursor *ast.Cursor) bool {
+		if ast.CallTo(cursor.Node(), "unquote") != nil {
+			return false
+		}
+
+		visit(cursor.Node())
+		return true
+	}, nil)
+}
+
+//---[ Hygiene Helper Methods ]-------------------------------------------------
@@ -0,0 +1,398 @@
+package macro
+
+import (
+	"fmt"
+
+	"monkey/ast"
+	"monkey/token"
+)
+
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (err Error) String() string {
+	return fmt.Sprintf("%s: %s", err.Position, err.Message)
+}
+
+// Definitions are the macros of a program by name, see DefineMacros.
+type Definitions map[string]*ast.MacroLiteral
+
+
+//---[ Module API Functions ]---------------------------------------------------
+
+// DefineMacros removes the top-level `let name = macro(...) { ... };`
+// statements from program and returns the macros they define.
+func DefineMacros(program *ast.Program) Definitions {
+	definitions := Definitions{}
+	statements  := []ast.Statement{}
+
+	for _, statement := range program.Statements {
+		let, ok := statement.(*ast.LetStatement)
+		if ok && let.Name != nil {
+			if literal, ok := let.Value.(*ast.MacroLiteral); ok {
+				definitions[let.Name.Value] = literal
+				continue
+			}
+		}
+
+		statements = append(statements, statement)
+	}
+
+	program.Statements = statements
+
+	return definitions
+}
+
+// ExpandMacros replaces every call of a macro in definitions by the AST its
+// body returns and returns program.
+//
+// The arguments of a call are not evaluated: each parameter holds the quoted
+// AST of its argument. The body runs at expansion time, so it may only use
+// integer / boolean literals and operators, if, let, return and the
+// quote(x) / unquote(x) builtins: quote(x) is the AST of x in which every
+// unquote(y) is replaced by the value of y (an AST or a literal). A macro
+// has to return a quoted AST. Expanded code is not expanded again.
+//
+// Expansion is hygienic: names bound inside quoted code get fresh names, so
+// they neither capture nor shadow variables at the call site. Names listed
+// after the code, as in quote(code, it), keep their name on purpose.
+func ExpandMacros(program *ast.Program, definitions Definitions) (*ast.Program, []Error) {
+	expander := &expander{
+		definitions: definitions,
+		used:        usedNames(program, definitions),
+	}
+
+	ast.Apply(program, nil, func(cursor *ast.Cursor) bool {
+		call, ok := cursor.Node().(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+
+		if expansion := expander.expand(call); expansion != nil {
+			cursor.Replace(expansion)
+		}
+
+		return true
+	})
+
+	return program, expander.errors
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Expansion Helper Methods ]-----------------------------------------------
+
+type expander struct {
+	definitions Definitions
+	used        map[string]bool  // names fresh names must not clash with
+	errors      []Error
+}
+
+// value of an expression evaluated at expansion time: a literal, or an AST
+// produced by quote (or passed in as an argument)
+type value struct {
+	node     ast.Expression
+	quoted   bool
+	returned bool  // by a return statement -> skips the rest of the macro body
+}
+
+type environment struct {
+	values map[string]value
+	outer  *environment
+}
+
+func newEnvironment(outer *environment) *environment {
+	return &environment{
+		values: make(map[string]value),
+		outer:  outer,
+	}
+}
+
+func (env *environment) lookup(name string) (value, bool) {
+	for current := env; current != nil; current = current.outer {
+		if found, ok := current.values[name]; ok {
+			return found, true
+		}
+	}
+
+	return value{}, false
+}
+
+// evaluation stops at the first error -> unwound with a panic, like ast.Apply
+type expansionError struct {
+	err Error
+}
+
+func (expander *expander) fail(node ast.Node, format string, args ...any) {
+	panic(expansionError{Error{
+		Position: ast.Pos(node),
+		Message:  fmt.Sprintf(format, args...),
+	}})
+}
+
+// the AST replacing call, nil if call is no macro call or expanding it failed
+func (expander *expander) expand(call *ast.CallExpression) (expansion ast.Expression) {
+	name, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+
+	macro, ok := expander.definitions[name.Value]
+	if !ok {
+		return nil
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			failure, ok := recovered.(expansionError)
+			if !ok {
+				panic(recovered)
+			}
+
+			expander.errors = append(expander.errors, failure.err)
+			expansion = nil
+		}
+	}()
+
+	if len(call.Arguments) != len(macro.Parameters) {
+		expander.fail(call, "wrong number of arguments to macro %s: want %d, got %d",
+			name.Value, len(macro.Parameters), len(call.Arguments))
+	}
+
+	env := newEnvironment(nil)
+	for i, parameter := range macro.Parameters {
+		env.values[parameter.Value] = value{node: call.Arguments[i], quoted: true}
+	}
+
+	result := expander.block(env, macro.Body)
+	if !result.quoted {
+		expander.fail(call, "macro %s must return a quoted AST, got %s", name.Value, result.node)
+	}
+
+	return result.node
+}
+
+func (expander *expander) block(env *environment, block *ast.BlockStatement) value {
+	var result value
+
+	for _, statement := range block.Statements {
+		switch node := statement.(type) {
+		case *ast.LetStatement:
+			if node.Name == nil {
+				expander.fail(node, "cannot destructure at macro expansion")
+			}
+
+			env.values[node.Name.Value] = expander.eval(env, node.Value)
+			result = value{}
+
+		case *ast.ReturnStatement:
+			result = expander.eval(env, node.ReturnValue)
+			result.returned = true
+
+			return result
+
+		case *ast.ExpressionStatement:
+			if result = expander.eval(env, node.Expression); result.returned {
+				return result
+			}
+
+		default:
+			expander.fail(node, "cannot run %s at macro expansion", node)
+		}
+	}
+
+	if result.node == nil {
+		expander.fail(block, "macro body produced no value")
+	}
+
+	return result
+}
+
+func (expander *expander) eval(env *environment, expression ast.Expression) value {
+	switch node := expression.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean:
+		return value{node: node}
+
+	case *ast.Identifier:
+		found, ok := env.lookup(node.Value)
+		if !ok {
+			expander.fail(node, "undefined: %s", node.Value)
+		}
+
+		return found
+
+	case *ast.PrefixExpression:
+		right := expander.literal(env, node.Right)
+		return value{node: expander.prefix(node, right)}
+
+	case *ast.InfixExpression:
+		left  := expander.literal(env, node.Left)
+		right := expander.literal(env, node.Right)
+
+		return value{node: expander.infix(node, left, right)}
+
+	case *ast.IfExpression:
+		condition := expander.literal(env, node.Condition)
+
+		// everything but false is truthy
+		if boolean, ok := condition.(*ast.Boolean); ok && !boolean.Value {
+			if node.Alternative == nil {
+				expander.fail(node, "if without else produced no value")
+			}
+
+			return expander.block(newEnvironment(env), node.Alternative)
+		}
+
+		return expander.block(newEnvironment(env), node.Consequence)
+
+	case *ast.CallExpression:
+		if arguments, ok := ast.CallArguments(node, "quote"); ok && len(arguments) > 0 {
+			return value{node: expander.quote(env, arguments[0], arguments[1:]), quoted: true}
+		}
+
+		if ast.CallTo(node, "unquote") != nil {
+			expander.fail(node, "unquote outside of quote")
+		}
+	}
+
+	expander.fail(expression, "cannot evaluate %s at macro expansion", expression)
+	return value{}
+}
+
+// literal operand of an operator, quoted ASTs cannot be computed with
+func (expander *expander) literal(env *environment, expression ast.Expression) ast.Expression {
+	operand := expander.eval(env, expression)
+	if operand.quoted {
+		expander.fail(expression, "cannot compute with quoted %s", operand.node)
+	}
+
+	return operand.node
+}
+
+func (expander *expander) prefix(node *ast.PrefixExpression, right ast.Expression) ast.Expression {
+	switch right := right.(type) {
+	case *ast.IntegerLiteral:
+		switch node.Operator {
+		case "-":
+			return integerLiteral(node, -right.Value)
+		case "!":
+			return booleanLiteral(node, false)
+		}
+
+	case *ast.Boolean:
+		if node.Operator == "!" {
+			return booleanLiteral(node, !right.Value)
+		}
+	}
+
+	expander.fail(node, "unknown operator: %s%s", node.Operator, right)
+	return nil
+}
+
+func (expander *expander) infix(node *ast.InfixExpression, left, right ast.Expression) ast.Expression {
+	leftInt, leftIsInt   := left.(*ast.IntegerLiteral)
+	rightInt, rightIsInt := right.(*ast.IntegerLiteral)
+
+	if leftIsInt && rightIsInt {
+		a, b := leftInt.Value, rightInt.Value
+
+		switch node.Operator {
+		case "+":
+			return integerLiteral(node, a+b)
+		case "-":
+			return integerLiteral(node, a-b)
+		case "*":
+			return integerLiteral(node, a*b)
+		case "/":
+			if b == 0 {
+				expander.fail(node, "division by zero")
+			}
+			return integerLiteral(node, a/b)
+		case "<":
+			return booleanLiteral(node, a < b)
+		case ">":
+			return booleanLiteral(node, a > b)
+		case "==":
+			return booleanLiteral(node, a == b)
+		case "!=":
+			return booleanLiteral(node, a != b)
+		}
+	}
+
+	leftBool, leftIsBool   := left.(*ast.Boolean)
+	rightBool, rightIsBool := right.(*ast.Boolean)
+
+	if leftIsBool && rightIsBool {
+		switch node.Operator {
+		case "==":
+			return booleanLiteral(node, leftBool.Value == rightBool.Value)
+		case "!=":
+			return booleanLiteral(node, leftBool.Value != rightBool.Value)
+		}
+	}
+
+	expander.fail(node, "unknown operator: %s %s %s", left, node.Operator, right)
+	return nil
+}
+
+// copy of quoted with its bound names renamed (apart from the captured ones)
+// and every unquote(x) replaced by the value of x
+func (expander *expander) quote(env *environment, quoted ast.Expression, captures []ast.Expression) ast.Expression {
+	captured := map[string]bool{}
+	for _, capture := range captures {
+		name, ok := capture.(*ast.Identifier)
+		if !ok {
+			expander.fail(capture, "quote: captured name must be an identifier, got %s", capture)
+		}
+
+		captured[name.Value] = true
+	}
+
+	copied := ast.Clone(quoted).(ast.Expression)
+	expander.rename(copied, captured)
+
+	result := ast.Apply(copied, nil, func(cursor *ast.Cursor) bool {
+		unquoted := ast.CallTo(cursor.Node(), "unquote")
+		if unquoted == nil {
+			return true
+		}
+
+		// arguments are shared by every unquote of them -> copy on insertion
+		cursor.Replace(ast.Clone(expander.eval(env, unquoted).node))
+
+		return true
+	})
+
+	return result.(ast.Expression)
+}
+
+// computed literals take the position of the expression they come from
+func integerLiteral(node ast.Node, number int64) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{
+		Token: token.Token{
+			Type:     token.INT,
+			Literal:  fmt.Sprintf("%d", number),
+			Position: ast.Pos(node),
+		},
+		Value: number,
+	}
+}
+
+func booleanLiteral(node ast.Node, boolean bool) *ast.Boolean {
+	literal := &ast.Boolean{
+		Token: token.Token{Type: token.FALSE, Literal: "false", Position: ast.Pos(node)},
+		Value: boolean,
+	}
+
+	if boolean {
+		literal.Token.Type    = token.TRUE
+		literal.Token.Literal = "true"
+	}
+
+	return literal
+}
+
+//---[ Expansion Helper Methods ]-----------------------------------------------
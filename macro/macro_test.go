@@ -0,0 +1,239 @@
+package macro
+
+import (
+	"testing"
+
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+)
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+let number = 1;
+let function = fn(x, y) { x + y };
+let mymacro = macro(x, y) { x + y; };
+`
+	program     := parse(t, input)
+	definitions := DefineMacros(program)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	if _, ok := definitions["number"]; ok {
+		t.Errorf("number should not be defined")
+	}
+
+	if _, ok := definitions["function"]; ok {
+		t.Errorf("function should not be defined")
+	}
+
+	macro, ok := definitions["mymacro"]
+	if !ok {
+		t.Fatalf("macro not in definitions")
+	}
+
+	if len(macro.Parameters) != 2 || macro.Parameters[0].Value != "x" || macro.Parameters[1].Value != "y" {
+		t.Errorf("macro parameters wrong. got=%v", macro.Parameters)
+	}
+
+	if body := macro.Body.String(); body != "(x + y)" {
+		t.Errorf("macro body wrong. want=%q, got=%q", "(x + y)", body)
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{
+			"let infixExpression = macro() { quote(1 + 2); }; infixExpression();",
+			"(1 + 2)",
+		},
+		{
+			"let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); }; reverse(2 + 2, 10 - 5);",
+			"((10 - 5) - (2 + 2))",
+		},
+		{
+			`let unless = macro(condition, consequence, alternative) {
+				quote(if (!(unquote(condition))) { unquote(consequence); } else { unquote(alternative); });
+			};
+			unless(10 > 5, a, b);`,
+			"if(!(10 > 5)) aelseb",
+		},
+		{
+			"let twice = macro(x) { let n = 1 + 1; quote(unquote(n) * unquote(x)) }; twice(f(y));",
+			"(2 * f(y))",
+		},
+		{
+			"let pick = macro(flag, a, b) { if (true) { return quote(unquote(a)); }; quote(unquote(b)) }; pick(x, 1, 2);",
+			"1",
+		},
+		{
+			"let m = macro(x) { quote(x + unquote(x)) }; let x = 1; m(2) + m(x);",
+			"let x = 1;((x + 2) + (x + x))",
+		},
+		{
+			"fn f(a) { a } let id = macro(x) { x }; f(id(3));",
+			"fn f(a)af(3)",
+		},
+	}
+
+	for _, test := range tests {
+		program     := parse(t, test.input)
+		definitions := DefineMacros(program)
+
+		expanded, errors := ExpandMacros(program, definitions)
+		if len(errors) != 0 {
+			t.Errorf("%q - unexpected errors: %v", test.input, errors)
+			continue
+		}
+
+		if actual := expanded.String(); actual != test.expected {
+			t.Errorf("%q - wrong expansion. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestExpansionErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let m = macro(a) { quote(a) }; m();", "1:32: wrong number of arguments to macro m: want 1, got 0"},
+		{"let m = macro() { 1 }; m();", "1:24: macro m must return a quoted AST, got 1"},
+		{"let m = macro(a) { a + 1 }; m(2);", "1:20: cannot compute with quoted 2"},
+		{"let m = macro() { quote(unquote(y)) }; m();", "1:33: undefined: y"},
+		{"let m = macro() { unquote(1) }; m();", "1:19: unquote outside of quote"},
+		{"let m = macro() { f(1) }; m();", "1:19: cannot evaluate f(1) at macro expansion"},
+		{"let m = macro() { quote(unquote(1 / 0)) }; m();", "1:33: division by zero"},
+	}
+
+	for _, test := range tests {
+		program := parse(t, test.input)
+
+		_, errors := ExpandMacros(program, DefineMacros(program))
+		if len(errors) != 1 || errors[0].String() != test.expected {
+			t.Errorf("%q - wrong errors. want=%q, got=%v", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestHygiene(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		// swap(tmp, y) must not read its own temporary instead of the caller's tmp
+		{
+			`let swap = macro(a, b) {
+				quote(if (true) { let tmp = unquote(a); unquote(a) = unquote(b); unquote(b) = tmp; });
+			};
+			let tmp = 1; let y = 2; swap(tmp, y);`,
+			"let tmp = 1;let y = 2;iftrue let tmp_1 = tmp;(tmp = y)(y = tmp_1)",
+		},
+		// every expansion gets its own names
+		{
+			`let twice = macro(e) { quote(fn(x) { let y = x; y + y }(unquote(e))) };
+			twice(x) + twice(y);`,
+			"(fn(x_1)let y_1 = x_1;(y_1 + y_1)(x) + fn(x_2)let y_2 = x_2;(y_2 + y_2)(y))",
+		},
+		// loop variables, match patterns and hash shorthands are renamed too
+		{
+			`let each = macro(xs, body) { quote(if (true) { for (k, v in unquote(xs)) { match (v) { {n} => n + k, [m] => unquote(body) } } }) };
+			each(k, v);`,
+			"iftrue for(k_1, v_1 in k) matchv_1 { {n: n_1} => (n_1 + k_1), [m_1] => v }",
+		},
+		// and comprehension variables
+		{
+			`let doubled = macro(xs) { quote([x * 2 for x in unquote(xs) if x > 0]) };
+			doubled(x);`,
+			"[(x_1 * 2) for x_1 in x if (x_1 > 0)]",
+		},
+		// as are caught errors
+		{
+			`let attempt = macro(body) { quote(try { unquote(body) } catch (e) { e }) };
+			attempt(e);`,
+			"try e catch(e_1) e_1",
+		},
+		// member properties are no variables
+		{
+			`let field = macro(p) { quote(fn(name) { unquote(p).name + name }) };
+			field(q);`,
+			"fn(name_1)(q.name + name_1)",
+		},
+		// free names keep referring to the call site, even next to a binder of the same name
+		{
+			`let m = macro() { quote(x + fn(x) { x }(1)) };
+			let x = 5; m();`,
+			"let x = 5;(x + fn(x_1)x_1(1))",
+		},
+		{
+			`let m = macro(e) { quote(fn(helper) { helper }(unquote(e)) + helper(1)) };
+			m(2);`,
+			"(fn(helper_1)helper_1(2) + helper(1))",
+		},
+		// captured names are visible to the code passed in
+		{
+			`let withIt = macro(value, body) { quote(if (true) { let it = unquote(value); unquote(body) }, it) };
+			withIt(5, it * 2);`,
+			"iftrue let it = 5;(it * 2)",
+		},
+	}
+
+	for _, test := range tests {
+		program := parse(t, test.input)
+
+		expanded, errors := ExpandMacros(program, DefineMacros(program))
+		if len(errors) != 0 {
+			t.Errorf("%q - unexpected errors: %v", test.input, errors)
+			continue
+		}
+
+		if actual := expanded.String(); actual != test.expected {
+			t.Errorf("%q - wrong expansion.\nwant=%q\n got=%q", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestExpansionIsFresh(t *testing.T) {
+	program := parse(t, "let m = macro(x) { quote(unquote(x) + unquote(x)) }; m(a); m(a);")
+
+	expanded, errors := ExpandMacros(program, DefineMacros(program))
+	if len(errors) != 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+
+	first  := expanded.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+	second := expanded.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+
+	if first.Left == first.Right || first == second {
+		t.Errorf("expansions share nodes")
+	}
+
+	if !ast.Equal(first, second) {
+		t.Errorf("expansions differ: %v", ast.Diff(first, second))
+	}
+}
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func parse(t *testing.T, input string) *ast.Program {
+	p       := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return program
+}
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
@@ -6,10 +6,34 @@ import (
 	"fmt"
 	"log"	
 
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/lint"
+	"monkey/macro"
+	"monkey/module"
+	"monkey/object"
+	"monkey/parser"
 	"monkey/repl"
+	"monkey/typecheck"
 )
 
 func main() {
+	// `monkey lint file.mk ...` -> static checks instead of the REPL
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:]))
+	}
+
+	// `monkey check [-types] file.mk ...` -> type inference instead of the REPL
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck(os.Args[2:]))
+	}
+
+	// `monkey run file.mk` -> evaluates the program instead of the REPL
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runFile(os.Args[2:]))
+	}
+
 	// Gets the current OS session's user's name
 	user, err := user.Current()
 	if err != nil {
@@ -22,3 +46,144 @@ func main() {
 	// start REPL (language "shell")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// prints findings as file:line:col, exit status 1 if anything was reported
+func runLint(paths []string) int {
+	status := 0
+	linter := lint.New()
+
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		for _, finding := range linter.Lint(string(source)) {
+			fmt.Printf("%s:%s\n", path, finding)
+			status = 1
+		}
+	}
+
+	return status
+}
+
+// prints type errors (of the program with its macros expanded) as
+// file:line:col, with -types also every top-level let's inferred type. Exit
+// status 1 if the program does not type check
+func runCheck(args []string) int {
+	status     := 0
+	printTypes := len(args) > 0 && args[0] == "-types"
+
+	if printTypes {
+		args = args[1:]
+	}
+
+	for _, path := range args {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		p       := parser.New(lexer.New(string(source)))
+		program := p.ParseProgram()
+
+		if len(p.Diagnostics()) != 0 {
+			for _, err := range p.Diagnostics() {
+				fmt.Printf("%s:%s\n", path, err)
+			}
+
+			status = 1
+			continue
+		}
+
+		if _, errs := macro.ExpandMacros(program, macro.DefineMacros(program)); len(errs) != 0 {
+			for _, err := range errs {
+				fmt.Printf("%s:%s\n", path, err)
+			}
+
+			status = 1
+			continue
+		}
+
+		result := typecheck.Check(program, evaluator.BuiltinTypes())
+
+		for _, err := range result.Errors {
+			fmt.Printf("%s:%s\n", path, err)
+			status = 1
+		}
+
+		if !printTypes {
+			continue
+		}
+
+		for _, binding := range result.Bindings {
+			fmt.Printf("%s:%s: %s\n", path, binding.Position, binding)
+		}
+	}
+
+	return status
+}
+
+// loads the program at the path in args together with the modules it
+// imports (from next to it or MONKEYPATH), expands their macros and runs it.
+// Errors go to stderr as file:line:col (an uncaught one with its call stack),
+// exit status 1 then
+func runFile(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: monkey run file.mk")
+		return 2
+	}
+
+	loader := module.NewLoader(module.SearchPath()...)
+
+	program, err := loader.Load(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if !expandMacros(program, map[*module.Module]bool{}) {
+		return 1
+	}
+
+	if exception, ok := evaluator.EvalModule(program).(*object.Exception); ok {
+		fmt.Fprintln(os.Stderr, exception.Error)
+		for _, frame := range exception.Error.Stack {
+			fmt.Fprintf(os.Stderr, "\t%s\n", frame)
+		}
+
+		return 1
+	}
+
+	return 0
+}
+
+// expands the macros of mod and of the modules it imports (each once, macros
+// are local to their module), printing expansion errors. False if any
+func expandMacros(mod *module.Module, expanded map[*module.Module]bool) bool {
+	if expanded[mod] {
+		return true
+	}
+
+	expanded[mod] = true
+	ok           := true
+
+	for _, statement := range mod.Program.Statements {
+		if node, isImport := statement.(*ast.ImportStatement); isImport {
+			ok = expandMacros(mod.Imports[node.Name.Value], expanded) && ok
+		}
+	}
+
+	_, errs := macro.ExpandMacros(mod.Program, macro.DefineMacros(mod.Program))
+
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", mod.Path, err)
+		ok = false
+	}
+
+	return ok
+}
@@ -0,0 +1,216 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+)
+
+// Module is a parsed source file together with the modules it imports.
+type Module struct {
+	Path    string                      // file the module was loaded from
+	Program *ast.Program
+	Imports map[string]*Module          // by the name they are imported as
+	Exports map[string]*ast.Identifier  // the module's namespace: exported name -> its declaration
+}
+
+// Error is a problem with the module at Path, e.g. a parse error or an
+// import that cannot be loaded (Position is the import statement's then).
+type Error struct {
+	Path     string
+	Position token.Position
+	Message  string
+}
+
+func (err *Error) Error() string {
+	if !err.Position.IsValid() {
+		return fmt.Sprintf("%s: %s", err.Path, err.Message)
+	}
+
+	return fmt.Sprintf("%s:%s: %s", err.Path, err.Position, err.Message)
+}
+
+// Loader loads modules and everything they import. Every file is loaded
+// once: importing it again (from anywhere) yields the same *Module.
+type Loader struct {
+	SearchPath []string  // directories tried after the importing file's one
+
+	modules map[string]*Module  // by absolute path
+	loading []string            // import chain being loaded, as absolute paths
+	paths   map[string]string   // absolute path -> Module.Path (for messages)
+}
+
+
+//---[ Module API Functions ]---------------------------------------------------
+
+// NewLoader returns a loader searching the directories of searchPath.
+func NewLoader(searchPath ...string) *Loader {
+	return &Loader{
+		SearchPath: searchPath,
+		modules:    make(map[string]*Module),
+		paths:      make(map[string]string),
+	}
+}
+
+// SearchPath returns the directories listed in the MONKEYPATH environment
+// variable (separated like PATH).
+func SearchPath() []string {
+	list := os.Getenv("MONKEYPATH")
+	if list == "" {
+		return nil
+	}
+
+	return filepath.SplitList(list)
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Loader API Methods ]-----------------------------------------------------
+
+// Load parses the file at path and, recursively, the modules it imports.
+//
+// An import path is looked up relative to the directory of the importing
+// file first, then in every directory of the search path. Importing a module
+// that is still being loaded is an import cycle: the error lists the chain of
+// imports, e.g. `import cycle: a.mk -> b.mk -> a.mk`.
+func (loader *Loader) Load(path string) (*Module, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, &Error{Path: path, Message: err.Error()}
+	}
+
+	if module, ok := loader.modules[absolute]; ok {
+		return module, nil
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &Error{Path: path, Message: err.Error()}
+	}
+
+	p       := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+
+	if len(p.Diagnostics()) != 0 {
+		errs := []error{}
+		for _, err := range p.Diagnostics() {
+			errs = append(errs, &Error{Path: path, Position: err.Position, Message: err.Message})
+		}
+
+		return nil, errors.Join(errs...)
+	}
+
+	module := &Module{
+		Path:    path,
+		Program: program,
+		Imports: make(map[string]*Module),
+		Exports: make(map[string]*ast.Identifier),
+	}
+
+	loader.paths[absolute] = path
+	loader.loading = append(loader.loading, absolute)
+	defer func() { loader.loading = loader.loading[:len(loader.loading)-1] }()
+
+	for _, statement := range program.Statements {
+		switch node := statement.(type) {
+		case *ast.ImportStatement:
+			if _, ok := module.Imports[node.Name.Value]; ok {
+				message := fmt.Sprintf("%s imported twice", node.Name.Value)
+				return nil, &Error{Path: path, Position: node.Name.Token.Position, Message: message}
+			}
+
+			imported, err := loader.loadImport(module, node)
+			if err != nil {
+				return nil, err
+			}
+
+			module.Imports[node.Name.Value] = imported
+
+		case *ast.ExportStatement:
+			for _, name := range node.Names() {
+				if _, ok := module.Exports[name.Value]; ok {
+					message := fmt.Sprintf("%s exported twice", name.Value)
+					return nil, &Error{Path: path, Position: name.Token.Position, Message: message}
+				}
+
+				module.Exports[name.Value] = name
+			}
+		}
+	}
+
+	loader.modules[absolute] = module
+
+	return module, nil
+}
+
+//---[ Loader API Methods ]-----------------------------------------------------
+
+
+//---[ Loader Helper Methods ]--------------------------------------------------
+
+func (loader *Loader) loadImport(importer *Module, statement *ast.ImportStatement) (*Module, error) {
+	fail := func(format string, args ...any) (*Module, error) {
+		return nil, &Error{
+			Path:     importer.Path,
+			Position: statement.Token.Position,
+			Message:  fmt.Sprintf(format, args...),
+		}
+	}
+
+	path, ok := loader.find(filepath.Dir(importer.Path), statement.Path)
+	if !ok {
+		return fail("cannot find module %q", statement.Path)
+	}
+
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return fail("%s", err)
+	}
+
+	for i, loading := range loader.loading {
+		if loading != absolute {
+			continue
+		}
+
+		chain := []string{}
+		for _, member := range loader.loading[i:] {
+			chain = append(chain, loader.paths[member])
+		}
+		chain = append(chain, loader.paths[absolute])
+
+		return fail("import cycle: %s", strings.Join(chain, " -> "))
+	}
+
+	return loader.Load(path)
+}
+
+// the file importPath refers to from a module in directory, false if none
+func (loader *Loader) find(directory, importPath string) (string, bool) {
+	if filepath.IsAbs(importPath) {
+		return importPath, isFile(importPath)
+	}
+
+	for _, candidate := range append([]string{directory}, loader.SearchPath...) {
+		path := filepath.Join(candidate, importPath)
+		if isFile(path) {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+//---[ Loader Helper Methods ]--------------------------------------------------
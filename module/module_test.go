@@ -0,0 +1,179 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk":     `import "lib/math.mk" as math; import "lib/util.mk" as util; math;`,
+		"lib/math.mk": `import "util.mk" as util; export fn double(x) { x * 2 } export let [one, two] = pair; let hidden = 1;`,
+		"lib/util.mk": `export let id = fn(x) { x };`,
+	})
+
+	loader := NewLoader()
+
+	main, err := loader.Load(filepath.Join(dir, "main.mk"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	math, util := main.Imports["math"], main.Imports["util"]
+	if math == nil || util == nil {
+		t.Fatalf("imports missing. got=%v", main.Imports)
+	}
+
+	if exports := exportNames(math); !slices.Equal(exports, []string{"double", "one", "two"}) {
+		t.Errorf("math exports wrong. got=%v", exports)
+	}
+
+	if exports := exportNames(util); !slices.Equal(exports, []string{"id"}) {
+		t.Errorf("util exports wrong. got=%v", exports)
+	}
+
+	// lib/util.mk is imported from lib/math.mk (relative to it) and main.mk
+	if math.Imports["util"] != util {
+		t.Errorf("util loaded twice")
+	}
+
+	again, err := loader.Load(filepath.Join(dir, "lib", "math.mk"))
+	if err != nil || again != math {
+		t.Errorf("loading math again should hit the cache. got=%p (%v), want=%p", again, err, math)
+	}
+}
+
+func TestLoadSearchPath(t *testing.T) {
+	library := writeFiles(t, map[string]string{
+		"strings.mk": `export let found = 1;`,
+		"local.mk":   `export let local = false;`,
+	})
+	dir := writeFiles(t, map[string]string{
+		"main.mk":  `import "strings.mk" as s; import "local.mk" as l;`,
+		"local.mk": `export let local = true;`,
+	})
+
+	main, err := NewLoader(library).Load(filepath.Join(dir, "main.mk"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if path := main.Imports["s"].Path; path != filepath.Join(library, "strings.mk") {
+		t.Errorf("strings.mk not found on the search path. got=%s", path)
+	}
+
+	// the importing file's directory wins over the search path
+	if path := main.Imports["l"].Path; path != filepath.Join(dir, "local.mk") {
+		t.Errorf("local.mk should be found next to main.mk. got=%s", path)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct{
+		files    map[string]string
+		expected string
+	}{
+		{
+			map[string]string{"main.mk": `let x = 1;` + "\n" + `import "missing.mk" as missing;`},
+			`main.mk:2:1: cannot find module "missing.mk"`,
+		},
+		{
+			map[string]string{"main.mk": `import "broken.mk" as broken;`, "broken.mk": `let = 1;`},
+			"broken.mk:1:5: expected next token to be IDENT, got = instead\nbroken.mk:1:5: no prefix parse function for = found",
+		},
+		{
+			map[string]string{"main.mk": `export let x = 1; export fn x() { 2 }`},
+			"main.mk:1:29: x exported twice",
+		},
+		{
+			map[string]string{
+				"main.mk": `import "a.mk" as lib;` + "\n" + `import "b.mk" as lib;`,
+				"a.mk":    `export let x = 1;`,
+				"b.mk":    `export let y = 2;`,
+			},
+			"main.mk:2:18: lib imported twice",
+		},
+		{
+			map[string]string{"main.mk": `import "main.mk" as self;`},
+			"main.mk:1:1: import cycle: main.mk -> main.mk",
+		},
+		{
+			map[string]string{
+				"main.mk": `import "a.mk" as a;`,
+				"a.mk":    `import "b.mk" as b;`,
+				"b.mk":    `export let x = 1;` + "\n" + `import "a.mk" as a;`,
+			},
+			"b.mk:2:1: import cycle: a.mk -> b.mk -> a.mk",
+		},
+	}
+
+	for _, test := range tests {
+		dir := writeFiles(t, test.files)
+
+		_, err := NewLoader().Load(filepath.Join(dir, "main.mk"))
+		if err == nil {
+			t.Errorf("%v - expected error %q", test.files, test.expected)
+			continue
+		}
+
+		// paths in messages are relative to the temporary directory
+		if actual := strings.ReplaceAll(err.Error(), dir+string(filepath.Separator), ""); actual != test.expected {
+			t.Errorf("%v - wrong error. want=%q, got=%q", test.files, test.expected, actual)
+		}
+	}
+}
+
+func TestSearchPathFromEnvironment(t *testing.T) {
+	t.Setenv("MONKEYPATH", strings.Join([]string{"a", "b"}, string(filepath.ListSeparator)))
+
+	if directories := SearchPath(); !slices.Equal(directories, []string{"a", "b"}) {
+		t.Errorf("wrong search path. got=%v", directories)
+	}
+
+	t.Setenv("MONKEYPATH", "")
+
+	if directories := SearchPath(); len(directories) != 0 {
+		t.Errorf("empty MONKEYPATH should give no directories. got=%v", directories)
+	}
+}
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+// writes files (path -> source) to a new temporary directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func exportNames(module *Module) []string {
+	names := []string{}
+	for name := range module.Exports {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
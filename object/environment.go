@@ -0,0 +1,58 @@
+package object
+
+// Environment maps names to values. Each function call, block and loop
+// iteration gets its own, enclosed in the one it appears in.
+type Environment struct {
+	store map[string]Object
+	outer *Environment
+}
+
+
+//---[ Module API Functions ]---------------------------------------------------
+
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object)}
+}
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+
+	return env
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Environment API Methods ]------------------------------------------------
+
+// Get finds the value of name, walking outwards.
+func (env *Environment) Get(name string) (Object, bool) {
+	for current := env; current != nil; current = current.outer {
+		if value, ok := current.store[name]; ok {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Set declares name in env itself, replacing an earlier declaration there.
+func (env *Environment) Set(name string, value Object) Object {
+	env.store[name] = value
+	return value
+}
+
+// Assign updates the nearest declaration of name, false if there is none.
+func (env *Environment) Assign(name string, value Object) bool {
+	for current := env; current != nil; current = current.outer {
+		if _, ok := current.store[name]; ok {
+			current.store[name] = value
+			return true
+		}
+	}
+
+	return false
+}
+
+//---[ Environment API Methods ]------------------------------------------------
@@ -0,0 +1,408 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"monkey/ast"
+	"monkey/token"
+)
+
+type ObjectType string
+
+// type names as the typechecker spells them, so messages agree
+const (
+	INTEGER_OBJ  = "int"
+	BOOLEAN_OBJ  = "bool"
+	STRING_OBJ   = "string"
+	NULL_OBJ     = "null"
+	ARRAY_OBJ    = "array"
+	HASH_OBJ     = "hash"
+	FUNCTION_OBJ = "fn"
+	BUILTIN_OBJ  = "builtin"
+	ERROR_OBJ    = "error"
+	QUOTE_OBJ    = "quote"
+	MODULE_OBJ   = "module"
+	RANGE_OBJ    = "range"
+
+	// signals: never values of the program, they only travel up the evaluator
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
+	EXCEPTION_OBJ    = "EXCEPTION"
+)
+
+type Object interface {
+	Type() ObjectType
+	Inspect() string
+}
+
+
+//---[ Object Types ]-----------------------------------------------------------
+
+type Integer struct {
+	Value int64
+}
+
+func (integer *Integer) Type() ObjectType { return INTEGER_OBJ }
+func (integer *Integer) Inspect() string  { return strconv.FormatInt(integer.Value, 10) }
+
+
+type Boolean struct {
+	Value bool
+}
+
+func (boolean *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+func (boolean *Boolean) Inspect() string  { return strconv.FormatBool(boolean.Value) }
+
+
+type String struct {
+	Value string
+}
+
+func (str *String) Type() ObjectType { return STRING_OBJ }
+func (str *String) Inspect() string  { return strconv.Quote(str.Value) }
+
+
+type Null struct{}
+
+func (null *Null) Type() ObjectType { return NULL_OBJ }
+func (null *Null) Inspect() string  { return "null" }
+
+
+type Array struct {
+	Elements []Object
+}
+
+func (array *Array) Type() ObjectType { return ARRAY_OBJ }
+func (array *Array) Inspect() string  { return "[" + inspectAll(array.Elements) + "]" }
+
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash maps hashable keys to values. Entries keep the order their keys were
+// first set in.
+type Hash struct {
+	Pairs map[HashKey]HashPair
+	Order []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+func (hash *Hash) Type() ObjectType { return HASH_OBJ }
+
+func (hash *Hash) Inspect() string {
+	entries := []string{}
+	for _, pair := range hash.Entries() {
+		entries = append(entries, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// Get returns the value of key, false if there is none.
+func (hash *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := hash.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Set sets the value of key, a new key goes after the others.
+func (hash *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+
+	if _, ok := hash.Pairs[hashKey]; !ok {
+		hash.Order = append(hash.Order, hashKey)
+	}
+
+	hash.Pairs[hashKey] = HashPair{Key: key, Value: value}
+}
+
+// Entries returns the entries in order.
+func (hash *Hash) Entries() []HashPair {
+	pairs := []HashPair{}
+	for _, key := range hash.Order {
+		pairs = append(pairs, hash.Pairs[key])
+	}
+
+	return pairs
+}
+
+
+// Function is a FunctionLiteral closed over the environment it was
+// evaluated in.
+type Function struct {
+	Literal *ast.FunctionLiteral
+	Env     *Environment
+	Path    string  // file of the module it was defined in, "" outside modules
+}
+
+func (function *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (function *Function) Inspect() string  { return function.Literal.String() }
+
+// Name is the name the function was declared or let-bound as, "fn" if none.
+func (function *Function) Name() string {
+	if function.Literal.Name == "" {
+		return "fn"
+	}
+
+	return function.Literal.Name
+}
+
+
+type BuiltinFunction func(args ...Object) (Object, error)
+
+// Builtin is a function implemented in Go. An error it returns is raised as
+// a runtime error at the call.
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+func (builtin *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (builtin *Builtin) Inspect() string  { return "builtin " + builtin.Name }
+
+
+// Error describes a runtime error or a thrown value: what went wrong, where
+// and in which calls. A catch clause binds it, as the value with the fields
+// message, value and stack.
+type Error struct {
+	Message  string
+	Value    Object          // what was thrown, the Message (a *String) for runtime errors
+	Position token.Position  // of the expression that failed (invalid if unknown)
+	Path     string          // file of the module it happened in, "" outside modules
+	Stack    []Frame         // the calls in progress, innermost first
+}
+
+func (err *Error) Type() ObjectType { return ERROR_OBJ }
+func (err *Error) Inspect() string  { return "error: " + err.Message }
+
+func (err *Error) String() string {
+	if location := location(err.Path, err.Position); location != "" {
+		return fmt.Sprintf("%s: %s", location, err.Message)
+	}
+
+	return err.Message
+}
+
+
+// Frame is a call in progress: the function called and where the call is.
+type Frame struct {
+	Function string
+	Position token.Position
+	Path     string  // file of the module the call is in, "" outside modules
+}
+
+func (frame Frame) String() string {
+	if location := location(frame.Path, frame.Position); location != "" {
+		return frame.Function + " at " + location
+	}
+
+	return frame.Function
+}
+
+
+
+// Quote is the AST of quote(x): x with its unquote(...) calls replaced.
+type Quote struct {
+	Node ast.Expression
+}
+
+func (quote *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (quote *Quote) Inspect() string  { return "quote(" + quote.Node.String() + ")" }
+
+
+
+// Range is start..end or start..=end (Inclusive). It holds no elements: a
+// for-in loop counts through it.
+type Range struct {
+	Start     int64
+	End       int64
+	Inclusive bool
+}
+
+func (rng *Range) Type() ObjectType { return RANGE_OBJ }
+
+func (rng *Range) Inspect() string {
+	operator := ".."
+	if rng.Inclusive {
+		operator = "..="
+	}
+
+	return strconv.FormatInt(rng.Start, 10) + operator + strconv.FormatInt(rng.End, 10)
+}
+
+// Len returns the number of integers in the range, at most math.MaxInt64.
+func (rng *Range) Len() int64 {
+	if rng.End < rng.Start || rng.End == rng.Start && !rng.Inclusive {
+		return 0
+	}
+
+	// End - Start does not fit an int64 for e.g. -1..math.MaxInt64, but an uint64
+	length := uint64(rng.End) - uint64(rng.Start)
+	if length >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	if rng.Inclusive {
+		length++
+	}
+
+	return int64(length)
+}
+
+
+// Module is an evaluated module: the environment its top level ran in, of
+// which importers see the exported names.
+type Module struct {
+	Path    string
+	Env     *Environment
+	Exports map[string]bool
+}
+
+func (module *Module) Type() ObjectType { return MODULE_OBJ }
+func (module *Module) Inspect() string  { return "module " + strconv.Quote(module.Path) }
+
+// Export returns the value of an exported name, false if name is not exported.
+func (module *Module) Export(name string) (Object, bool) {
+	if !module.Exports[name] {
+		return nil, false
+	}
+
+	return module.Env.Get(name)
+}
+
+//---[ Object Types ]-----------------------------------------------------------
+
+
+//---[ Signals ]----------------------------------------------------------------
+
+// ReturnValue carries the value of a return statement out of the blocks
+// around it, up to the function call.
+type ReturnValue struct {
+	Value Object
+}
+
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+
+
+// Break and Continue leave the blocks of a loop body, up to the loop.
+type Break struct{}
+
+func (brk *Break) Type() ObjectType { return BREAK_OBJ }
+func (brk *Break) Inspect() string  { return "break" }
+
+
+type Continue struct{}
+
+func (cont *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (cont *Continue) Inspect() string  { return "continue" }
+
+
+// Exception carries an Error up the evaluation: it aborts everything it
+// passes through.
+type Exception struct {
+	Error *Error
+}
+
+func (exception *Exception) Type() ObjectType { return EXCEPTION_OBJ }
+func (exception *Exception) Inspect() string  { return exception.Error.Inspect() }
+
+//---[ Signals ]----------------------------------------------------------------
+
+
+//---[ Hash Keys ]--------------------------------------------------------------
+
+// Hashable values can be keys of a Hash: integers, booleans and strings.
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+// HashKey identifies a key of a Hash: equal values of the same type have
+// the same HashKey.
+type HashKey struct {
+	Type  ObjectType
+	Value int64   // integers, booleans (0 or 1)
+	Text  string  // strings
+}
+
+func (integer *Integer) HashKey() HashKey {
+	return HashKey{Type: integer.Type(), Value: integer.Value}
+}
+
+func (boolean *Boolean) HashKey() HashKey {
+	if boolean.Value {
+		return HashKey{Type: boolean.Type(), Value: 1}
+	}
+
+	return HashKey{Type: boolean.Type()}
+}
+
+func (str *String) HashKey() HashKey {
+	return HashKey{Type: str.Type(), Text: str.Value}
+}
+
+//---[ Hash Keys ]--------------------------------------------------------------
+
+
+// SliceBounds turns the bounds of `xs[start..end]` (`..=` if inclusive) into
+// indexes 0 <= from <= to <= length of the sliced elements: negative bounds
+// count from the end, bounds beyond either end are clamped and a start at or
+// after the end gives an empty slice.
+func SliceBounds(length, start, end int64, inclusive bool) (from, to int64) {
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	if inclusive && end < length {
+		end++ // past length, the bound is cut to length anyway (and end++ could overflow)
+	}
+
+	from = min(max(start, 0), length)
+	to   = min(max(end, from), length)
+
+	return from, to
+}
+
+// Text is object as it appears in messages and output: strings without
+// their quotes.
+func Text(object Object) string {
+	if str, ok := object.(*String); ok {
+		return str.Value
+	}
+
+	return object.Inspect()
+}
+
+// path:line:col, without the parts that are unknown
+func location(path string, position token.Position) string {
+	if !position.IsValid() {
+		return path
+	}
+
+	if path == "" {
+		return position.String()
+	}
+
+	return path + ":" + position.String()
+}
+
+// joins the Inspect of objects with ", "
+func inspectAll(objects []Object) string {
+	parts := []string{}
+	for _, object := range objects {
+		parts = append(parts, object.Inspect())
+	}
+
+	return strings.Join(parts, ", ")
+}
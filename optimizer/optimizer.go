@@ -0,0 +1,447 @@
+package optimizer
+
+import (
+	"strconv"
+
+	"monkey/ast"
+	"monkey/object"
+	"monkey/resolver"
+	"monkey/token"
+)
+
+//---[ Module API Functions ]---------------------------------------------------
+
+// Optimize rewrites program in place and returns it:
+//
+//   - prefix / infix expressions over integer and boolean literals are folded
+//     (division by zero and operators that fail at runtime are left alone)
+//   - neutral operations are removed (x + 0, x * 1, x / 1, !!b, --x) when the
+//     operand is known to be an integer / boolean, so type errors still happen
+//   - if expressions with a constant condition lose their dead branch, and
+//     statement-level ones are replaced by the statements of the live branch
+//   - while loops whose condition is constantly false are removed
+//   - slices of string literals with literal bounds are cut: "hello"[-3..]
+//     -> "llo"
+func Optimize(program *ast.Program) *ast.Program {
+	optimizer := &optimizer{
+		resolution: resolver.Resolve(program),
+	}
+	optimizer.countDeclarations()
+
+	ast.Apply(program, nil, optimizer.post)
+
+	return program
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Optimizer Helper Methods ]-----------------------------------------------
+
+type valueKind int
+
+const (
+	unknownKind valueKind = iota
+	integerKind
+	booleanKind
+)
+
+type declarationKey struct {
+	scope *resolver.Scope
+	name  string
+}
+
+type optimizer struct {
+	resolution   *resolver.Result
+	declarations map[declarationKey]int  // lets per name & scope -> redeclared names are not trusted
+}
+
+func (optimizer *optimizer) countDeclarations() {
+	optimizer.declarations = make(map[declarationKey]int)
+
+	for identifier, binding := range optimizer.resolution.Definitions {
+		if binding.Identifier == identifier {
+			optimizer.declarations[declarationKey{binding.Scope, binding.Name}]++
+		}
+	}
+}
+
+func (optimizer *optimizer) post(cursor *ast.Cursor) bool {
+	switch node := cursor.Node().(type) {
+	case *ast.PrefixExpression:
+		if replacement := optimizer.prefix(node); replacement != nil {
+			cursor.Replace(replacement)
+		}
+
+	case *ast.InfixExpression:
+		if replacement := optimizer.infix(node); replacement != nil {
+			cursor.Replace(replacement)
+		}
+
+	case *ast.IndexExpression:
+		if replacement := foldSlice(node); replacement != nil {
+			cursor.Replace(replacement)
+		}
+
+	case *ast.IfExpression:
+		pruneBranches(node)
+
+	case *ast.ExpressionStatement:
+		if cursor.Index() >= 0 {
+			inlineConstantIf(cursor, node)
+		}
+
+	case *ast.WhileStatement:
+		if cursor.Index() >= 0 {
+			removeDeadLoop(cursor, node)
+		}
+	}
+
+	return true
+}
+
+func (optimizer *optimizer) prefix(node *ast.PrefixExpression) ast.Expression {
+	position := ast.Pos(node)
+
+	switch right := node.Right.(type) {
+	case *ast.IntegerLiteral:
+		switch node.Operator {
+		case "-":
+			return integerLiteral(position, -right.Value)
+		case "!":
+			return booleanLiteral(position, false)  // every integer is truthy
+		}
+
+	case *ast.Boolean:
+		if node.Operator == "!" {
+			return booleanLiteral(position, !right.Value)
+		}
+
+	case *ast.PrefixExpression:
+		// !!b -> b, --x -> x (only if the inner operand keeps its type)
+		if right.Operator != node.Operator {
+			return nil
+		}
+
+		switch {
+		case node.Operator == "!" && optimizer.kindOf(right.Right) == booleanKind:
+			return right.Right
+		case node.Operator == "-" && optimizer.kindOf(right.Right) == integerKind:
+			return right.Right
+		}
+	}
+
+	return nil
+}
+
+func (optimizer *optimizer) infix(node *ast.InfixExpression) ast.Expression {
+	if folded := foldInfix(node); folded != nil {
+		return folded
+	}
+
+	left, leftIsInt   := node.Left.(*ast.IntegerLiteral)
+	right, rightIsInt := node.Right.(*ast.IntegerLiteral)
+
+	switch node.Operator {
+	case "+":
+		if leftIsInt && left.Value == 0 && optimizer.kindOf(node.Right) == integerKind {
+			return node.Right
+		}
+		if rightIsInt && right.Value == 0 && optimizer.kindOf(node.Left) == integerKind {
+			return node.Left
+		}
+
+	case "*":
+		if leftIsInt && left.Value == 1 && optimizer.kindOf(node.Right) == integerKind {
+			return node.Right
+		}
+		if rightIsInt && right.Value == 1 && optimizer.kindOf(node.Left) == integerKind {
+			return node.Left
+		}
+
+	case "-":
+		if rightIsInt && right.Value == 0 && optimizer.kindOf(node.Left) == integerKind {
+			return node.Left
+		}
+
+	case "/":
+		if rightIsInt && right.Value == 1 && optimizer.kindOf(node.Left) == integerKind {
+			return node.Left
+		}
+	}
+
+	return nil
+}
+
+// what an expression evaluates to, if that can be told without running it
+func (optimizer *optimizer) kindOf(expression ast.Expression) valueKind {
+	switch node := expression.(type) {
+	case *ast.IntegerLiteral:
+		return integerKind
+
+	case *ast.Boolean:
+		return booleanKind
+
+	case *ast.PrefixExpression:
+		switch node.Operator {
+		case "!":
+			return booleanKind
+		case "-":
+			return integerKind  // or a runtime error
+		}
+
+	case *ast.InfixExpression:
+		switch node.Operator {
+		case "+":
+			// strings are concatenated: only integers added up are an integer
+			if optimizer.kindOf(node.Left) == integerKind && optimizer.kindOf(node.Right) == integerKind {
+				return integerKind
+			}
+		case "-", "*", "/":
+			return integerKind  // or a runtime error
+		case "<", ">", "==", "!=":
+			return booleanKind
+		}
+
+	case *ast.Identifier:
+		binding := optimizer.resolution.Definitions[node]
+		if binding == nil || binding.Kind != resolver.LetBinding {
+			return unknownKind
+		}
+
+		// redeclared or reassigned -> the let's value may no longer be current
+		if optimizer.declarations[declarationKey{binding.Scope, binding.Name}] != 1 || len(binding.Assignments) > 0 {
+			return unknownKind
+		}
+
+		// a destructured name holds some part of the value, not all of it
+		let := binding.Declaration.(*ast.LetStatement)
+		if let.Name != binding.Identifier {
+			return unknownKind
+		}
+
+		return optimizer.kindOf(let.Value)
+	}
+
+	return unknownKind
+}
+
+
+// folding mirrors the evaluator: integers do arithmetic & comparisons,
+// == / != compare anything, everything else is a runtime error -> not folded
+func foldInfix(node *ast.InfixExpression) ast.Expression {
+	position := ast.Pos(node)
+
+	leftInt, leftIsInt   := node.Left.(*ast.IntegerLiteral)
+	rightInt, rightIsInt := node.Right.(*ast.IntegerLiteral)
+
+	if leftIsInt && rightIsInt {
+		left, right := leftInt.Value, rightInt.Value
+
+		switch node.Operator {
+		case "+":
+			return integerLiteral(position, left+right)
+		case "-":
+			return integerLiteral(position, left-right)
+		case "*":
+			return integerLiteral(position, left*right)
+		case "/":
+			if right == 0 {
+				return nil
+			}
+			return integerLiteral(position, left/right)
+		case "<":
+			return booleanLiteral(position, left < right)
+		case ">":
+			return booleanLiteral(position, left > right)
+		case "==":
+			return booleanLiteral(position, left == right)
+		case "!=":
+			return booleanLiteral(position, left != right)
+		}
+
+		return nil
+	}
+
+	if !isLiteral(node.Left) || !isLiteral(node.Right) {
+		return nil
+	}
+
+	// mixed or boolean operands: only (in)equality is defined
+	leftBool, leftIsBool   := node.Left.(*ast.Boolean)
+	rightBool, rightIsBool := node.Right.(*ast.Boolean)
+	equal := leftIsBool && rightIsBool && leftBool.Value == rightBool.Value
+
+	switch node.Operator {
+	case "==":
+		return booleanLiteral(position, equal)
+	case "!=":
+		return booleanLiteral(position, !equal)
+	}
+
+	return nil
+}
+
+// "text"[start..end] with literal (or missing) bounds -> the sliced text
+func foldSlice(node *ast.IndexExpression) ast.Expression {
+	text, isText   := node.Left.(*ast.StringLiteral)
+	slice, isSlice := node.Index.(*ast.RangeExpression)
+	if !isText || !isSlice {
+		return nil
+	}
+
+	bounds := []int64{0, int64(len(text.Value))}
+	for i, bound := range []ast.Expression{slice.Start, slice.End} {
+		if bound == nil {
+			continue
+		}
+
+		literal, ok := bound.(*ast.IntegerLiteral)
+		if !ok {
+			return nil
+		}
+		bounds[i] = literal.Value
+	}
+
+	start, end := object.SliceBounds(int64(len(text.Value)), bounds[0], bounds[1], slice.Inclusive && slice.End != nil)
+
+	return &ast.StringLiteral{
+		Token: token.Token{Type: token.STRING, Literal: text.Value[start:end], Position: ast.Pos(node)},
+		Value: text.Value[start:end],
+	}
+}
+
+// if (true) { a } else { b } -> if (true) { a }
+// if (false) { a } else { b } -> if (true) { b }
+func pruneBranches(node *ast.IfExpression) {
+	truthy, constant := truthiness(node.Condition)
+	if !constant {
+		return
+	}
+
+	if truthy {
+		node.Alternative = nil
+		return
+	}
+
+	if node.Alternative != nil {
+		node.Condition   = booleanLiteral(ast.Pos(node.Condition), true)
+		node.Consequence = node.Alternative
+		node.Alternative = nil
+	}
+}
+
+// `if (true) { a; b }` as a statement -> `a; b`, `if (false) { a }` -> removed.
+// A block declaring names stays a block, so they remain scoped to it.
+func inlineConstantIf(cursor *ast.Cursor, statement *ast.ExpressionStatement) {
+	ifExp, ok := statement.Expression.(*ast.IfExpression)
+	if !ok || ifExp.Alternative != nil {
+		return
+	}
+
+	truthy, constant := truthiness(ifExp.Condition)
+	if !constant {
+		return
+	}
+
+	// the last statement of a block is its value: keep `null`-producing ifs there
+	isLast := cursor.Index() == statementCount(cursor.Parent())-1
+
+	switch {
+	case truthy && declaresNames(ifExp.Consequence):
+		cursor.Replace(ifExp.Consequence)
+
+	case truthy && len(ifExp.Consequence.Statements) > 0:
+		for _, inner := range ifExp.Consequence.Statements {
+			cursor.InsertBefore(inner)
+		}
+		cursor.Delete()
+
+	case !truthy && !isLast:
+		cursor.Delete()
+	}
+}
+
+// `while (false) { a }` never runs its body -> removed
+func removeDeadLoop(cursor *ast.Cursor, loop *ast.WhileStatement) {
+	truthy, constant := truthiness(loop.Condition)
+	isLast := cursor.Index() == statementCount(cursor.Parent())-1
+
+	if constant && !truthy && !isLast {
+		cursor.Delete()
+	}
+}
+
+// the evaluator treats everything but false (and null) as true
+func truthiness(condition ast.Expression) (truthy bool, constant bool) {
+	switch node := condition.(type) {
+	case *ast.Boolean:
+		return node.Value, true
+	case *ast.IntegerLiteral:
+		return true, true
+	case *ast.NullLiteral:
+		return false, true
+	}
+
+	return false, false
+}
+
+// whether block has a let or function declaration of its own
+func declaresNames(block *ast.BlockStatement) bool {
+	for _, statement := range block.Statements {
+		switch statement.(type) {
+		case *ast.LetStatement, *ast.FunctionDeclaration:
+			return true
+		}
+	}
+
+	return false
+}
+
+func statementCount(parent ast.Node) int {
+	switch node := parent.(type) {
+	case *ast.Program:
+		return len(node.Statements)
+	case *ast.BlockStatement:
+		return len(node.Statements)
+	}
+
+	return 0
+}
+
+func isLiteral(expression ast.Expression) bool {
+	switch expression.(type) {
+	case *ast.IntegerLiteral, *ast.Boolean:
+		return true
+	}
+
+	return false
+}
+
+// folded nodes keep the position of the expression they replace
+func integerLiteral(position token.Position, value int64) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{
+		Token: token.Token{
+			Type:     token.INT,
+			Literal:  strconv.FormatInt(value, 10),
+			Position: position,
+		},
+		Value: value,
+	}
+}
+
+func booleanLiteral(position token.Position, value bool) *ast.Boolean {
+	boolean := &ast.Boolean{
+		Token: token.Token{Type: token.FALSE, Literal: "false", Position: position},
+		Value: value,
+	}
+
+	if value {
+		boolean.Token.Type    = token.TRUE
+		boolean.Token.Literal = "true"
+	}
+
+	return boolean
+}
+
+//---[ Optimizer Helper Methods ]-----------------------------------------------
@@ -0,0 +1,171 @@
+package optimizer
+
+import (
+	"testing"
+
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+)
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func TestConstantFolding(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(10 - 4) / 3", "2"},
+		{"7 / 2", "3"},
+		{"-(2 * 3)", "-6"},
+		{"!!true", "true"},
+		{"!5", "false"},
+		{"1 < 2 == true", "true"},
+		{"3 > 4 != false", "false"},
+		{"true == false", "false"},
+		{"1 == true", "false"},
+		{"1 != true", "true"},
+
+		// runtime errors must survive
+		{"1 / 0", "(1 / 0)"},
+		{"1 / (2 - 2)", "(1 / 0)"},
+		{"true + false", "(true + false)"},
+		{"-true", "(-true)"},
+		{"true < false", "(true < false)"},
+
+		// slices of string literals: negative bounds count from the end
+		{`"hello"[1..3]`, `"el"`},
+		{`"hello"[1..=3]`, `"ell"`},
+		{`"hello"[..2]`, `"he"`},
+		{`"hello"[-3..]`, `"llo"`},
+		{`"hello"[1..-1]`, `"ell"`},
+		{`"hello"[..=-2]`, `"hell"`},
+		{`"hello"[-10..10]`, `"hello"`},
+		{`"hello"[4..2]`, `""`},
+		{`"hello"[1 + 1..]`, `"llo"`},
+		{`"hello"[n..]`, `("hello"[(n..)])`},
+	}
+
+	for _, test := range tests {
+		testOptimize(t, test.input, test.expected)
+	}
+}
+
+func TestAlgebraicSimplification(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let x = 5; (2 * 3) + x * 1", "let x = 5;(6 + x)"},
+		{"let x = 5; 0 + x - 0", "let x = 5;x"},
+		{"let x = 5; x / 1", "let x = 5;x"},
+		{"let x = 5; --x", "let x = 5;x"},
+		{"let b = 1 < 2; !!b", "let b = true;b"},
+		{"let n = 2 + 2; let m = n * 1; m + 0", "let n = 4;let m = n;m"},
+		{"let a = 1; let b = 2; (a + b) * 1", "let a = 1;let b = 2;(a + b)"},
+
+		// unknown operand types: simplifying could hide a type error
+		{"x * 1", "(x * 1)"},
+		{"let b = true; b + 0", "let b = true;(b + 0)"},
+		{"fn(x) { x * 1 }", "fn(x)(x * 1)"},
+		{"fn(a, b) { (a + b) * 1 }", "fn(a, b)((a + b) * 1)"},
+		{`let s = "a"; (s + s) * 1`, `let s = "a";((s + s) * 1)`},
+		{"let x = 5; let x = true; x * 1", "let x = 5;let x = true;(x * 1)"},
+		{"!!x", "(!(!x))"},
+		{"let x = 5; x = true; x * 1", "let x = 5;(x = true)(x * 1)"},
+		{"let n = 1; n += 0 + 2", "let n = 1;(n += 2)"},
+	}
+
+	for _, test := range tests {
+		testOptimize(t, test.input, test.expected)
+	}
+}
+
+func TestIfPruning(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"if (1 < 2) { a; b }; c", "abc"},
+		{"if (1 > 2) { a } else { b; c }; d", "bcd"},
+		{"if (false) { a }; b", "b"},
+		{"if (null) { a } else { b }; c", "bc"},
+		{"if (false) { a }", "iffalse a"},
+		{"let v = if (true) { a } else { b };", "let v = iftrue a;"},
+		{"let v = if (0) { a } else { b };", "let v = if0 a;"},
+		{"let v = if (!true) { a } else { b };", "let v = iftrue b;"},
+		{"fn() { if (2 == 2) { return 1; }; 2 }", "fn()return 1;2"},
+		{"if (x) { a } else { b }", "ifx aelseb"},
+		{"if (false) { a } else if (x) { b } else { c }; d", "ifx belsecd"},
+		{"if (x) { a } else if (1 < 2) { b } else { c }", "ifx aelseb"},
+		{"while (1 > 2) { a }; b", "b"},
+		{"while (false) { a }", "whilefalse a"},
+		{"while (x < 1 + 1) { a }", "while(x < 2) a"},
+	}
+
+	for _, test := range tests {
+		testOptimize(t, test.input, test.expected)
+	}
+}
+
+func TestFoldedNodesKeepPosition(t *testing.T) {
+	program := parse(t, "let x = 5;\nlet y = 2 * 3 + 1;")
+	Optimize(program)
+
+	value := program.Statements[1].(*ast.LetStatement).Value
+	if position := ast.Pos(value); position.String() != "2:9" {
+		t.Errorf("folded literal position wrong. got=%s", position)
+	}
+}
+
+func TestIfPruningKeepsScopes(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let x = 1; if (true) { let x = 2; }; x", "let x = 2;"},
+		{"if (true) { fn g() { 1 } }; g()", "fn g()1"},
+	}
+
+	for _, test := range tests {
+		program := Optimize(parse(t, test.input))
+
+		var block *ast.BlockStatement
+		for _, statement := range program.Statements {
+			if statement, ok := statement.(*ast.BlockStatement); ok {
+				block = statement
+			}
+		}
+
+		if block == nil || block.String() != test.expected {
+			t.Errorf("%q - declarations not kept in a block. got=%q", test.input, program.String())
+		}
+	}
+}
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func parse(t *testing.T, input string) *ast.Program {
+	p       := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return program
+}
+
+func testOptimize(t *testing.T, input string, expected string) {
+	actual := Optimize(parse(t, input)).String()
+
+	if actual != expected {
+		t.Errorf("%q - wrong result. want=%q, got=%q", input, expected, actual)
+	}
+}
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
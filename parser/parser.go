@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"monkey/ast"
 	"monkey/lexer"
@@ -17,12 +18,18 @@ type (
 const (
 	_ int = iota
 	LOWEST      
+	ASSIGN       // =, +=
+	NULLISH      // ??
+	PIPE         // |>
 	EQUALS       // ==
 	LESSGREATER  // <, >
+	RANGE        // a..b, a..=b
 	SUM          // +
 	PRODUCT      // *
 	PREFIX       // -x, !x
 	CALL         // f()
+	INDEX        // xs[i]
+	MEMBER       // x.name
 )
 
 var precedences = map[token.TokenType]int{
@@ -34,41 +41,83 @@ var precedences = map[token.TokenType]int{
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.LPAREN:   CALL,
+	token.LBRACKET: INDEX,
+	token.DOT:      MEMBER,
+	token.NULLISH:  NULLISH,
+
+	token.RANGE:           RANGE,
+	token.RANGE_INCLUSIVE: RANGE,
+
+	token.OPTIONAL_DOT:      MEMBER,
+	token.OPTIONAL_LBRACKET: INDEX,
+	token.PIPE:     PIPE,
+
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
 }
 
 type Parser struct {
 	lex       *lexer.Lexer
-	errors    []string
+	errors    []Error
 
 	currToken token.Token
 	peekToken token.Token
 
+	loopDepth  int  // loops around the current statement (reset in function bodies)
+	blockDepth int  // blocks around the current statement, 0 -> top level
+
 	prefixParseMap map[token.TokenType]prefixParseFn
 	infixParseMap  map[token.TokenType]infixParseFn
 }
 
+// Error is a syntax error found at Position (usually the offending token).
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (err Error) String() string {
+	if !err.Position.IsValid() {
+		return err.Message
+	}
+
+	return fmt.Sprintf("%s: %s", err.Position, err.Message)
+}
+
 
 //---[ Module API Functions ]---------------------------------------------------
 
 func New(lex *lexer.Lexer) *Parser {
 	parser := &Parser{
 		lex:    lex,
-		errors: []string{},
+		errors: []Error{},
 	}
 
 	// register tokens + associated parse functions
 	parser.prefixParseMap = make(map[token.TokenType]prefixParseFn)
 	parser.registerPrefix(token.IDENT, parser.parseIdentifier)
-	parser.registerPrefix(token.INT,   parser.parseIntegerLiteral)
+	parser.registerPrefix(token.INT,    parser.parseIntegerLiteral)
+	parser.registerPrefix(token.STRING, parser.parseStringLiteral)
+	parser.registerPrefix(token.TEMPLATE, parser.parseTemplateLiteral)
+	parser.registerPrefix(token.LBRACKET, parser.parseArrayLiteral)
+	parser.registerPrefix(token.LBRACE,   parser.parseHashLiteral)
 	parser.registerPrefix(token.BANG,  parser.parsePrefixExpression)
 	parser.registerPrefix(token.MINUS, parser.parsePrefixExpression)
 
 	parser.registerPrefix(token.TRUE,  parser.parseBoolean)
 	parser.registerPrefix(token.FALSE, parser.parseBoolean)
+	parser.registerPrefix(token.NULL,  parser.parseNullLiteral)
 
 	parser.registerPrefix(token.LPAREN, parser.parseGroupedExpression)
 
 	parser.registerPrefix(token.IF,       parser.parseIfExpression)
+	parser.registerPrefix(token.MATCH,    parser.parseMatchExpression)
+	parser.registerPrefix(token.TRY,      parser.parseTryExpression)
+	parser.registerPrefix(token.MACRO,    parser.parseMacroLiteral)
 	parser.registerPrefix(token.FUNCTION, parser.parseFunctionLiteral)
 
 	parser.infixParseMap = make(map[token.TokenType]infixParseFn)
@@ -81,6 +130,25 @@ func New(lex *lexer.Lexer) *Parser {
 	parser.registerInfix(token.LT,       parser.parseInfixExpression)
 	parser.registerInfix(token.GT,       parser.parseInfixExpression)
 
+	parser.registerInfix(token.NULLISH,  parser.parseInfixExpression)
+	parser.registerInfix(token.PIPE,     parser.parsePipeExpression)
+
+	parser.registerInfix(token.LPAREN,   parser.parseCallExpression)
+	parser.registerInfix(token.LBRACKET, parser.parseIndexExpression)
+	parser.registerInfix(token.DOT,      parser.parseMemberExpression)
+
+	parser.registerInfix(token.OPTIONAL_DOT,      parser.parseMemberExpression)
+	parser.registerInfix(token.OPTIONAL_LBRACKET, parser.parseIndexExpression)
+
+	parser.registerInfix(token.RANGE,           parser.parseRangeExpression)
+	parser.registerInfix(token.RANGE_INCLUSIVE, parser.parseRangeExpression)
+
+	parser.registerInfix(token.ASSIGN,          parser.parseAssignExpression)
+	parser.registerInfix(token.PLUS_ASSIGN,     parser.parseAssignExpression)
+	parser.registerInfix(token.MINUS_ASSIGN,    parser.parseAssignExpression)
+	parser.registerInfix(token.ASTERISK_ASSIGN, parser.parseAssignExpression)
+	parser.registerInfix(token.SLASH_ASSIGN,    parser.parseAssignExpression)
+
 	// sets currToken & peekToken
 	parser.nextToken()
 	parser.nextToken()
@@ -112,7 +180,18 @@ func (parser *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// Errors returns the messages of the syntax errors found so far.
 func (parser *Parser) Errors() []string {
+	messages := []string{}
+	for _, err := range parser.errors {
+		messages = append(messages, err.Message)
+	}
+
+	return messages
+}
+
+// Diagnostics returns the syntax errors found so far with their positions.
+func (parser *Parser) Diagnostics() []Error {
 	return parser.errors
 }
 
@@ -128,14 +207,48 @@ func (parser *Parser) nextToken() {
 }
 
 func (parser *Parser) parseStatement() ast.Statement {
+	// typed nil pointers must not leak into the ast.Statement interface
 	switch parser.currToken.Type {
 	case token.LET:
-		return parser.parseLetStatement()
+		if statement := parser.parseLetStatement(); statement != nil {
+			return statement
+		}
 	case token.RETURN:
 		return parser.parseReturnStatement()
+	case token.THROW:
+		return parser.parseThrowStatement()
+	case token.WHILE:
+		if statement := parser.parseWhileStatement(); statement != nil {
+			return statement
+		}
+	case token.FOR:
+		if statement := parser.parseForInStatement(); statement != nil {
+			return statement
+		}
+	case token.BREAK, token.CONTINUE:
+		return parser.parseLoopControlStatement()
+	case token.IMPORT:
+		if statement := parser.parseImportStatement(); statement != nil {
+			return statement
+		}
+	case token.EXPORT:
+		if statement := parser.parseExportStatement(); statement != nil {
+			return statement
+		}
+	case token.FUNCTION:
+		// `fn name(...)` declares, `fn(...)` is a literal
+		if !parser.peekTokenIs(token.IDENT) {
+			return parser.parseExpressionStatement()
+		}
+
+		if statement := parser.parseFunctionDeclaration(); statement != nil {
+			return statement
+		}
 	default:
 		return parser.parseExpressionStatement()
 	}
+
+	return nil
 }
 
 func (parser *Parser) parseLetStatement() *ast.LetStatement {
@@ -143,174 +256,376 @@ func (parser *Parser) parseLetStatement() *ast.LetStatement {
 		Token: parser.currToken,
 	}
 
-	if !parser.expectPeek(token.IDENT) {
-		return nil
-	}
+	switch {
+	case parser.peekTokenIs(token.LBRACKET), parser.peekTokenIs(token.LBRACE):
+		parser.nextToken()
+		if statement.Pattern = parser.parsePattern(map[string]bool{}); statement.Pattern == nil {
+			return nil
+		}
 
-	statement.Name = &ast.Identifier{
-		Token: parser.currToken,
-		Value: parser.currToken.Literal,
+		// a let has no other arm to fall back to
+		if refutable := refutablePart(statement.Pattern); refutable != nil {
+			parser.errorAt(ast.Pos(refutable), "literal pattern %s cannot be used in let", refutable)
+			return nil
+		}
+
+	case parser.expectPeek(token.IDENT):
+		if statement.Name = parser.parseTypedIdentifier(); statement.Name == nil {
+			return nil
+		}
+
+	default:
+		return nil
 	}
 
 	if !parser.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
-	// TODO: skipping expressions until semicolon encountered
-	for !parser.currTokenIs(token.SEMICOLON) {
+	parser.nextToken()
+	statement.Value = parser.parseExpression(LOWEST)
+
+	// let add = fn(...) -> the literal is known as add
+	if literal, ok := statement.Value.(*ast.FunctionLiteral); ok && statement.Name != nil && literal.Name == "" {
+		literal.Name = statement.Name.Value
+	}
+
+	if parser.peekTokenIs(token.SEMICOLON) {
 		parser.nextToken()
 	}
 
 	return statement
 }
 
-func (parser *Parser) parseReturnStatement() *ast.ReturnStatement {
-	statement := &ast.ReturnStatement{
-		Token: parser.currToken,
+// helpers for patterns: `let [a, b] = xs;`, `match (x) { [1, y] => ... }`
+
+// pattern starting at the current token, nil if it is malformed. seen holds
+// the names bound so far, a name may only be bound once per pattern
+func (parser *Parser) parsePattern(seen map[string]bool) ast.Pattern {
+	switch parser.currToken.Type {
+	case token.IDENT:
+		if parser.currToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: parser.currToken}
+		}
+
+		return parser.parsePatternName(seen)
+
+	case token.INT, token.TRUE, token.FALSE, token.STRING, token.NULL:
+		return &ast.LiteralPattern{
+			Token: parser.currToken,
+			Value: parser.prefixParseMap[parser.currToken.Type](),
+		}
+
+	case token.MINUS:
+		pattern := &ast.LiteralPattern{Token: parser.currToken}
+		if !parser.expectPeek(token.INT) {
+			return nil
+		}
+
+		pattern.Value = &ast.PrefixExpression{
+			Token:    pattern.Token,
+			Operator: "-",
+			Right:    parser.parseIntegerLiteral(),
+		}
+
+		return pattern
+
+	case token.LBRACKET:
+		return parser.parseArrayPattern(seen)
+
+	case token.LBRACE:
+		return parser.parseHashPattern(seen)
 	}
 
-	parser.nextToken()
+	parser.errorf("expected pattern, got %s instead", parser.currToken.Type)
+	return nil
+}
 
-	// TODO: skipping expressions until semicolon encountered
-	for !parser.currTokenIs(token.SEMICOLON) {
-		parser.nextToken()
+func (parser *Parser) parsePatternName(seen map[string]bool) *ast.Identifier {
+	name := &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+
+	if seen[name.Value] {
+		parser.errorf("duplicate name %s in pattern", name.Value)
+		return nil
 	}
+	seen[name.Value] = true
 
-	return statement
+	return name
 }
 
-func (parser *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	statement := &ast.ExpressionStatement{
-		Token: parser.currToken,
+// [a, [b, _], ...rest]
+func (parser *Parser) parseArrayPattern(seen map[string]bool) ast.Pattern {
+	pattern := &ast.ArrayPattern{
+		Token:    parser.currToken,
+		Elements: []ast.Pattern{},
 	}
 
-	statement.Expression = parser.parseExpression(LOWEST)
+	if parser.peekTokenIs(token.RBRACKET) {
+		parser.nextToken()
+		return pattern
+	}
 
-	// optional semicolons -> easier REPL input
-	if parser.peekTokenIs(token.SEMICOLON) {
+	for {
 		parser.nextToken()
+
+		if parser.currTokenIs(token.ELLIPSIS) {
+			if !parser.expectPeek(token.IDENT) {
+				return nil
+			}
+
+			if pattern.Rest = parser.parsePatternName(seen); pattern.Rest == nil {
+				return nil
+			}
+
+			if !parser.peekTokenIs(token.RBRACKET) {
+				parser.errorAt(pattern.Rest.Token.Position, "rest element %s must be the last element", pattern.Rest.Value)
+				return nil
+			}
+		} else {
+			element := parser.parsePattern(seen)
+			if element == nil {
+				return nil
+			}
+
+			pattern.Elements = append(pattern.Elements, element)
+		}
+
+		if !parser.peekTokenIs(token.COMMA) {
+			break
+		}
+
+		parser.nextToken()   // onto the comma
 	}
 
-	return statement
+	if !parser.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
 }
 
-func (parser *Parser) parseBlockStatement() *ast.BlockStatement {
-	block := &ast.BlockStatement{
-		Token:      parser.currToken,
-		Statements: []ast.Statement{},
+// {name, kind: 1, point: [x, y], "full name": n} -> a string key needs a value
+func (parser *Parser) parseHashPattern(seen map[string]bool) ast.Pattern {
+	pattern := &ast.HashPattern{
+		Token:  parser.currToken,
+		Keys:   []*ast.Identifier{},
+		Values: []ast.Pattern{},
 	}
 
-	parser.nextToken()
+	if parser.peekTokenIs(token.RBRACE) {
+		parser.nextToken()
+		return pattern
+	}
 
-	for !parser.currTokenIs(token.EOF) && !parser.currTokenIs(token.RBRACE) {
-		statement := parser.parseStatement()
-		if statement != nil {
-			block.Statements = append(block.Statements, statement)
+	for {
+		parser.nextToken()
+
+		if !parser.currTokenIs(token.IDENT) && !parser.currTokenIs(token.STRING) {
+			parser.errorf("expected key in hash pattern, got %s instead", parser.currToken.Type)
+			return nil
 		}
 
-		parser.nextToken()
-	}
+		key := &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+		for _, previous := range pattern.Keys {
+			if previous.Value == key.Value {
+				parser.errorf("duplicate key %s in hash pattern", key.Value)
+				return nil
+			}
+		}
 
-	return block
-}
+		var value ast.Pattern
 
+		if parser.peekTokenIs(token.COLON) {
+			parser.nextToken()   // onto the colon
+			parser.nextToken()   // onto the value pattern
 
-func (parser *Parser) parseExpression(precedence int) ast.Expression {
-	prefixFunc, ok := parser.prefixParseMap[parser.currToken.Type]
-	if !ok {
-		parser.noPrefixParseFuncError(parser.currToken.Type)
-		return nil
+			if value = parser.parsePattern(seen); value == nil {
+				return nil
+			}
+		} else if key.Token.Type == token.STRING {
+			parser.peekError(token.COLON)
+			return nil
+		} else if parser.parsePatternName(seen) == nil {
+			// shorthand: the key is also the name bound
+			return nil
+		}
+
+		pattern.Keys   = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+
+		if !parser.peekTokenIs(token.COMMA) {
+			break
+		}
+
+		parser.nextToken()   // onto the comma
 	}
 
-	leftExp := prefixFunc()
+	if !parser.expectPeek(token.RBRACE) {
+		return nil
+	}
 
-	noSemicolonNext := !parser.peekTokenIs(token.SEMICOLON)
-	nextPrecLarger  := precedence < parser.peekPrecedence()
+	return pattern
+}
 
-	// keep building as long as the next precedence is larger
-	for noSemicolonNext && nextPrecLarger {
-		infixFunc, ok := parser.infixParseMap[parser.peekToken.Type]
-		if !ok {
-			return leftExp
+// first pattern nested in pattern that does not match every value (of the
+// right shape), nil if there is none
+func refutablePart(pattern ast.Pattern) ast.Pattern {
+	switch node := pattern.(type) {
+	case *ast.LiteralPattern:
+		return node
+
+	case *ast.ArrayPattern:
+		for _, element := range node.Elements {
+			if refutable := refutablePart(element); refutable != nil {
+				return refutable
+			}
 		}
 
-		parser.nextToken()
-		leftExp = infixFunc(leftExp)
-
-		noSemicolonNext = !parser.peekTokenIs(token.SEMICOLON)
-		nextPrecLarger  = precedence < parser.peekPrecedence()
+	case *ast.HashPattern:
+		for i := range node.Keys {
+			if refutable := refutablePart(node.Value(i)); refutable != nil {
+				return refutable
+			}
+		}
 	}
 
-	return leftExp
+	return nil
 }
 
-func (parser *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{
+func (parser *Parser) parseFunctionDeclaration() *ast.FunctionDeclaration {
+	statement := &ast.FunctionDeclaration{
+		Token: parser.currToken,
+	}
+
+	parser.nextToken()
+	statement.Name = &ast.Identifier{
 		Token: parser.currToken,
 		Value: parser.currToken.Literal,
 	}
+
+	// continues after the name as if it were `fn(...) { ... }`
+	literal, ok := parser.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !ok {
+		return nil
+	}
+
+	literal.Token      = statement.Token
+	literal.Name       = statement.Name.Value
+	statement.Function = literal
+
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
 }
 
-func (parser *Parser) parseIntegerLiteral() ast.Expression {
-	literal := &ast.IntegerLiteral{
+// import "path" as name; -> only at the top level of a program
+func (parser *Parser) parseImportStatement() *ast.ImportStatement {
+	statement := &ast.ImportStatement{
 		Token: parser.currToken,
 	}
 
-	value, err := strconv.ParseInt(parser.currToken.Literal, 0, 64)
-	if err != nil {
-		errMsg := fmt.Sprintf("could not parse %q as int64", parser.currToken.Literal)
-		parser.errors = append(parser.errors, errMsg)
-		
+	if parser.blockDepth > 0 {
+		parser.errorf("import must be at the top level")
+	}
+
+	if !parser.expectPeek(token.STRING) {
 		return nil
 	}
+	statement.Path = parser.currToken.Literal
 
-	literal.Value = value
+	if !parser.expectPeek(token.AS) || !parser.expectPeek(token.IDENT) {
+		return nil
+	}
 
-	return literal
+	statement.Name = &ast.Identifier{
+		Token: parser.currToken,
+		Value: parser.currToken.Literal,
+	}
+
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
 }
 
-func (parser *Parser) parseBoolean() ast.Expression {
-	return &ast.Boolean{
+// export let ...; / export fn name(...) { ... } -> only at the top level
+func (parser *Parser) parseExportStatement() *ast.ExportStatement {
+	statement := &ast.ExportStatement{
 		Token: parser.currToken,
-		Value: parser.currTokenIs(token.TRUE),
 	}
+
+	if parser.blockDepth > 0 {
+		parser.errorf("export must be at the top level")
+	}
+
+	parser.nextToken()
+
+	switch {
+	case parser.currTokenIs(token.LET):
+		if let := parser.parseLetStatement(); let != nil {
+			statement.Statement = let
+		}
+
+	case parser.currTokenIs(token.FUNCTION) && parser.peekTokenIs(token.IDENT):
+		if declaration := parser.parseFunctionDeclaration(); declaration != nil {
+			statement.Statement = declaration
+		}
+
+	default:
+		parser.errorf("expected let or fn name after export, got %s instead", parser.currToken.Type)
+	}
+
+	if statement.Statement == nil {
+		return nil
+	}
+
+	return statement
 }
 
-func (parser *Parser) parsePrefixExpression() ast.Expression {
-	expression := &ast.PrefixExpression{
-		Token:    parser.currToken,
-		Operator: parser.currToken.Literal,
+func (parser *Parser) parseReturnStatement() *ast.ReturnStatement {
+	statement := &ast.ReturnStatement{
+		Token: parser.currToken,
 	}
 
 	parser.nextToken()
-	expression.Right = parser.parseExpression(PREFIX)
+	statement.ReturnValue = parser.parseExpression(LOWEST)
 
-	return expression
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
 }
 
+func (parser *Parser) parseThrowStatement() *ast.ThrowStatement {
+	statement := &ast.ThrowStatement{
+		Token: parser.currToken,
+	}
 
-func (parser *Parser) parseGroupedExpression() ast.Expression {
 	parser.nextToken()
+	statement.Value = parser.parseExpression(LOWEST)
 
-	expression := parser.parseExpression(LOWEST)
-	if !parser.expectPeek(token.RPAREN) {
-		return nil
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
 	}
 
-	return expression
+	return statement
 }
 
-func (parser *Parser) parseIfExpression() ast.Expression {
-	expression := &ast.IfExpression{
+func (parser *Parser) parseWhileStatement() *ast.WhileStatement {
+	statement := &ast.WhileStatement{
 		Token: parser.currToken,
 	}
-	
+
 	if !parser.expectPeek(token.LPAREN) {
 		return nil
 	}
 
 	parser.nextToken()
-	expression.Condition = parser.parseExpression(LOWEST)
+	statement.Condition = parser.parseExpression(LOWEST)
 
 	if !parser.expectPeek(token.RPAREN) {
 		return nil
@@ -320,49 +635,688 @@ func (parser *Parser) parseIfExpression() ast.Expression {
 		return nil
 	}
 
-	expression.Consequence = parser.parseBlockStatement()
+	parser.loopDepth++
+	statement.Body = parser.parseBlockStatement()
+	parser.loopDepth--
 
-	if parser.peekTokenIs(token.ELSE) {
+	if parser.peekTokenIs(token.SEMICOLON) {
 		parser.nextToken()
-
-		if !parser.expectPeek(token.LBRACE) {
-			return nil
-		}
-
-		expression.Alternative = parser.parseBlockStatement()
 	}
 
-	return expression
+	return statement
 }
 
-func (parser *Parser) parseFunctionLiteral() ast.Expression {
-	literal := &ast.FunctionLiteral{
+// for (x in xs) { ... } or for (k, v in xs) { ... }
+func (parser *Parser) parseForInStatement() *ast.ForInStatement {
+	statement := &ast.ForInStatement{
 		Token: parser.currToken,
 	}
 
-	if !parser.expectPeek(token.LPAREN) {
+	if !parser.expectPeek(token.LPAREN) || !parser.expectPeek(token.IDENT) {
 		return nil
 	}
 
-	fmt.Println("[DEBUG] parseFunctionLiteral: made it past (")
+	statement.Value = &ast.Identifier{
+		Token: parser.currToken,
+		Value: parser.currToken.Literal,
+	}
+
+	if parser.peekTokenIs(token.COMMA) {
+		parser.nextToken()
 
-	literal.Parameters = parser.parseFunctionParameters()
+		if !parser.expectPeek(token.IDENT) {
+			return nil
+		}
 
-	fmt.Println("[DEBUG] parseFunctionLiteral: parsed parameters")
+		statement.Key   = statement.Value
+		statement.Value = &ast.Identifier{
+			Token: parser.currToken,
+			Value: parser.currToken.Literal,
+		}
+	}
 
-	if !parser.expectPeek(token.LBRACE) {
-		fmt.Println("[DEBUG] parseFunctionLiteral: next token was not {")
+	if !parser.expectPeek(token.IN) {
 		return nil
 	}
 
-	fmt.Println("[DEBUG] parseFunctionLiteral: made it past {")
+	parser.nextToken()
+	statement.Iterable = parser.parseExpression(LOWEST)
 
-	literal.Body = parser.parseBlockStatement()
+	if !parser.expectPeek(token.RPAREN) || !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
 
-	fmt.Println("[DEBUG] parseFunctionLiteral: made it past }")
+	parser.loopDepth++
+	statement.Body = parser.parseBlockStatement()
+	parser.loopDepth--
 
-	return literal
-}
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+// break / continue -> only valid inside a loop of the current function
+func (parser *Parser) parseLoopControlStatement() ast.Statement {
+	var statement ast.Statement = &ast.BreakStatement{Token: parser.currToken}
+	if parser.currTokenIs(token.CONTINUE) {
+		statement = &ast.ContinueStatement{Token: parser.currToken}
+	}
+
+	if parser.loopDepth == 0 {
+		parser.errorf("%s outside of a loop", parser.currToken.Literal)
+	}
+
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+	statement := &ast.ExpressionStatement{
+		Token: parser.currToken,
+	}
+
+	statement.Expression = parser.parseExpression(LOWEST)
+
+	// optional semicolons -> easier REPL input
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return statement
+}
+
+func (parser *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{
+		Token:      parser.currToken,
+		Statements: []ast.Statement{},
+	}
+
+	parser.nextToken()
+	parser.blockDepth++
+
+	for !parser.currTokenIs(token.EOF) && !parser.currTokenIs(token.RBRACE) {
+		statement := parser.parseStatement()
+		if statement != nil {
+			block.Statements = append(block.Statements, statement)
+		}
+
+		parser.nextToken()
+	}
+
+	parser.blockDepth--
+
+	return block
+}
+
+
+func (parser *Parser) parseExpression(precedence int) ast.Expression {
+	prefixFunc, ok := parser.prefixParseMap[parser.currToken.Type]
+	if !ok {
+		parser.noPrefixParseFuncError(parser.currToken.Type)
+		return nil
+	}
+
+	leftExp := prefixFunc()
+
+	noSemicolonNext := !parser.peekTokenIs(token.SEMICOLON)
+	nextPrecLarger  := precedence < parser.peekPrecedence()
+
+	// keep building as long as the next precedence is larger
+	for noSemicolonNext && nextPrecLarger {
+		infixFunc, ok := parser.infixParseMap[parser.peekToken.Type]
+		if !ok {
+			return leftExp
+		}
+
+		parser.nextToken()
+		leftExp = infixFunc(leftExp)
+
+		noSemicolonNext = !parser.peekTokenIs(token.SEMICOLON)
+		nextPrecLarger  = precedence < parser.peekPrecedence()
+	}
+
+	return leftExp
+}
+
+func (parser *Parser) parseIdentifier() ast.Expression {
+	return &ast.Identifier{
+		Token: parser.currToken,
+		Value: parser.currToken.Literal,
+	}
+}
+
+func (parser *Parser) parseIntegerLiteral() ast.Expression {
+	literal := &ast.IntegerLiteral{
+		Token: parser.currToken,
+	}
+
+	value, err := strconv.ParseInt(parser.currToken.Literal, 0, 64)
+	if err != nil {
+		parser.errorf("could not parse %q as int64", parser.currToken.Literal)
+		return nil
+	}
+
+	literal.Value = value
+
+	return literal
+}
+
+func (parser *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: parser.currToken}
+}
+
+func (parser *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{
+		Token: parser.currToken,
+		Value: parser.currToken.Literal,
+	}
+}
+
+// every ${} of the template is parsed by a parser of its own, whose lexer
+// starts where the expression does: nodes and errors inside carry their
+// position in the whole source
+func (parser *Parser) parseTemplateLiteral() ast.Expression {
+	template := &ast.TemplateLiteral{Token: parser.currToken}
+	text     := ""
+
+	for _, segment := range lexer.SplitTemplate(parser.currToken) {
+		if !segment.Expression {
+			text += segment.Text
+			continue
+		}
+
+		if strings.TrimSpace(segment.Text) == "" {
+			parser.errorAt(segment.Position, "empty ${} in template")
+			continue
+		}
+
+		nested           := New(lexer.NewAt(segment.Text, segment.Position))
+		nested.loopDepth  = parser.loopDepth
+		nested.blockDepth = parser.blockDepth + 1
+
+		expression := nested.parseExpression(LOWEST)
+		if !nested.peekTokenIs(token.EOF) {
+			nested.errorAt(nested.peekToken.Position, "expected end of template expression, got %s instead", nested.peekToken.Type)
+		}
+
+		parser.errors = append(parser.errors, nested.errors...)
+
+		if expression == nil {
+			continue
+		}
+
+		template.Strings     = append(template.Strings, text)
+		template.Expressions = append(template.Expressions, expression)
+		text = ""
+	}
+
+	template.Strings = append(template.Strings, text)
+
+	return template
+}
+
+func (parser *Parser) parseBoolean() ast.Expression {
+	return &ast.Boolean{
+		Token: parser.currToken,
+		Value: parser.currTokenIs(token.TRUE),
+	}
+}
+
+func (parser *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    parser.currToken,
+		Operator: parser.currToken.Literal,
+	}
+
+	parser.nextToken()
+	expression.Right = parser.parseExpression(PREFIX)
+
+	return expression
+}
+
+
+func (parser *Parser) parseGroupedExpression() ast.Expression {
+	parser.nextToken()
+
+	expression := parser.parseExpression(LOWEST)
+	if !parser.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return expression
+}
+
+func (parser *Parser) parseIfExpression() ast.Expression {
+	expression := &ast.IfExpression{
+		Token: parser.currToken,
+	}
+	
+	if !parser.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	parser.nextToken()
+	expression.Condition = parser.parseExpression(LOWEST)
+
+	if !parser.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Consequence = parser.parseBlockStatement()
+
+	if parser.peekTokenIs(token.ELSE) {
+		parser.nextToken()
+
+		// else if (...) { } -> the nested if is the only statement of the alternative
+		if parser.peekTokenIs(token.IF) {
+			parser.nextToken()
+
+			nested := parser.parseIfExpression()
+			if nested == nil {
+				return nil
+			}
+
+			expression.Alternative = ast.ElseIf(nested.(*ast.IfExpression))
+			return expression
+		}
+
+		if !parser.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Alternative = parser.parseBlockStatement()
+	}
+
+	return expression
+}
+
+// macro(a, b) { body }: plain parameter names only
+func (parser *Parser) parseMacroLiteral() ast.Expression {
+	literal := &ast.MacroLiteral{
+		Token:      parser.currToken,
+		Parameters: []*ast.Identifier{},
+	}
+
+	if !parser.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	for !parser.peekTokenIs(token.RPAREN) {
+		if len(literal.Parameters) > 0 && !parser.expectPeek(token.COMMA) {
+			return nil
+		}
+
+		if !parser.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		literal.Parameters = append(literal.Parameters, &ast.Identifier{
+			Token: parser.currToken,
+			Value: parser.currToken.Literal,
+		})
+	}
+
+	parser.nextToken()   // onto the )
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	loopDepth := parser.loopDepth
+	parser.loopDepth = 0
+
+	literal.Body = parser.parseBlockStatement()
+	parser.loopDepth = loopDepth
+
+	return literal
+}
+
+// try { ... } catch (e) { ... } finally { ... } -> catch and / or finally
+func (parser *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{
+		Token: parser.currToken,
+	}
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = parser.parseBlockStatement()
+
+	if parser.peekTokenIs(token.CATCH) {
+		parser.nextToken()
+
+		if !parser.expectPeek(token.LPAREN) || !parser.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		expression.Parameter = &ast.Identifier{
+			Token: parser.currToken,
+			Value: parser.currToken.Literal,
+		}
+
+		if !parser.expectPeek(token.RPAREN) || !parser.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Handler = parser.parseBlockStatement()
+	}
+
+	if parser.peekTokenIs(token.FINALLY) {
+		parser.nextToken()
+
+		if !parser.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Finally = parser.parseBlockStatement()
+	}
+
+	if expression.Handler == nil && expression.Finally == nil {
+		parser.errorf("try without catch or finally")
+		return nil
+	}
+
+	return expression
+}
+
+// [a, b, c] -> a first element followed by `for` starts a comprehension instead
+func (parser *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{
+		Token:    parser.currToken,
+		Elements: []ast.Expression{},
+	}
+
+	if parser.peekTokenIs(token.RBRACKET) {
+		parser.nextToken()
+		return array
+	}
+
+	for {
+		parser.nextToken()
+		element := parser.parseExpression(LOWEST)
+
+		if len(array.Elements) == 0 && parser.peekTokenIs(token.FOR) {
+			return parser.parseArrayComprehension(array.Token, element)
+		}
+
+		array.Elements = append(array.Elements, element)
+
+		if !parser.peekTokenIs(token.COMMA) {
+			break
+		}
+		parser.nextToken()
+	}
+
+	if !parser.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return array
+}
+
+// {key: value, ...} -> a first entry followed by `for` starts a comprehension instead
+func (parser *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{
+		Token:  parser.currToken,
+		Keys:   []ast.Expression{},
+		Values: []ast.Expression{},
+	}
+
+	if parser.peekTokenIs(token.RBRACE) {
+		parser.nextToken()
+		return hash
+	}
+
+	for {
+		parser.nextToken()
+		key := parser.parseExpression(LOWEST)
+
+		if !parser.expectPeek(token.COLON) {
+			return nil
+		}
+
+		parser.nextToken()
+		value := parser.parseExpression(LOWEST)
+
+		if len(hash.Keys) == 0 && parser.peekTokenIs(token.FOR) {
+			return parser.parseHashComprehension(hash.Token, key, value)
+		}
+
+		hash.Keys   = append(hash.Keys, key)
+		hash.Values = append(hash.Values, value)
+
+		if !parser.peekTokenIs(token.COMMA) {
+			break
+		}
+		parser.nextToken()
+	}
+
+	if !parser.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
+// [value for x in xs if filter], value is parsed already
+func (parser *Parser) parseArrayComprehension(start token.Token, value ast.Expression) ast.Expression {
+	comprehension := &ast.Comprehension{Token: start, Value: value}
+
+	if !parser.parseGenerators(comprehension) || !parser.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return comprehension
+}
+
+// {key: value for (k, v) in h if filter}, key and value are parsed already
+func (parser *Parser) parseHashComprehension(start token.Token, key, value ast.Expression) ast.Expression {
+	comprehension := &ast.Comprehension{Token: start, Key: key, Value: value}
+
+	if !parser.parseGenerators(comprehension) || !parser.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return comprehension
+}
+
+// for x in xs if a ... for (k, v) in h ... -> at least one generator, each
+// with any number of filters
+func (parser *Parser) parseGenerators(comprehension *ast.Comprehension) bool {
+	if !parser.expectPeek(token.FOR) {
+		return false
+	}
+
+	for {
+		generator := &ast.Generator{Token: parser.currToken}
+
+		if parser.peekTokenIs(token.LPAREN) {
+			parser.nextToken()
+
+			if !parser.expectPeek(token.IDENT) {
+				return false
+			}
+			generator.Key = &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+
+			if !parser.expectPeek(token.COMMA) || !parser.expectPeek(token.IDENT) {
+				return false
+			}
+			generator.Value = &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+
+			if !parser.expectPeek(token.RPAREN) {
+				return false
+			}
+
+			if generator.Key.Value == generator.Value.Value {
+				parser.errorAt(generator.Value.Token.Position, "duplicate name %s in generator", generator.Key.Value)
+			}
+		} else {
+			if !parser.expectPeek(token.IDENT) {
+				return false
+			}
+			generator.Value = &ast.Identifier{Token: parser.currToken, Value: parser.currToken.Literal}
+		}
+
+		if !parser.expectPeek(token.IN) {
+			return false
+		}
+
+		parser.nextToken()
+		generator.Iterable = parser.parseExpression(LOWEST)
+
+		for parser.peekTokenIs(token.IF) {
+			parser.nextToken()
+			parser.nextToken()
+
+			generator.Filters = append(generator.Filters, parser.parseExpression(LOWEST))
+		}
+
+		comprehension.Generators = append(comprehension.Generators, generator)
+
+		if !parser.peekTokenIs(token.FOR) {
+			return true
+		}
+		parser.nextToken()
+	}
+}
+
+// match (subject) { pattern [if guard] => body, ... }
+func (parser *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{
+		Token: parser.currToken,
+		Arms:  []*ast.MatchArm{},
+	}
+
+	if !parser.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	parser.nextToken()
+	expression.Subject = parser.parseExpression(LOWEST)
+
+	if !parser.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !parser.peekTokenIs(token.RBRACE) {
+		parser.nextToken()
+
+		arm := parser.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+
+		expression.Arms = append(expression.Arms, arm)
+
+		// arms are separated by commas, optional after a block body
+		if parser.peekTokenIs(token.COMMA) {
+			parser.nextToken()
+		} else if !parser.currTokenIs(token.RBRACE) && !parser.peekTokenIs(token.RBRACE) {
+			parser.peekError(token.COMMA)
+			return nil
+		}
+	}
+
+	parser.nextToken()   // onto the closing brace
+
+	if len(expression.Arms) == 0 {
+		parser.errorf("match without arms")
+		return nil
+	}
+
+	return expression
+}
+
+func (parser *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{
+		Token: parser.currToken,
+	}
+
+	if arm.Pattern = parser.parsePattern(map[string]bool{}); arm.Pattern == nil {
+		return nil
+	}
+
+	if parser.peekTokenIs(token.IF) {
+		parser.nextToken()
+		parser.nextToken()
+
+		arm.Guard = parser.parseExpression(LOWEST)
+	}
+
+	if !parser.expectPeek(token.FAT_ARROW) {
+		return nil
+	}
+
+	parser.nextToken()
+
+	if parser.currTokenIs(token.LBRACE) {
+		arm.Body = parser.parseBlockStatement()
+		return arm
+	}
+
+	// `=> expression` is kept as a block of one expression statement
+	body := &ast.ExpressionStatement{
+		Token:      parser.currToken,
+		Expression: parser.parseExpression(LOWEST),
+	}
+
+	arm.Body = &ast.BlockStatement{
+		Token:      body.Token,
+		Statements: []ast.Statement{body},
+	}
+
+	return arm
+}
+
+func (parser *Parser) parseFunctionLiteral() ast.Expression {
+	literal := &ast.FunctionLiteral{
+		Token: parser.currToken,
+	}
+
+	if !parser.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !parser.parseFunctionParameters(literal) {
+		return nil
+	}
+
+	if parser.peekTokenIs(token.ARROW) {
+		parser.nextToken()
+		parser.nextToken()
+
+		if literal.ReturnType = parser.parseTypeExpression(); literal.ReturnType == nil {
+			return nil
+		}
+	}
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// loops around the literal do not continue into its body
+	loopDepth := parser.loopDepth
+	parser.loopDepth = 0
+
+	literal.Body = parser.parseBlockStatement()
+	parser.loopDepth = loopDepth
+
+	return literal
+}
 
 
 func (parser *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
@@ -380,6 +1334,157 @@ func (parser *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 }
 
 
+func (parser *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	return &ast.CallExpression{
+		Token:     parser.currToken,
+		Function:  function,
+		Arguments: parser.parseCallArguments(),
+	}
+}
+
+// x |> f(y) -> f(x, y), x |> f -> f(x). The call keeps the |> token so it is
+// printed as written
+func (parser *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	pipe := parser.currToken
+
+	parser.nextToken()
+	right := parser.parseExpression(PIPE)
+
+	switch node := right.(type) {
+	case *ast.CallExpression:
+		if node.IsPiped() {
+			break   // only from a parenthesized pipeline, which is no call
+		}
+
+		node.Token     = pipe
+		node.Arguments = append([]ast.Expression{left}, node.Arguments...)
+		return node
+
+	case *ast.Identifier, *ast.FunctionLiteral:
+		return &ast.CallExpression{
+			Token:     pipe,
+			Function:  node,
+			Arguments: []ast.Expression{left},
+		}
+
+	case nil:
+		return nil   // already reported
+	}
+
+	parser.errorAt(ast.Pos(right), "right side of |> must be a function call, got %s", right)
+	return nil
+}
+
+
+func (parser *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	expression := &ast.IndexExpression{
+		Token:    parser.currToken,
+		Left:     left,
+		Optional: parser.currTokenIs(token.OPTIONAL_LBRACKET),
+	}
+
+	parser.nextToken()
+
+	// xs[..5] -> slice without start
+	if parser.currTokenIs(token.RANGE) || parser.currTokenIs(token.RANGE_INCLUSIVE) {
+		expression.Index = parser.parseRangeExpression(nil)
+	} else {
+		expression.Index = parser.parseExpression(LOWEST)
+	}
+
+	if !parser.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return expression
+}
+
+// start..end / start..=end, start is nil for `xs[..end]`. The end may only be
+// left out right before `]` of an exclusive slice: xs[1..]. Ranges do not
+// associate: a..b..c is an error
+func (parser *Parser) parseRangeExpression(start ast.Expression) ast.Expression {
+	expression := &ast.RangeExpression{
+		Token:     parser.currToken,
+		Start:     start,
+		Inclusive: parser.currTokenIs(token.RANGE_INCLUSIVE),
+	}
+
+	if parser.peekTokenIs(token.RBRACKET) {
+		if expression.Inclusive {
+			parser.errorf("inclusive range without end")
+			return nil
+		}
+
+		return expression
+	}
+
+	precedence := parser.currPrecedence()
+	parser.nextToken()
+	expression.End = parser.parseExpression(precedence)
+
+	if parser.peekTokenIs(token.RANGE) || parser.peekTokenIs(token.RANGE_INCLUSIVE) {
+		parser.errorAt(parser.peekToken.Position, "ranges cannot be chained")
+		return nil
+	}
+
+	return expression
+}
+
+// right-associative: a = b = c -> a = (b = c)
+func (parser *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    parser.currToken,
+		Target:   target,
+		Operator: parser.currToken.Literal,
+	}
+
+	assignable := false
+
+	switch node := target.(type) {
+	case *ast.Identifier, nil:
+		// nil -> the target itself failed to parse, already reported
+		assignable = true
+	case *ast.IndexExpression:
+		// a?[i] = x -> nothing to store into if a is null
+		assignable = !node.Optional
+	case *ast.MemberExpression:
+		assignable = !node.Optional
+	default:
+		// quoted code may assign to whatever a macro argument is: unquote(a) = 1
+		assignable = ast.CallTo(target, "unquote") != nil
+	}
+
+	if !assignable {
+		parser.errorAt(ast.Pos(target), "cannot assign to %s", target)
+	}
+
+	parser.nextToken()
+	expression.Value = parser.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+// object.name / object?.name -> name is a field / method name, never a variable
+func (parser *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	expression := &ast.MemberExpression{
+		Token:    parser.currToken,
+		Object:   object,
+		Optional: parser.currTokenIs(token.OPTIONAL_DOT),
+	}
+
+	if !parser.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	expression.Property = &ast.Identifier{
+		Token: parser.currToken,
+		Value: parser.currToken.Literal,
+	}
+
+	return expression
+}
+
+
 // helpers for parseLetStatement()
 
 func (parser *Parser) currTokenIs(tokenType token.TokenType) bool {
@@ -403,41 +1508,186 @@ func (parser *Parser) expectPeek(tokenType token.TokenType) bool {
 
 // helper for parseFunctionLiteral()
 
-func (parser *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// (a, b: int = 10, ...rest) -> fills the parameter fields of literal
+func (parser *Parser) parseFunctionParameters(literal *ast.FunctionLiteral) bool {
+	literal.Parameters = []*ast.Identifier{}
 
 	// case 1: empty parameter list -> ) immediately follows after (
 	if parser.peekTokenIs(token.RPAREN) {
 		parser.nextToken()
-		return identifiers
+		return true
 	}
 
 	// case 2: parameters, possibly in comma separated list
-	parser.nextToken()
+	defaults    := []ast.Expression{}
+	hasDefaults := false
+
+	for {
+		parser.nextToken()
+
+		isRest := parser.currTokenIs(token.ELLIPSIS)
+		if isRest {
+			parser.nextToken()
+		}
+
+		if !parser.currTokenIs(token.IDENT) {
+			parser.errorf("expected parameter name, got %s instead", parser.currToken.Type)
+			return false
+		}
+
+		parameter := parser.parseTypedIdentifier()
+		if parameter == nil {
+			return false
+		}
+
+		var value ast.Expression
+
+		switch {
+		case parser.peekTokenIs(token.ASSIGN) && isRest:
+			parser.errorAt(parameter.Token.Position, "rest parameter %s cannot have a default value", parameter.Value)
+			return false
+
+		case parser.peekTokenIs(token.ASSIGN):
+			parser.nextToken()   // skips the name
+			parser.nextToken()   // skips the =
+
+			value       = parser.parseExpression(LOWEST)
+			hasDefaults = true
+
+		case hasDefaults && !isRest:
+			parser.errorAt(parameter.Token.Position, "parameter %s without default value follows a parameter with one", parameter.Value)
+			return false
+		}
+
+		literal.Parameters = append(literal.Parameters, parameter)
+		defaults           = append(defaults, value)
 
+		if !parser.peekTokenIs(token.COMMA) {
+			literal.Variadic = isRest
+			break
+		}
+
+		if isRest {
+			parser.errorAt(parameter.Token.Position, "rest parameter %s must be the last parameter", parameter.Value)
+			return false
+		}
+
+		parser.nextToken()   // onto the comma
+	}
+
+	if hasDefaults {
+		literal.Defaults = defaults
+	}
+
+	return parser.expectPeek(token.RPAREN)
+}
+
+
+// helpers for type annotations: `name: type`, `-> type`
+
+// name with optional `: type`, nil if the annotation is malformed
+func (parser *Parser) parseTypedIdentifier() *ast.Identifier {
 	identifier := &ast.Identifier{
 		Token: parser.currToken,
 		Value: parser.currToken.Literal,
 	}
-	identifiers = append(identifiers, identifier)
 
-	for parser.peekTokenIs(token.COMMA) {
-		parser.nextToken()   // skips the comma
-		parser.nextToken()   // skips the whitespace
+	if !parser.peekTokenIs(token.COLON) {
+		return identifier
+	}
+
+	parser.nextToken()   // skips the name
+	parser.nextToken()   // skips the colon
 
-		identifier := &ast.Identifier{
+	if identifier.Type = parser.parseTypeExpression(); identifier.Type == nil {
+		return nil
+	}
+
+	return identifier
+}
+
+// int, bool, null or fn(type, ...) -> type
+func (parser *Parser) parseTypeExpression() ast.TypeExpression {
+	switch parser.currToken.Type {
+	case token.IDENT, token.NULL:
+		return &ast.NamedType{
 			Token: parser.currToken,
-			Value: parser.currToken.Literal,
+			Name:  parser.currToken.Literal,
+		}
+
+	case token.FUNCTION:
+		function := &ast.FunctionType{
+			Token:      parser.currToken,
+			Parameters: []ast.TypeExpression{},
+		}
+
+		if !parser.expectPeek(token.LPAREN) {
+			return nil
+		}
+
+		for !parser.peekTokenIs(token.RPAREN) {
+			if len(function.Parameters) > 0 && !parser.expectPeek(token.COMMA) {
+				return nil
+			}
+
+			parser.nextToken()
+
+			parameter := parser.parseTypeExpression()
+			if parameter == nil {
+				return nil
+			}
+
+			function.Parameters = append(function.Parameters, parameter)
+		}
+
+		parser.nextToken()   // onto the )
+
+		if !parser.expectPeek(token.ARROW) {
+			return nil
+		}
+
+		parser.nextToken()
+
+		if function.Result = parser.parseTypeExpression(); function.Result == nil {
+			return nil
 		}
 
-		identifiers = append(identifiers, identifier)
+		return function
+	}
+
+	parser.errorf("expected type, got %s instead", parser.currToken.Type)
+
+	return nil
+}
+
+
+// helper for parseCallExpression()
+
+func (parser *Parser) parseCallArguments() []ast.Expression {
+	arguments := []ast.Expression{}
+
+	// case 1: no arguments -> ) immediately follows after (
+	if parser.peekTokenIs(token.RPAREN) {
+		parser.nextToken()
+		return arguments
+	}
+
+	// case 2: comma separated list of expressions
+	parser.nextToken()
+	arguments = append(arguments, parser.parseExpression(LOWEST))
+
+	for parser.peekTokenIs(token.COMMA) {
+		parser.nextToken()   // skips the comma
+		parser.nextToken()   // moves onto the next argument
+
+		arguments = append(arguments, parser.parseExpression(LOWEST))
 	}
 
 	if !parser.expectPeek(token.RPAREN) {
 		return nil
 	}
 
-	return identifiers
+	return arguments
 }
 
 
@@ -459,15 +1709,25 @@ func (parser *Parser) peekPrecedence() int {
 }
 
 
-// helper for Errors()
+// helper for Errors(): an error at the current token
+func (parser *Parser) errorf(format string, args ...any) {
+	parser.errorAt(parser.currToken.Position, format, args...)
+}
+
+func (parser *Parser) errorAt(position token.Position, format string, args ...any) {
+	parser.errors = append(parser.errors, Error{
+		Position: position,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
 func (parser *Parser) peekError(tokenType token.TokenType) {
-	message := fmt.Sprintf(
+	parser.errorAt(
+		parser.peekToken.Position,
 		"expected next token to be %s, got %s instead",
 		tokenType,
 		parser.peekToken.Type,
 	)
-	
-	parser.errors = append(parser.errors, message)
 }
 
 
@@ -483,8 +1743,7 @@ func (parser *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn)
 
 // parseExpression() helper for better error messages
 func (parser *Parser) noPrefixParseFuncError(t token.TokenType) {
-	message := fmt.Sprintf("no prefix parse function for %s found", t)
-	parser.errors = append(parser.errors, message)
+	parser.errorf("no prefix parse function for %s found", t)
 }
 
 //---[ Parser Helper Methods ]--------------------------------------------------
@@ -3,6 +3,8 @@ package parser
 import (
 	"testing"
 	"fmt"
+	"strings"
+	"slices"
 
 	"monkey/ast"
 	"monkey/lexer"
@@ -39,295 +41,1113 @@ func TestLetStatements(t *testing.T) {
 		statement := program.Statements[0]
 		testLetStatement(t, statement, test.expectedIdent)
 
-		// val := statement.(*ast.LetStatement).Value
-		// testLiteralExpression(t, val, test.expectedValue)
+		val := statement.(*ast.LetStatement).Value
+		testLiteralExpression(t, val, test.expectedValue)
 	}
 }
 
-func TestReturnStatements(t *testing.T) {
-	input := `
-return 5;
-return 10;
-return 993322;
-`
-	
-	lex     := lexer.New(input)
-	parser  := New(lex)
-	program := parser.ParseProgram()
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		names    []string
+		expected string
+	}{
+		{"let [a, b] = xs;", []string{"a", "b"}, "let [a, b] = xs;"},
+		{"let [a, b, ...rest] = xs;", []string{"a", "b", "rest"}, "let [a, b, ...rest] = xs;"},
+		{"let [...all] = xs", []string{"all"}, "let [...all] = xs;"},
+		{"let {name, age} = person;", []string{"name", "age"}, "let {name, age} = person;"},
+		{"let [] = xs;", []string{}, "let [] = xs;"},
+		{"let [_, [a, _], ...r] = xs;", []string{"a", "r"}, "let [_, [a, _], ...r] = xs;"},
+		{"let {name: n, pos: [x, y], age} = p;", []string{"n", "x", "y", "age"}, "let {name: n, pos: [x, y], age} = p;"},
+	}
 
-	checkParserErrors(t, parser)
+	for _, test := range tests {
+		parser  := New(lexer.New(test.input))
+		program := parser.ParseProgram()
 
-	if len(program.Statements) != 3 {
-		t.Fatalf(
-			"program.Statements does not contain 3 statements. got=%d",
-			len(program.Statements),
-		)
-	}
+		checkParserErrors(t, parser)
 
-	for _, statement := range program.Statements {
-		returnStatement, ok := statement.(*ast.ReturnStatement)
-		if !ok {
-			t.Errorf("statement not *ast.ReturnStatement, got=%T", statement)
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q - expected 1 statement. got=%d", test.input, len(program.Statements))
 		}
 
-		if returnStatement.TokenLiteral() != "return" {
-			t.Errorf(
-				"returnStatement.TokenLiteral() not `return`, got %q",
-				returnStatement.TokenLiteral(),
-			)
+		let, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok || let.Pattern == nil || let.Name != nil {
+			t.Fatalf("%q - not a destructuring let. got=%#v", test.input, program.Statements[0])
+		}
+
+		names := []string{}
+		for _, name := range let.Names() {
+			names = append(names, name.Value)
+		}
+
+		if !slices.Equal(names, test.names) {
+			t.Errorf("%q - names wrong. want=%v, got=%v", test.input, test.names, names)
+		}
+
+		if actual := program.String(); actual != test.expected {
+			t.Errorf("%q - String() wrong. want=%q, got=%q", test.input, test.expected, actual)
 		}
 	}
 }
 
+func TestInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, 1] = xs;", "literal pattern 1 cannot be used in let"},
+		{"let {kind: -1} = h;", "literal pattern -1 cannot be used in let"},
+		{"let [a,] = xs;", "expected pattern, got ] instead"},
+		{"let [a b] = xs;", "expected next token to be ], got IDENT instead"},
+		{"let [...rest, a] = xs;", "rest element rest must be the last element"},
+		{"let [a, [b, a]] = xs;", "duplicate name a in pattern"},
+		{"let {a, b: a} = h;", "duplicate name a in pattern"},
+		{"let {a, a: b} = h;", "duplicate key a in hash pattern"},
+		{"let {...rest} = h;", "expected key in hash pattern, got ... instead"},
+		{`let {"a"} = h;`, "expected next token to be :, got } instead"},
+		{`let {"a": null} = h;`, "literal pattern null cannot be used in let"},
+		{`let {a: "b"} = h;`, `literal pattern "b" cannot be used in let`},
+	}
 
-func TestIdentifierExpression(t *testing.T) {
-	input := "foobar;"
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
 
-	lex     := lexer.New(input)
-	parser  := New(lex)
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (x) { 1 => a, -2 => { b; c } [y, _, ...r] if y > 0 => y, {kind: true, n} => n, _ => 0 }`
+
+	parser  := New(lexer.New(input))
 	program := parser.ParseProgram()
 
 	checkParserErrors(t, parser)
 
 	if len(program.Statements) != 1 {
-		t.Fatalf(
-			"program contains incorrect number of statements. got=%d",
-			len(program.Statements),
-		)
+		t.Fatalf("expected 1 statement. got=%d", len(program.Statements))
 	}
 
-	statement, ok := program.Statements[0].(*ast.ExpressionStatement) 
+	match, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
 	if !ok {
-		t.Fatalf(
-			"program.Statements[0] is not *ast.ExpressionStatement. got=%T",
-			program.Statements[0],
-		)
+		t.Fatalf("expression not *ast.MatchExpression. got=%T", program.Statements[0].(*ast.ExpressionStatement).Expression)
 	}
 
-	identifier, ok := statement.Expression.(*ast.Identifier)
-	if !ok {
-		t.Fatalf(
-			"expression is not *ast.Identifier. got=%T",
-			statement.Expression,
-		)
-	}
+	testIdentifier(t, match.Subject, "x")
 
-	if identifier.Value != "foobar" {
-		t.Errorf(
-			"identifier.Value not %s. got=%s",
-			"foobar",
-			identifier.Value,
-		)
+	expected := []struct {
+		pattern string
+		guard   string
+		body    string
+	}{
+		{"1", "", "a"},
+		{"-2", "", "bc"},
+		{"[y, _, ...r]", "(y > 0)", "y"},
+		{"{kind: true, n}", "", "n"},
+		{"_", "", "0"},
 	}
-	
-	if identifier.TokenLiteral() != "foobar" {
-		t.Errorf(
-			"identifer.TokenLiteral is not %s. got=%s",
-			"foobar",
-			identifier.TokenLiteral(),
-		)
+
+	if len(match.Arms) != len(expected) {
+		t.Fatalf("wrong number of arms. want=%d, got=%d", len(expected), len(match.Arms))
 	}
-}
 
-func TestIntegerLiteralExpression(t *testing.T) {
-	input := "5;"
+	for i, arm := range match.Arms {
+		if arm.Pattern.String() != expected[i].pattern {
+			t.Errorf("arms[%d] pattern wrong. want=%q, got=%q", i, expected[i].pattern, arm.Pattern)
+		}
 
-	lex     := lexer.New(input)
-	parser  := New(lex)
-	program := parser.ParseProgram()
+		guard := ""
+		if arm.Guard != nil {
+			guard = arm.Guard.String()
+		}
 
-	checkParserErrors(t, parser)
+		if guard != expected[i].guard {
+			t.Errorf("arms[%d] guard wrong. want=%q, got=%q", i, expected[i].guard, guard)
+		}
 
-	if len(program.Statements) != 1 {
-		t.Fatalf(
-			"program does not have 1 statement. got=%d",
-			len(program.Statements),
-		)
+		if arm.Body.String() != expected[i].body {
+			t.Errorf("arms[%d] body wrong. want=%q, got=%q", i, expected[i].body, arm.Body)
+		}
 	}
 
-	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf(
-			"program.Statements[0] is not *ast.ExpressionStatement. got=%T",
-			program.Statements[0],
-		)
+	if !match.HasCatchAll() {
+		t.Errorf("match.HasCatchAll() = false, want true")
 	}
 
-	literal, ok := statement.Expression.(*ast.IntegerLiteral)
-	if !ok {
-		t.Fatalf(
-			"expression not *ast.IntegerLiteral. got=%T",
-			statement.Expression,
-		)
+	// string and null literals, string keys
+	literals := New(lexer.New(`match (x) { "a" => 1, null => 2, {kind: "a", "full name": n} => n }`))
+	program   = literals.ParseProgram()
+
+	checkParserErrors(t, literals)
+
+	arms := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression).Arms
+
+	testNodeEqual(t, arms[0].Pattern, &ast.LiteralPattern{Value: &ast.StringLiteral{Value: "a"}})
+	testNodeEqual(t, arms[1].Pattern, &ast.LiteralPattern{Value: &ast.NullLiteral{}})
+
+	if pattern := arms[2].Pattern.String(); pattern != `{kind: "a", "full name": n}` {
+		t.Errorf("hash pattern with string key wrong. got=%q", pattern)
 	}
 
-	if literal.Value != 5 {
-		t.Errorf(
-			"literal.Value not %d. got=%d",
-			5,
-			literal.Value,
-		)
+	if names := arms[2].Pattern.Names(); len(names) != 1 || names[0].Value != "n" {
+		t.Errorf("hash pattern should bind n only. got=%v", names)
 	}
 
-	if literal.TokenLiteral() != "5" {
-		 t.Errorf(
-			 "literal.TokenLiteral not %s. got=%s",
-			 "5",
-			 literal.TokenLiteral(),
-		 )
+	if _, ok := match.Arms[1].Pattern.(*ast.LiteralPattern); !ok {
+		t.Errorf("arms[1] pattern not *ast.LiteralPattern. got=%T", match.Arms[1].Pattern)
+	}
+
+	want := "matchx { 1 => a, -2 => bc, [y, _, ...r] if (y > 0) => y, {kind: true, n} => n, _ => 0 }"
+	if actual := match.String(); actual != want {
+		t.Errorf("match.String() wrong.\nwant=%q\n got=%q", want, actual)
 	}
 }
 
-func TestBooleanExpressions(t *testing.T) {
-	tests := []struct{
+func TestMacroLiteralParsing(t *testing.T) {
+	tests := []struct {
 		input    string
-		expected bool
+		params   []string
+		expected string
 	}{
-		{"true;", true},
-		{"false;", false},
+		{"macro(x, y) { x + y; }", []string{"x", "y"}, "macro(x, y)(x + y)"},
+		{"macro() { quote(1) }", []string{}, "macro()quote(1)"},
 	}
 
 	for _, test := range tests {
-		lex     := lexer.New(test.input)
-		parser  := New(lex)
+		parser  := New(lexer.New(test.input))
 		program := parser.ParseProgram()
 
 		checkParserErrors(t, parser)
 
-		if len(program.Statements) != 1 {
-			t.Fatalf("program has not enough statements. got=%d",
-				len(program.Statements))
+		macro, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MacroLiteral)
+		if !ok {
+			t.Fatalf("%q - expression not *ast.MacroLiteral. got=%T",
+				test.input, program.Statements[0].(*ast.ExpressionStatement).Expression)
 		}
 
-		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
-		if !ok {
-			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
-				program.Statements[0])
+		if len(macro.Parameters) != len(test.params) {
+			t.Fatalf("%q - wrong number of parameters. want=%d, got=%d",
+				test.input, len(test.params), len(macro.Parameters))
 		}
 
-		boolean, ok := statement.Expression.(*ast.Boolean) 
-		if !ok {
-			t.Fatalf("expression not *ast.Boolean. got=%T", statement.Expression)
+		for i, name := range test.params {
+			testLiteralExpression(t, macro.Parameters[i], name)
 		}
 
-		if boolean.Value != test.expected {
-			t.Errorf("boolean.Value not %t. got=%t", test.expected,
-				boolean.Value)
+		if actual := macro.String(); actual != test.expected {
+			t.Errorf("%q - macro.String() wrong. want=%q, got=%q", test.input, test.expected, actual)
 		}
 	}
-}
 
-func TestParsingPrefixExpressions(t *testing.T) {
-	tests := []struct{
-		input    string
-		operator string
-		value    any
-	}{
-		{"!5;", "!", 5},
-		{"-15;", "-", 15},
-		{"!foobar;", "!", "foobar"},
-		{"-foobar;", "-", "foobar"},
-		{"!true;", "!", true},
-		{"!false;", "!", false},
+	for _, input := range []string{"macro(x y) { x }", "macro(1) { x }", "macro(x) x"} {
+		parser := New(lexer.New(input))
+		parser.ParseProgram()
+
+		if len(parser.Errors()) == 0 {
+			t.Errorf("%q - expected parser errors", input)
+		}
 	}
+}
 
-	for _, test := range tests {
-		lex     := lexer.New(test.input)
-		parser  := New(lex)
-		program := parser.ParseProgram()
+func TestPipeDesugaring(t *testing.T) {
+	input := "xs |> map(f) |> sum"
 
-		checkParserErrors(t, parser)
+	parser  := New(lexer.New(input))
+	program := parser.ParseProgram()
 
-		if len(program.Statements) != 1 {
-			t.Fatalf(
-				"program.Statements does not contain 1 statement. got=%d",
-				len(program.Statements),
-			)
-		}
+	checkParserErrors(t, parser)
 
-		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
-		if !ok {
-			t.Fatalf(
-				"program.Statements[0] is not *ast.ExpressionStatement. got=%T",
-				program.Statements[0],
-			)
-		}
+	sum, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if !ok || !sum.IsPiped() {
+		t.Fatalf("expression not a piped *ast.CallExpression. got=%#v", program.Statements[0])
+	}
 
-		expression, ok := statement.Expression.(*ast.PrefixExpression)
-		if !ok {
-			t.Fatalf(
-				"statement.Expression is not *ast.PrefixExpression. got=%T",
-				statement.Expression,
-			)
-		}
+	testIdentifier(t, sum.Function, "sum")
+	if len(sum.Arguments) != 1 {
+		t.Fatalf("sum: wrong number of arguments. got=%d", len(sum.Arguments))
+	}
 
-		if expression.Operator != test.operator {
-			t.Fatalf(
-				"expression.Operator is not '%s'. got='%s'",
-				test.operator,
-				expression.Operator,
-			)
-		}
+	mapCall, ok := sum.Arguments[0].(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("sum argument not *ast.CallExpression. got=%T", sum.Arguments[0])
+	}
 
-		if !testLiteralExpression(t, expression.Right, test.value) {
-			return
-		}
+	testIdentifier(t, mapCall.Function, "map")
+	if len(mapCall.Arguments) != 2 {
+		t.Fatalf("map: wrong number of arguments. got=%d", len(mapCall.Arguments))
+	}
+
+	testIdentifier(t, mapCall.Arguments[0], "xs")
+	testIdentifier(t, mapCall.Arguments[1], "f")
+
+	if position := ast.Pos(sum); position.String() != "1:1" {
+		t.Errorf("piped call should start at its first argument. got=%s", position)
 	}
 }
 
-func TestParsingInfixExpressions(t *testing.T) {
-	tests := []struct{
-		input      string
-		leftValue  any
-		operator   string
-		rightValue any
+func TestInvalidPipes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
 	}{
-		{"5 + 5", 5, "+", 5},
-		{"5 - 5;", 5, "-", 5},
-		{"5 * 5;", 5, "*", 5},
-		{"5 / 5;", 5, "/", 5},
-		{"5 > 5;", 5, ">", 5},
-		{"5 < 5;", 5, "<", 5},
-		{"5 == 5;", 5, "==", 5},
-		{"5 != 5;", 5, "!=", 5},
-		{"true == true", true, "==", true},
-		{"true != false", true, "!=", false},
-		{"false == false", false, "==", false},
+		{"x |> 1", "right side of |> must be a function call, got 1"},
+		{"x |> f() == y", "right side of |> must be a function call, got (f() == y)"},
+		{"x | f", "no prefix parse function for ILLEGAL found"},
 	}
 
 	for _, test := range tests {
-		lex     := lexer.New(test.input)
-		parser  := New(lex)
-		program := parser.ParseProgram()
-
-		checkParserErrors(t, parser)
-
-		if len(program.Statements) != 1 {
-			t.Fatalf(
-				"program.Statements does not contain %d statements. got=%d\n",
-				1,
-				len(program.Statements),
-			)
-		}
-
-		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
-		if !ok {
-			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
-			program.Statements[0])
-		}
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
 
-		if !testInfixExpression(t, statement.Expression, test.leftValue, test.operator, test.rightValue) {
-			return
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
 		}
 	}
 }
 
-func TestOperatorPrecedenceParsing(t *testing.T) {
-	tests := []struct{
+func TestInvalidMatchExpressions(t *testing.T) {
+	tests := []struct {
 		input    string
 		expected string
 	}{
-		{
+		{"match (x) { }", "match without arms"},
+		{"match x { _ => 1 }", "expected next token to be (, got IDENT instead"},
+		{"match (x) { 1 => a 2 => b }", "expected next token to be ,, got INT instead"},
+		{"match (x) { 1 a }", "expected next token to be =>, got IDENT instead"},
+		{"match (x) { a + 1 => a }", "expected next token to be =>, got + instead"},
+		{"match (x) { [a, a] => a }", "duplicate name a in pattern"},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestImportExportStatements(t *testing.T) {
+	input := `
+import "lib/math.mk" as math
+export let [a, b] = pair;
+export fn double(x) { x * 2 }
+`
+	parser  := New(lexer.New(input))
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	imported, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("statement not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+
+	if imported.Path != "lib/math.mk" {
+		t.Errorf("import path wrong. want=%q, got=%q", "lib/math.mk", imported.Path)
+	}
+	testIdentifier(t, imported.Name, "math")
+
+	tests := []struct {
+		names    []string
+		expected string
+	}{
+		{[]string{"a", "b"}, "export let [a, b] = pair;"},
+		{[]string{"double"}, "export fn double(x)(x * 2)"},
+	}
+
+	for i, test := range tests {
+		export, ok := program.Statements[i+1].(*ast.ExportStatement)
+		if !ok {
+			t.Fatalf("statement %d not *ast.ExportStatement. got=%T", i+1, program.Statements[i+1])
+		}
+
+		names := []string{}
+		for _, name := range export.Names() {
+			names = append(names, name.Value)
+		}
+
+		if !slices.Equal(names, test.names) {
+			t.Errorf("exported names wrong. want=%v, got=%v", test.names, names)
+		}
+
+		if actual := export.String(); actual != test.expected {
+			t.Errorf("export.String() wrong. want=%q, got=%q", test.expected, actual)
+		}
+	}
+}
+
+func TestInvalidImportExport(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"import lib as lib;", "expected next token to be STRING, got IDENT instead"},
+		{`import "lib.mk";`, "expected next token to be AS, got ; instead"},
+		{`import "lib.mk" as 1;`, "expected next token to be IDENT, got INT instead"},
+		{`fn f() { import "lib.mk" as lib; }`, "import must be at the top level"},
+		{"if (x) { export let y = 1; }", "export must be at the top level"},
+		{"export 1;", "expected let or fn name after export, got INT instead"},
+		{"export fn(x) { x };", "expected let or fn name after export, got FUNCTION instead"},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestMemberExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ast.Expression
+	}{
+		{
+			"person.name",
+			&ast.MemberExpression{Object: expectedLiteral("person"), Property: expectedLiteral("name").(*ast.Identifier)},
+		},
+		{
+			"lib.func(x)",
+			&ast.CallExpression{
+				Function:  &ast.MemberExpression{Object: expectedLiteral("lib"), Property: expectedLiteral("func").(*ast.Identifier)},
+				Arguments: []ast.Expression{expectedLiteral("x")},
+			},
+		},
+		{
+			"a?.b?[c]",
+			&ast.IndexExpression{
+				Left:     &ast.MemberExpression{Object: expectedLiteral("a"), Property: expectedLiteral("b").(*ast.Identifier), Optional: true},
+				Index:    expectedLiteral("c"),
+				Optional: true,
+			},
+		},
+		{
+			"a ?? null",
+			&ast.InfixExpression{Left: expectedLiteral("a"), Operator: "??", Right: &ast.NullLiteral{}},
+		},
+		{
+			`"abc".len()`,
+			&ast.CallExpression{
+				Function:  &ast.MemberExpression{Object: &ast.StringLiteral{Value: "abc"}, Property: expectedLiteral("len").(*ast.Identifier)},
+				Arguments: []ast.Expression{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		parser  := New(lexer.New(test.input))
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		testNodeEqual(t, program.Statements[0].(*ast.ExpressionStatement).Expression, test.expected)
+	}
+
+	invalid := []struct {
+		input    string
+		expected string
+	}{
+		{"a.1", "expected next token to be IDENT, got INT instead"},
+		{"a.", "expected next token to be IDENT, got EOF instead"},
+		{"a.if", "expected next token to be IDENT, got IF instead"},
+	}
+
+	for _, test := range invalid {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input     string
+		parameter string
+		expected  string
+	}{
+		{"try { f() } catch (e) { e }", "e", "try f() catch(e) e"},
+		{"try { f() } finally { close() }", "", "try f() finally close()"},
+		{
+			"let x = try { throw 1; } catch (err) { 2 } finally { 3 };",
+			"err",
+			"let x = try throw 1; catch(err) 2 finally 3;",
+		},
+	}
+
+	for _, test := range tests {
+		parser  := New(lexer.New(test.input))
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if actual := program.String(); actual != test.expected {
+			t.Errorf("%q - wrong string. want=%q, got=%q", test.input, test.expected, actual)
+		}
+
+		var try *ast.TryExpression
+		ast.Apply(program, func(cursor *ast.Cursor) bool {
+			if node, ok := cursor.Node().(*ast.TryExpression); ok {
+				try = node
+			}
+			return try == nil
+		}, nil)
+
+		if try == nil {
+			t.Fatalf("%q - no *ast.TryExpression found", test.input)
+		}
+
+		if test.parameter == "" {
+			if try.Parameter != nil || try.Handler != nil {
+				t.Errorf("%q - unexpected catch clause", test.input)
+			}
+			continue
+		}
+
+		testIdentifier(t, try.Parameter, test.parameter)
+	}
+}
+
+func TestInvalidTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { f() }", "try without catch or finally"},
+		{"try f() catch (e) { e }", "expected next token to be {, got IDENT instead"},
+		{"try { f() } catch e { e }", "expected next token to be (, got IDENT instead"},
+		{"try { f() } catch (1) { e }", "expected next token to be IDENT, got INT instead"},
+		{"try { f() } finally (e) { e }", "expected next token to be {, got ( instead"},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestRangeExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ast.Expression
+	}{
+		{"1..3", &ast.RangeExpression{Start: expectedLiteral(1), End: expectedLiteral(3)}},
+		{"a..=b", &ast.RangeExpression{Start: expectedLiteral("a"), End: expectedLiteral("b"), Inclusive: true}},
+		{
+			"xs[1..3]",
+			&ast.IndexExpression{
+				Left:  expectedLiteral("xs"),
+				Index: &ast.RangeExpression{Start: expectedLiteral(1), End: expectedLiteral(3)},
+			},
+		},
+		{
+			"s[..=5]",
+			&ast.IndexExpression{
+				Left:  expectedLiteral("s"),
+				Index: &ast.RangeExpression{End: expectedLiteral(5), Inclusive: true},
+			},
+		},
+		{
+			"s?[2..]",
+			&ast.IndexExpression{
+				Left:     expectedLiteral("s"),
+				Index:    &ast.RangeExpression{Start: expectedLiteral(2)},
+				Optional: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		parser  := New(lexer.New(test.input))
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		testNodeEqual(t, program.Statements[0].(*ast.ExpressionStatement).Expression, test.expected)
+	}
+
+	invalid := []struct {
+		input    string
+		expected string
+	}{
+		{"..5", "no prefix parse function for .. found"},
+		{"xs[1..=]", "inclusive range without end"},
+		{"let r = 1..;", "no prefix parse function for ; found"},
+		{"xs[1..2..]", "ranges cannot be chained"},
+		{"0..1..=2", "ranges cannot be chained"},
+	}
+
+	for _, test := range invalid {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestArrayAndHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[]", "[]"},
+		{"[1, 2 * 2, xs[0]]", "[1, (2 * 2), (xs[0])]"},
+		{"{}", "{}"},
+		{`{"one": 1, two: 1 + 1, [3]: [3]}`, `{"one": 1, two: (1 + 1), [3]: [3]}`},
+		{"[{a: [b]}, [x for x in xs]]", "[{a: [b]}, [x for x in xs]]"},
+		{"let h = {};", "let h = {};"},
+	}
+
+	for _, test := range tests {
+		parser  := New(lexer.New(test.input))
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if actual := program.String(); actual != test.expected {
+			t.Errorf("%q - wrong string. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+
+	parser  := New(lexer.New(`{"a": [1, x], b: 2}`))
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	expected := &ast.HashLiteral{
+		Keys: []ast.Expression{&ast.StringLiteral{Value: "a"}, expectedLiteral("b")},
+		Values: []ast.Expression{
+			&ast.ArrayLiteral{Elements: []ast.Expression{expectedLiteral(1), expectedLiteral("x")}},
+			expectedLiteral(2),
+		},
+	}
+
+	testNodeEqual(t, program.Statements[0].(*ast.ExpressionStatement).Expression, expected)
+}
+
+func TestInvalidArrayAndHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2", "expected next token to be ], got EOF instead"},
+		{"[1 2]", "expected next token to be ], got INT instead"},
+		{"{a: 1, b}", "expected next token to be :, got } instead"},
+		{"{a: 1 b: 2}", "expected next token to be }, got IDENT instead"},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestComprehension(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[x * 2 for x in xs if x > 0]", "[(x * 2) for x in xs if (x > 0)]"},
+		{"{k: v for (k, v) in h}", "{k: v for (k, v) in h}"},
+		{
+			"[x + y for x in 0..n if x > 1 if x < 9 for y in ys[x..]]",
+			"[(x + y) for x in (0..n) if (x > 1) if (x < 9) for y in (ys[(x..)])]",
+		},
+		{"let pairs = [[y for y in xs] for x in xs];", "let pairs = [[y for y in xs] for x in xs];"},
+		{"{v: [k for k in v] for (k, v) in h}", "{v: [k for k in v] for (k, v) in h}"},
+	}
+
+	for _, test := range tests {
+		parser  := New(lexer.New(test.input))
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if actual := program.String(); actual != test.expected {
+			t.Errorf("%q - wrong string. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+
+	parser  := New(lexer.New("{k: v * 2 for (k, v) in h if v for x in k}"))
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	expected := &ast.Comprehension{
+		Key:   expectedLiteral("k"),
+		Value: &ast.InfixExpression{Left: expectedLiteral("v"), Operator: "*", Right: expectedLiteral(2)},
+		Generators: []*ast.Generator{
+			{
+				Key:      expectedLiteral("k").(*ast.Identifier),
+				Value:    expectedLiteral("v").(*ast.Identifier),
+				Iterable: expectedLiteral("h"),
+				Filters:  []ast.Expression{expectedLiteral("v")},
+			},
+			{Value: expectedLiteral("x").(*ast.Identifier), Iterable: expectedLiteral("k")},
+		},
+	}
+
+	testNodeEqual(t, program.Statements[0].(*ast.ExpressionStatement).Expression, expected)
+}
+
+func TestInvalidComprehensions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2 for x in xs]", "expected next token to be ], got FOR instead"},
+		{"{a: 1, b: 2 for x in xs}", "expected next token to be }, got FOR instead"},
+		{"{a for a in b}", "expected next token to be :, got FOR instead"},
+		{"[x for 1 in xs]", "expected next token to be IDENT, got INT instead"},
+		{"[x for x, y in xs]", "expected next token to be IN, got , instead"},
+		{"{k: v for (k, k) in h}", "duplicate name k in generator"},
+		{"[x for x in xs if]", "no prefix parse function for ] found"},
+		{"[x for x in xs", "expected next token to be ], got EOF instead"},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestTemplateLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		printed  string
+		expected *ast.TemplateLiteral
+	}{
+		{"`plain`", "`plain`", &ast.TemplateLiteral{Strings: []string{"plain"}}},
+		{
+			"`Hello ${user.name}, you have ${n + 1} items`",
+			"`Hello ${user.name}, you have ${(n + 1)} items`",
+			&ast.TemplateLiteral{
+				Strings:     []string{"Hello ", ", you have ", " items"},
+				Expressions: []ast.Expression{
+					&ast.MemberExpression{Object: expectedLiteral("user"), Property: expectedLiteral("name").(*ast.Identifier)},
+					&ast.InfixExpression{Left: expectedLiteral("n"), Operator: "+", Right: expectedLiteral(1)},
+				},
+			},
+		},
+		{
+			"`${a}${`<${b}>`}`",
+			"`${a}${`<${b}>`}`",
+			&ast.TemplateLiteral{
+				Strings:     []string{"", "", ""},
+				Expressions: []ast.Expression{
+					expectedLiteral("a"),
+					&ast.TemplateLiteral{Strings: []string{"<", ">"}, Expressions: []ast.Expression{expectedLiteral("b")}},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		parser  := New(lexer.New(test.input))
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		testNodeEqual(t, program.Statements[0].(*ast.ExpressionStatement).Expression, test.expected)
+
+		if actual := program.String(); actual != test.printed {
+			t.Errorf("%q - wrong string. want=%q, got=%q", test.input, test.printed, actual)
+		}
+	}
+
+	// nodes inside ${} carry their position in the whole source
+	parser   := New(lexer.New("let s =\n  `x: ${a +\n b}`;"))
+	program  := parser.ParseProgram()
+	checkParserErrors(t, parser)
+
+	template := program.Statements[0].(*ast.LetStatement).Value.(*ast.TemplateLiteral)
+	sum      := template.Expressions[0].(*ast.InfixExpression)
+
+	if position := ast.Pos(template); position.String() != "2:3" {
+		t.Errorf("wrong template position. got=%s", position)
+	}
+	if position := ast.Pos(sum.Left); position.String() != "2:9" {
+		t.Errorf("wrong position of a. got=%s", position)
+	}
+	if position := ast.Pos(sum.Right); position.String() != "3:2" {
+		t.Errorf("wrong position of b. got=%s", position)
+	}
+}
+
+func TestInvalidTemplateLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"`a ${} b`", "1:6: empty ${} in template"},
+		{"x;\n`a ${b c}`", "2:8: expected end of template expression, got IDENT instead"},
+		{"`${1 + }`", "1:8: no prefix parse function for EOF found"},
+		{"`a ${b`", "1:1: no prefix parse function for ILLEGAL found"},
+		{"`a ${`b ${c d}`}`", "1:13: expected end of template expression, got IDENT instead"},
+		{"x;\n`a ${\n  `b ${c\n d}`}`", "4:2: expected end of template expression, got IDENT instead"},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Diagnostics()
+		if len(errors) == 0 || errors[0].String() != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%v", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let = 5;", "1:5: expected next token to be IDENT, got = instead"},
+		{"let x = 1;\nx + ;", "2:5: no prefix parse function for ; found"},
+		{"fn(a, ...b, c) { a }", "1:10: rest parameter b must be the last parameter"},
+		{"let f = 1;\n  f() = 2", "2:3: cannot assign to f()"},
+		{"while (true) { 1 }; break;", "1:21: break outside of a loop"},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Diagnostics()
+		if len(errors) == 0 || errors[0].String() != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%v", test.input, test.expected, errors)
+		}
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedValue any
+	}{
+		{"return 5;", 5},
+		{"return true;", true},
+		{"return foobar;", "foobar"},
+	}
+
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf(
+				"program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements),
+			)
+		}
+
+		returnStatement, ok := program.Statements[0].(*ast.ReturnStatement)
+		if !ok {
+			t.Fatalf("statement not *ast.ReturnStatement, got=%T", program.Statements[0])
+		}
+
+		if returnStatement.TokenLiteral() != "return" {
+			t.Errorf(
+				"returnStatement.TokenLiteral() not `return`, got %q",
+				returnStatement.TokenLiteral(),
+			)
+		}
+
+		testLiteralExpression(t, returnStatement.ReturnValue, test.expectedValue)
+	}
+}
+
+
+func TestIdentifierExpression(t *testing.T) {
+	input := "foobar;"
+
+	lex     := lexer.New(input)
+	parser  := New(lex)
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"program contains incorrect number of statements. got=%d",
+			len(program.Statements),
+		)
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement) 
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not *ast.ExpressionStatement. got=%T",
+			program.Statements[0],
+		)
+	}
+
+	identifier, ok := statement.Expression.(*ast.Identifier)
+	if !ok {
+		t.Fatalf(
+			"expression is not *ast.Identifier. got=%T",
+			statement.Expression,
+		)
+	}
+
+	if identifier.Value != "foobar" {
+		t.Errorf(
+			"identifier.Value not %s. got=%s",
+			"foobar",
+			identifier.Value,
+		)
+	}
+	
+	if identifier.TokenLiteral() != "foobar" {
+		t.Errorf(
+			"identifer.TokenLiteral is not %s. got=%s",
+			"foobar",
+			identifier.TokenLiteral(),
+		)
+	}
+}
+
+func TestIntegerLiteralExpression(t *testing.T) {
+	input := "5;"
+
+	lex     := lexer.New(input)
+	parser  := New(lex)
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"program does not have 1 statement. got=%d",
+			len(program.Statements),
+		)
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not *ast.ExpressionStatement. got=%T",
+			program.Statements[0],
+		)
+	}
+
+	literal, ok := statement.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf(
+			"expression not *ast.IntegerLiteral. got=%T",
+			statement.Expression,
+		)
+	}
+
+	if literal.Value != 5 {
+		t.Errorf(
+			"literal.Value not %d. got=%d",
+			5,
+			literal.Value,
+		)
+	}
+
+	if literal.TokenLiteral() != "5" {
+		 t.Errorf(
+			 "literal.TokenLiteral not %s. got=%s",
+			 "5",
+			 literal.TokenLiteral(),
+		 )
+	}
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected bool
+	}{
+		{"true;", true},
+		{"false;", false},
+	}
+
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d",
+				len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+
+		boolean, ok := statement.Expression.(*ast.Boolean) 
+		if !ok {
+			t.Fatalf("expression not *ast.Boolean. got=%T", statement.Expression)
+		}
+
+		if boolean.Value != test.expected {
+			t.Errorf("boolean.Value not %t. got=%t", test.expected,
+				boolean.Value)
+		}
+	}
+}
+
+func TestParsingPrefixExpressions(t *testing.T) {
+	tests := []struct{
+		input    string
+		operator string
+		value    any
+	}{
+		{"!5;", "!", 5},
+		{"-15;", "-", 15},
+		{"!foobar;", "!", "foobar"},
+		{"-foobar;", "-", "foobar"},
+		{"!true;", "!", true},
+		{"!false;", "!", false},
+	}
+
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf(
+				"program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements),
+			)
+		}
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf(
+				"program.Statements[0] is not *ast.ExpressionStatement. got=%T",
+				program.Statements[0],
+			)
+		}
+
+		expression, ok := statement.Expression.(*ast.PrefixExpression)
+		if !ok {
+			t.Fatalf(
+				"statement.Expression is not *ast.PrefixExpression. got=%T",
+				statement.Expression,
+			)
+		}
+
+		if expression.Operator != test.operator {
+			t.Fatalf(
+				"expression.Operator is not '%s'. got='%s'",
+				test.operator,
+				expression.Operator,
+			)
+		}
+
+		if !testLiteralExpression(t, expression.Right, test.value) {
+			return
+		}
+	}
+}
+
+func TestParsingInfixExpressions(t *testing.T) {
+	tests := []struct{
+		input      string
+		leftValue  any
+		operator   string
+		rightValue any
+	}{
+		{"5 + 5", 5, "+", 5},
+		{"5 - 5;", 5, "-", 5},
+		{"5 * 5;", 5, "*", 5},
+		{"5 / 5;", 5, "/", 5},
+		{"5 > 5;", 5, ">", 5},
+		{"5 < 5;", 5, "<", 5},
+		{"5 == 5;", 5, "==", 5},
+		{"5 != 5;", 5, "!=", 5},
+		{"true == true", true, "==", true},
+		{"true != false", true, "!=", false},
+		{"false == false", false, "==", false},
+	}
+
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf(
+				"program.Statements does not contain %d statements. got=%d\n",
+				1,
+				len(program.Statements),
+			)
+		}
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+		}
+
+		if !testInfixExpression(t, statement.Expression, test.leftValue, test.operator, test.rightValue) {
+			return
+		}
+	}
+}
+
+func TestOperatorPrecedenceParsing(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{
 			"-a * b",
 			"((-a) * b)",
 		},
@@ -411,30 +1231,170 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"!(true == true)",
 			"(!(true == true))",
 		},
+		{
+			"a + add(b * c) + d",
+			"((a + add((b * c))) + d)",
+		},
+		{
+			"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))",
+			"add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))",
+		},
+		{
+			"add(a + b + c * d / f + g)",
+			"add((((a + b) + ((c * d) / f)) + g))",
+		},
+		{
+			"a * b[2] - c[i + 1](x)",
+			"((a * (b[2])) - (c[(i + 1)])(x))",
+		},
+		{
+			"a = b = 1 + 2",
+			"(a = (b = (1 + 2)))",
+		},
+		{
+			"x += y * 2 == z",
+			"(x += ((y * 2) == z))",
+		},
+		{
+			"xs[i] /= f(a = 1)",
+			"((xs[i]) /= f((a = 1)))",
+		},
+		{
+			"xs |> map(f) |> filter(g) |> sum()",
+			"(((xs |> map(f)) |> filter(g)) |> sum())",
+		},
+		{
+			"a + b |> f(c * d) |> g",
+			"(((a + b) |> f((c * d))) |> g())",
+		},
+		{
+			"y = x |> f(1)",
+			"(y = (x |> f(1)))",
+		},
+		{
+			"(x |> f()) == 1",
+			"((x |> f()) == 1)",
+		},
+		{
+			"-a.b * c.d(e)",
+			"((-a.b) * c.d(e))",
+		},
+		{
+			"lib.f(x).y[0] + xs[0].name",
+			"((lib.f(x).y[0]) + (xs[0]).name)",
+		},
+		{
+			`p.age += "abc".len() |> s.max()`,
+			`(p.age += ("abc".len() |> s.max()))`,
+		},
+		{
+			"a?.b?[0].c ?? d == e ?? null",
+			"(((a?.b?[0]).c ?? (d == e)) ?? null)",
+		},
+		{
+			"x = xs |> first() ?? -1",
+			"(x = ((xs |> first()) ?? (-1)))",
+		},
+		{
+			"0..n + 1 == r",
+			"((0..(n + 1)) == r)",
+		},
+		{
+			"a..=b * 2 < c",
+			"((a..=(b * 2)) < c)",
+		},
+		{
+			"xs[1..-1][..n - 1][i..][..]",
+			"((((xs[(1..(-1))])[(..(n - 1))])[(i..)])[(..)])",
+		},
+	}
+
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		for _, statement := range program.Statements {
+			fmt.Printf("%v\n", statement)
+		}
+
+		checkParserErrors(t, parser)
+
+		actual := program.String()
+		fmt.Printf("%s -> %s\n", test.input, actual)
+
+		if actual != test.expected {
+			t.Errorf("expected=%q, got%q", test.expected, actual)
+		}
+	}
+}
+
+func TestIfExpression(t *testing.T) {
+	input := "if (x < y) { x }"
+
+	lex     := lexer.New(input)
+	parser  := New(lex)
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"program.Statements does not contain %d statements. got=%d\n",
+			1,
+			len(program.Statements),
+		)
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0],
+		)
 	}
 
-	for _, test := range tests {
-		lex     := lexer.New(test.input)
-		parser  := New(lex)
-		program := parser.ParseProgram()
+	expression, ok := statement.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf(
+			"statement.Expression is not ast.IfExpression. got=%T",
+			statement.Expression,
+		)
+	}
 
-		for _, statement := range program.Statements {
-			fmt.Printf("%v\n", statement)
-		}
+	if !testInfixExpression(t, expression.Condition, "x", "<", "y") {
+		return
+	}
 
-		checkParserErrors(t, parser)
+	if len(expression.Consequence.Statements) != 1 {
+		t.Errorf(
+			"consequence is not 1 statement. got=%d\n",
+			expression.Consequence.Statements[0],
+		)
+	}
 
-		actual := program.String()
-		fmt.Printf("%s -> %s\n", test.input, actual)
+	consequence, ok := expression.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"Statements[0] is not ast.ExpressionStatement. got=%T",
+			expression.Consequence.Statements[0],
+		)
+	}
 
-		if actual != test.expected {
-			t.Errorf("expected=%q, got%q", test.expected, actual)
-		}
+	if !testIdentifier(t, consequence.Expression, "x") {
+		return
+	}
+
+	if expression.Alternative != nil {
+		t.Errorf(
+			"expression.Alternative.Statements not nil. got=%+v",
+			expression.Alternative,
+		)
 	}
 }
 
-func TestIfExpression(t *testing.T) {
-	input := "if (x < y) { x }"
+func TestIfElseExpression(t *testing.T) {
+	input := "if (x < y) { x } else { y }"
 
 	lex     := lexer.New(input)
 	parser  := New(lex)
@@ -477,28 +1437,302 @@ func TestIfExpression(t *testing.T) {
 		)
 	}
 
-	consequence, ok := expression.Consequence.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf(
-			"Statements[0] is not ast.ExpressionStatement. got=%T",
-			expression.Consequence.Statements[0],
-		)
+	consequence, ok := expression.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"Statements[0] is not ast.ExpressionStatement. got=%T",
+			expression.Consequence.Statements[0],
+		)
+	}
+
+	if !testIdentifier(t, consequence.Expression, "x") {
+		return
+	}
+
+	if len(expression.Alternative.Statements) != 1 {
+		t.Errorf(
+			"alternative is not 1 statement. got=%d\n",
+			expression.Alternative.Statements[0],
+		)
+	}
+
+	alternative, ok := expression.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"Statements[0] is not ast.ExpressionStatement. got=%T",
+			expression.Alternative.Statements[0],
+		)
+	}
+
+	if !testIdentifier(t, alternative.Expression, "y") {
+		return
+	}
+}
+
+func TestElseIfChain(t *testing.T) {
+	input := `if (a) { 1 } else if (b) { 2 } else if (c) { 3 } else { 4 }`
+
+	lex     := lexer.New(input)
+	parser  := New(lex)
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	expression := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+
+	conditions := []string{}
+	last       := expression
+	for current := expression; current != nil; current = current.NextIf() {
+		conditions = append(conditions, current.Condition.String())
+		last = current
+	}
+
+	if strings.Join(conditions, " ") != "a b c" {
+		t.Errorf("wrong chain of conditions. got=%q", conditions)
+	}
+
+	if last.Alternative == nil || last.Alternative.String() != "4" {
+		t.Errorf("final else wrong. got=%v", last.Alternative)
+	}
+
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{input, "ifa 1else ifb 2else ifc 3else4"},
+		{"if (a) { 1 } else if (b) { 2 }", "ifa 1else ifb 2"},
+		{"if (a) { 1 } else { if (b) { 2 } }", "ifa 1elseifb 2"},
+	}
+
+	for _, test := range tests {
+		program := New(lexer.New(test.input)).ParseProgram()
+
+		if actual := program.String(); actual != test.expected {
+			t.Errorf("%q - program.String() wrong. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+
+	broken := New(lexer.New("if (a) { 1 } else if { 2 }"))
+	broken.ParseProgram()
+
+	if errors := broken.Errors(); len(errors) == 0 || errors[0] != "expected next token to be (, got { instead" {
+		t.Errorf("wrong errors for malformed else if. got=%q", errors)
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y; }`
+
+	lex     := lexer.New(input)
+	parser  := New(lex)
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(
+			"program.Statements does not contain 1 statements. got=%d\n",
+			len(program.Statements),
+		)
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0],
+		)
+	}
+
+	function, ok := statement.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf(
+			"statement.Expression is not ast.FunctionLiteral. got=%T",
+			statement.Expression,
+		)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf(
+			"function literal parameters wrong. got=%d\n",
+			len(function.Parameters),
+		)
+	}
+
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf(
+			"function.Body.Statements does not have 1 statement. got=%d\n",
+			len(function.Body.Statements),
+		)
+	}
+
+	bodyStatement, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(
+			"function body statement is not ast.ExpressionStatement, got=%T",
+			function.Body.Statements[0],
+		)
+	}
+
+	testInfixExpression(t, bodyStatement.Expression, "x", "+", "y")
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string
+	}{
+		{input: "fn() {}",        expected: []string{}},
+		{input: "fn(x) {}",       expected: []string{"x"}},
+		{input: "fn(x, y, z) {}", expected: []string{"x", "y", "z"}},
+	}
+
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		function  := statement.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(test.expected) {
+			t.Errorf(
+				"length parameters wrong. want %d, got=%d\n",
+				len(test.expected),
+				len(function.Parameters),
+			)
+		}
+
+		for i, identifier := range test.expected {
+			testLiteralExpression(t, function.Parameters[i], identifier)
+		}
+	}
+}
+
+func TestFunctionDeclarationParsing(t *testing.T) {
+	input := `fn add(a, b) { a + b }; fn(x) { x }; let sub = fn(a, b) { a - b };`
+
+	lex     := lexer.New(input)
+	parser  := New(lex)
+	program := parser.ParseProgram()
+
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+
+	declaration, ok := program.Statements[0].(*ast.FunctionDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FunctionDeclaration. got=%T", program.Statements[0])
+	}
+
+	testIdentifier(t, declaration.Name, "add")
+
+	if declaration.Function.Name != "add" || declaration.Function.TokenLiteral() != "fn" {
+		t.Errorf("declared literal wrong. name=%q, token=%q",
+			declaration.Function.Name, declaration.Function.TokenLiteral())
+	}
+
+	if declaration.String() != "fn add(a, b)(a + b)" {
+		t.Errorf("declaration.String() wrong. got=%q", declaration.String())
+	}
+
+	anonymous := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if anonymous.Name != "" {
+		t.Errorf("anonymous literal has a name. got=%q", anonymous.Name)
+	}
+
+	bound := program.Statements[2].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	if bound.Name != "sub" {
+		t.Errorf("let-bound literal name wrong. got=%q", bound.Name)
+	}
+
+	broken := New(lexer.New("fn add { a }"))
+	broken.ParseProgram()
+
+	if errors := broken.Errors(); len(errors) == 0 || errors[0] != "expected next token to be (, got { instead" {
+		t.Errorf("wrong errors for malformed declaration. got=%q", errors)
+	}
+}
+
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct{
+		input    string
+		defaults []any  // nil: no default
+		variadic bool
+		expected string
+	}{
+		{"fn(a, b = 10) { }", []any{nil, 10}, false, "fn(a, b = 10)"},
+		{"fn(a, b = 1 + 2, c = a) { }", []any{nil, nil, "a"}, false, "fn(a, b = (1 + 2), c = a)"},
+		{"fn(a, ...rest) { }", nil, true, "fn(a, ...rest)"},
+		{"fn(...rest) { }", nil, true, "fn(...rest)"},
+		{"fn(a: int, b: int = 10, ...rest) { }", []any{nil, 10, nil}, true, "fn(a: int, b: int = 10, ...rest)"},
+	}
+
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+		if function.Variadic != test.variadic {
+			t.Errorf("%q - variadic wrong. want=%t, got=%t", test.input, test.variadic, function.Variadic)
+		}
+
+		if actual := function.String(); actual != test.expected {
+			t.Errorf("%q - function.String() wrong. want=%q, got=%q", test.input, test.expected, actual)
+		}
+
+		if len(function.Defaults) != len(test.defaults) {
+			t.Errorf("%q - wrong number of defaults. want=%d, got=%d",
+				test.input, len(test.defaults), len(function.Defaults))
+			continue
+		}
+
+		for i, value := range test.defaults {
+			if value != nil {
+				testLiteralExpression(t, function.Defaults[i], value)
+			}
+		}
+	}
+}
+
+func TestInvalidParameters(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"fn(1) { }", "expected parameter name, got INT instead"},
+		{"fn(a, (b)) { }", "expected parameter name, got ( instead"},
+		{"fn(a,) { }", "expected parameter name, got ) instead"},
+		{"fn(a = 1, b) { }", "parameter b without default value follows a parameter with one"},
+		{"fn(...rest, a) { }", "rest parameter rest must be the last parameter"},
+		{"fn(...rest = 1) { }", "rest parameter rest cannot have a default value"},
+		{"fn(... 1) { }", "expected parameter name, got INT instead"},
 	}
 
-	if !testIdentifier(t, consequence.Expression, "x") {
-		return
-	}
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
 
-	if expression.Alternative != nil {
-		t.Errorf(
-			"expression.Alternative.Statements not nil. got=%+v",
-			expression.Alternative,
-		)
+		errors := parser.Errors()
+		if len(errors) == 0 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, errors)
+		}
 	}
 }
 
-func TestIfElseExpression(t *testing.T) {
-	input := "if (x < y) { x } else { y }"
+func TestCallExpressionParsing(t *testing.T) {
+	input := "add(1, 2 * 3, 4 + 5);"
 
 	lex     := lexer.New(input)
 	parser  := New(lex)
@@ -522,59 +1756,206 @@ func TestIfElseExpression(t *testing.T) {
 		)
 	}
 
-	expression, ok := statement.Expression.(*ast.IfExpression)
+	expression, ok := statement.Expression.(*ast.CallExpression)
 	if !ok {
 		t.Fatalf(
-			"statement.Expression is not ast.IfExpression. got=%T",
+			"statement.Expression is not ast.CallExpression. got=%T",
 			statement.Expression,
 		)
 	}
 
-	if !testInfixExpression(t, expression.Condition, "x", "<", "y") {
+	if !testIdentifier(t, expression.Function, "add") {
 		return
 	}
 
-	if len(expression.Consequence.Statements) != 1 {
-		t.Errorf(
-			"consequence is not 1 statement. got=%d\n",
-			expression.Consequence.Statements[0],
-		)
+	if len(expression.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(expression.Arguments))
 	}
 
-	consequence, ok := expression.Consequence.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf(
-			"Statements[0] is not ast.ExpressionStatement. got=%T",
-			expression.Consequence.Statements[0],
-		)
+	testLiteralExpression(t, expression.Arguments[0], 1)
+	testInfixExpression(t, expression.Arguments[1], 2, "*", 3)
+	testInfixExpression(t, expression.Arguments[2], 4, "+", 5)
+}
+
+func TestCallArgumentParsing(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string
+	}{
+		{input: "add()",           expected: []string{}},
+		{input: "add(x)",          expected: []string{"x"}},
+		{input: "add(x, y, z)",    expected: []string{"x", "y", "z"}},
+		{input: "fn(x) { x }(y)",  expected: []string{"y"}},
 	}
 
-	if !testIdentifier(t, consequence.Expression, "x") {
-		return
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		call      := statement.Expression.(*ast.CallExpression)
+
+		if len(call.Arguments) != len(test.expected) {
+			t.Fatalf(
+				"length arguments wrong. want %d, got=%d\n",
+				len(test.expected),
+				len(call.Arguments),
+			)
+		}
+
+		for i, identifier := range test.expected {
+			testLiteralExpression(t, call.Arguments[i], identifier)
+		}
 	}
+}
 
-	if len(expression.Alternative.Statements) != 1 {
-		t.Errorf(
-			"alternative is not 1 statement. got=%d\n",
-			expression.Alternative.Statements[0],
-		)
+func TestAssignExpression(t *testing.T) {
+	tests := []struct{
+		input    string
+		target   ast.Expression
+		operator string
+		value    any
+	}{
+		{"x = 5;", expectedLiteral("x"), "=", 5},
+		{"x += y", expectedLiteral("x"), "+=", "y"},
+		{"x -= 1", expectedLiteral("x"), "-=", 1},
+		{"x *= 2", expectedLiteral("x"), "*=", 2},
+		{"x /= true", expectedLiteral("x"), "/=", true},
+		{
+			"xs[0] = 1",
+			&ast.IndexExpression{Left: expectedLiteral("xs"), Index: expectedLiteral(0)},
+			"=", 1,
+		},
+		{
+			"unquote(a) = 1",
+			&ast.CallExpression{Function: expectedLiteral("unquote"), Arguments: []ast.Expression{expectedLiteral("a")}},
+			"=", 1,
+		},
 	}
 
-	alternative, ok := expression.Alternative.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf(
-			"Statements[0] is not ast.ExpressionStatement. got=%T",
-			expression.Alternative.Statements[0],
-		)
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		expected  := &ast.AssignExpression{
+			Target:   test.target,
+			Operator: test.operator,
+			Value:    expectedLiteral(test.value),
+		}
+
+		testNodeEqual(t, statement.Expression, expected)
 	}
+}
 
-	if !testIdentifier(t, alternative.Expression, "y") {
-		return
+func TestInvalidAssignTarget(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"1 = 2", "cannot assign to 1"},
+		{"f(x) = 2", "cannot assign to f(x)"},
+		{"a + b = 2", "cannot assign to (a + b)"},
+		{"x = 1 += 2", "cannot assign to 1"},
+		{"a?.b = 2", "cannot assign to a?.b"},
+		{"a?[0] += 2", "cannot assign to (a?[0])"},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) != 1 || errors[0] != test.expected {
+			t.Errorf("%q - wrong errors. want=%q, got=%q", test.input, test.expected, errors)
+		}
 	}
 }
 
-func TestFunctionLiteralParsing(t *testing.T) {
-	input := `fn(x, y) { x + y; }`
+func TestTypeAnnotationParsing(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let x = 5;", "let x = 5;"},
+		{"fn(a: int, b: bool) -> int { a }", "fn(a: int, b: bool) -> int a"},
+		{"fn(a, b: bool) { a }", "fn(a, b: bool)a"},
+		{"fn() -> null { }", "fn() -> null "},
+		{
+			"let apply: fn(fn(int) -> int, int) -> int = fn(f, x) { f(x) };",
+			"let apply: fn(fn(int) -> int, int) -> int = fn(f, x)f(x);",
+		},
+		{"let thunk: fn() -> bool = fn() { true };", "let thunk: fn() -> bool = fn()true;"},
+	}
+
+	for _, test := range tests {
+		lex     := lexer.New(test.input)
+		parser  := New(lex)
+		program := parser.ParseProgram()
+
+		checkParserErrors(t, parser)
+
+		if actual := program.String(); actual != test.expected {
+			t.Errorf("%q - wrong program. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+
+	program := New(lexer.New("fn(a: int) -> bool { true }")).ParseProgram()
+	function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	expected := &ast.FunctionLiteral{
+		Parameters: []*ast.Identifier{
+			{Value: "a", Type: &ast.NamedType{Name: "int"}},
+		},
+		ReturnType: &ast.NamedType{Name: "bool"},
+		Body: &ast.BlockStatement{
+			Statements: []ast.Statement{
+				&ast.ExpressionStatement{Expression: &ast.Boolean{Value: true}},
+			},
+		},
+	}
+
+	testNodeEqual(t, function, expected)
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected string
+	}{
+		{"let x: = 5;", "expected type, got = instead"},
+		{"let x: 5 = 5;", "expected type, got INT instead"},
+		{"fn(a: ) { a }", "expected type, got ) instead"},
+		{"fn() -> { }", "expected type, got { instead"},
+		{"let f: fn(int) = 5;", "expected next token to be ->, got = instead"},
+		{"let f: fn(int bool) -> int = 5;", "expected next token to be ,, got IDENT instead"},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q - expected parser errors", test.input)
+			continue
+		}
+
+		if errors[0] != test.expected {
+			t.Errorf("%q - wrong first error. want=%q, got=%q", test.input, test.expected, errors[0])
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < 10) { if (x == 5) { break; }; continue; }`
 
 	lex     := lexer.New(input)
 	parser  := New(lex)
@@ -583,64 +1964,49 @@ func TestFunctionLiteralParsing(t *testing.T) {
 	checkParserErrors(t, parser)
 
 	if len(program.Statements) != 1 {
-		t.Fatalf(
-			"program.Statements does not contain 1 statements. got=%d\n",
-			len(program.Statements),
-		)
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
 	}
 
-	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	statement, ok := program.Statements[0].(*ast.WhileStatement)
 	if !ok {
-		t.Fatalf(
-			"program.Statements[0] is not ast.ExpressionStatement. got=%T",
-			program.Statements[0],
-		)
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T", program.Statements[0])
 	}
 
-	function, ok := statement.Expression.(*ast.FunctionLiteral)
-	if !ok {
-		t.Fatalf(
-			"statement.Expression is not ast.FunctionLiteral. got=%T",
-			statement.Expression,
-		)
+	if !testInfixExpression(t, statement.Condition, "x", "<", 10) {
+		return
 	}
 
-	if len(function.Parameters) != 2 {
-		t.Fatalf(
-			"function literal parameters wrong. got=%d\n",
-			len(function.Parameters),
-		)
+	expected := &ast.BlockStatement{
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{
+				Expression: &ast.IfExpression{
+					Condition: expectedInfix("x", "==", 5),
+					Consequence: &ast.BlockStatement{
+						Statements: []ast.Statement{&ast.BreakStatement{}},
+					},
+				},
+			},
+			&ast.ContinueStatement{},
+		},
 	}
 
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
-
-	if len(function.Body.Statements) != 1 {
-		t.Fatalf(
-			"function.Body.Statements does not have 1 statement. got=%d\n",
-			len(function.Body.Statements),
-		)
-	}
+	testNodeEqual(t, statement.Body, expected)
 
-	bodyStatement, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf(
-			"function body statement is not ast.ExpressionStatement, got=%T",
-			function.Body.Statements[0],
-		)
+	if actual := program.String(); actual != "while(x < 10) if(x == 5) break;continue;" {
+		t.Errorf("program.String() wrong. got=%q", actual)
 	}
-
-	testInfixExpression(t, bodyStatement.Expression, "x", "+", "y")
 }
 
-func TestFunctionParameterParsing(t *testing.T) {
+func TestForInStatement(t *testing.T) {
 	tests := []struct{
 		input    string
-		expected []string
+		key      string
+		value    string
+		expected string
 	}{
-		{input: "fn() {}",        expected: []string{}},
-		{input: "fn(x) {}",       expected: []string{"x"}},
-		{input: "fn(x, y, z) {}", expected: []string{"x", "y", "z"}},
+		{"for (x in items) { x }", "", "x", "for(x in items) x"},
+		{"for (k, v in table) { k + v; break; };", "k", "v", "for(k, v in table) (k + v)break;"},
+		{"for (x in f(1)) { continue }", "", "x", "for(x in f(1)) continue;"},
 	}
 
 	for _, test := range tests {
@@ -650,19 +2016,77 @@ func TestFunctionParameterParsing(t *testing.T) {
 
 		checkParserErrors(t, parser)
 
-		statement := program.Statements[0].(*ast.ExpressionStatement)
-		function  := statement.Expression.(*ast.FunctionLiteral)
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q - expected 1 statement. got=%d", test.input, len(program.Statements))
+		}
 
-		if len(function.Parameters) != len(test.expected) {
-			t.Errorf(
-				"length parameters wrong. want %d, got=%d\n",
-				len(test.expected),
-				len(function.Parameters),
-			)
+		statement, ok := program.Statements[0].(*ast.ForInStatement)
+		if !ok {
+			t.Fatalf("%q - statement is not ast.ForInStatement. got=%T", test.input, program.Statements[0])
 		}
 
-		for i, identifier := range test.expected {
-			testLiteralExpression(t, function.Parameters[i], identifier)
+		if test.key == "" && statement.Key != nil {
+			t.Errorf("%q - unexpected key %s", test.input, statement.Key)
+		}
+
+		if test.key != "" {
+			testIdentifier(t, statement.Key, test.key)
+		}
+
+		testIdentifier(t, statement.Value, test.value)
+
+		if actual := program.String(); actual != test.expected {
+			t.Errorf("%q - program.String() wrong. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+
+	errors := []struct{
+		input    string
+		expected string
+	}{
+		{"for x in xs { x }", "expected next token to be (, got IDENT instead"},
+		{"for (x of xs) { x }", "expected next token to be IN, got IDENT instead"},
+		{"for (1 in xs) { x }", "expected next token to be IDENT, got INT instead"},
+		{"for (k, in xs) { x }", "expected next token to be IDENT, got IN instead"},
+	}
+
+	for _, test := range errors {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		if len(parser.Errors()) == 0 || parser.Errors()[0] != test.expected {
+			t.Errorf("%q - wrong errors. want first=%q, got=%q", test.input, test.expected, parser.Errors())
+		}
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string
+	}{
+		{"break;", []string{"break outside of a loop"}},
+		{"if (x) { continue; }", []string{"continue outside of a loop"}},
+		{"while (x) { fn() { break; } }", []string{"break outside of a loop"}},
+		{"while (x) { fn() { while (y) { break; } }; continue; }", []string{}},
+		{"while (x) { while (y) { } break; }", []string{}},
+		{"for (x in xs) { break; }; continue;", []string{"continue outside of a loop"}},
+	}
+
+	for _, test := range tests {
+		parser := New(lexer.New(test.input))
+		parser.ParseProgram()
+
+		errors := parser.Errors()
+		if len(errors) != len(test.expected) {
+			t.Errorf("%q - wrong errors. want=%q, got=%q", test.input, test.expected, errors)
+			continue
+		}
+
+		for i, message := range test.expected {
+			if errors[i] != message {
+				t.Errorf("%q - errors[%d] wrong. want=%q, got=%q", test.input, i, message, errors[i])
+			}
 		}
 	}
 }
@@ -807,31 +2231,48 @@ func testInfixExpression(
 	operator   string,
 	right      any,
 ) bool {
-	infix, ok := expression.(*ast.InfixExpression)
-	if !ok {
-		t.Errorf(
-			"expression is not ast.InfixExpression. got=%T(%s)",
-			expression,
-			expression,
-		)
-		return false
+	return testNodeEqual(t, expression, expectedInfix(left, operator, right))
+}
+
+// the node testInfixExpression() compares against
+func expectedInfix(left any, operator string, right any) *ast.InfixExpression {
+	return &ast.InfixExpression{
+		Left:     expectedLiteral(left),
+		Operator: operator,
+		Right:    expectedLiteral(right),
 	}
+}
 
-	if !testLiteralExpression(t, infix.Left, left) {
-		return false
+// compares structurally, reporting every differing field by its path
+func testNodeEqual(t *testing.T, actual ast.Node, expected ast.Node) bool {
+	diffs := ast.Diff(actual, expected)
+	if len(diffs) == 0 {
+		return true
 	}
 
-	if infix.Operator != operator {
-		t.Errorf("infix.Operator is not '%s'. got=%q", operator, infix.Operator)
-		return false
+	t.Errorf("node %q differs from expected %q", actual, expected)
+	for _, diff := range diffs {
+		t.Errorf("  %s", diff)
 	}
 
-	if !testLiteralExpression(t, infix.Right, right) {
-		return false
+	return false
+}
+
+// builds the node testLiteralExpression() would accept for expected
+func expectedLiteral(expected any) ast.Expression {
+	switch castedValue := expected.(type) {
+	case int:
+		return &ast.IntegerLiteral{Value: int64(castedValue)}
+	case int64:
+		return &ast.IntegerLiteral{Value: castedValue}
+	case bool:
+		return &ast.Boolean{Value: castedValue}
+	case string:
+		return &ast.Identifier{Value: castedValue}
 	}
 
-	return true
-} 
+	return nil
+}
 
 //―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
 
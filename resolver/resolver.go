@@ -0,0 +1,450 @@
+package resolver
+
+import (
+	"fmt"
+	"slices"
+
+	"monkey/ast"
+)
+
+type BindingKind int
+
+const (
+	LetBinding       BindingKind = iota  // introduced by a LetStatement
+	ParameterBinding                     // introduced by a FunctionLiteral or MacroLiteral parameter
+	LoopBinding                          // introduced by the variables of a ForInStatement or comprehension Generator
+	FunctionBinding                      // introduced by a FunctionDeclaration (hoisted)
+	PatternBinding                       // introduced by the pattern of a MatchArm
+	ImportBinding                        // introduced by an ImportStatement (the module's namespace)
+	CatchBinding                         // introduced by the catch clause of a TryExpression
+	Predeclared                          // supplied by the caller (builtins, REPL state)
+)
+
+// Binding is one declaration of a name, together with every use resolved to it.
+type Binding struct {
+	Name        string
+	Kind        BindingKind
+	Identifier  *ast.Identifier  // defining identifier (nil for Predeclared)
+	Declaration ast.Node         // *ast.LetStatement, *ast.FunctionLiteral, *ast.MacroLiteral, *ast.ForInStatement, *ast.Generator, *ast.FunctionDeclaration, *ast.MatchArm, *ast.ImportStatement or *ast.TryExpression
+	Scope       *Scope
+	Uses        []*ast.Identifier
+	Assignments []*ast.AssignExpression  // reassignments of the name after its declaration
+}
+
+// Scope holds the bindings declared directly in a Program, FunctionLiteral or
+// MacroLiteral (parameters + top level of its body), ForInStatement (loop variables),
+// Generator (its variables, seen by later generators and the comprehension's
+// elements), MatchArm (pattern names, seen by guard and body), TryExpression
+// (the caught error, seen by the catch block) or BlockStatement.
+type Scope struct {
+	Parent   *Scope
+	Node     ast.Node
+	Bindings map[string]*Binding
+	Children []*Scope
+}
+
+type Diagnostic struct {
+	Identifier *ast.Identifier
+	Message    string
+}
+
+func (diagnostic Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", diagnostic.Identifier.Token.Position, diagnostic.Message)
+}
+
+// Result is everything the resolver learned about a program.
+type Result struct {
+	Universe      *Scope                                 // predeclared names, parent of the program scope
+	Scopes        map[ast.Node]*Scope                    // Program, FunctionLiteral, MacroLiteral, ForInStatement, Generator, MatchArm, TryExpression, BlockStatement -> scope
+	Definitions   map[*ast.Identifier]*Binding           // every identifier (use or declaration) -> binding
+	FreeVariables map[*ast.FunctionLiteral][]*Binding    // bindings a function uses but does not declare
+	Diagnostics   []Diagnostic
+}
+
+
+//---[ Module API Functions ]---------------------------------------------------
+
+// Resolve binds every identifier in program to its declaration. Names in
+// predeclared are treated as declared before the program starts.
+//
+// Within a scope, uses must come after the declaration, except for names of
+// function declarations, which are hoisted to the top of their scope. Function
+// bodies are resolved once the whole program has been seen, since they only
+// run when called: this allows recursion and functions referring to later
+// bindings.
+func Resolve(program *ast.Program, predeclared ...string) *Result {
+	resolver := &resolver{
+		result: &Result{
+			Scopes:        make(map[ast.Node]*Scope),
+			Definitions:   make(map[*ast.Identifier]*Binding),
+			FreeVariables: make(map[*ast.FunctionLiteral][]*Binding),
+		},
+	}
+
+	universe := resolver.openScope(nil, nil)
+	for _, name := range predeclared {
+		universe.Bindings[name] = &Binding{
+			Name:  name,
+			Kind:  Predeclared,
+			Scope: universe,
+		}
+	}
+	resolver.result.Universe = universe
+
+	programScope := resolver.openScope(universe, program)
+	resolver.statements(programScope, program.Statements)
+
+	// deferred bodies may queue further (nested) functions
+	for len(resolver.pending) > 0 {
+		next := resolver.pending[0]
+		resolver.pending = resolver.pending[1:]
+
+		resolver.functionBody(next.scope, next.function)
+	}
+
+	return resolver.result
+}
+
+// Lookup finds the binding visible as name in scope, walking outwards.
+func (scope *Scope) Lookup(name string) *Binding {
+	for current := scope; current != nil; current = current.Parent {
+		if binding, ok := current.Bindings[name]; ok {
+			return binding
+		}
+	}
+
+	return nil
+}
+
+// Function returns the innermost FunctionLiteral enclosing scope, if any.
+func (scope *Scope) Function() *ast.FunctionLiteral {
+	for current := scope; current != nil; current = current.Parent {
+		if function, ok := current.Node.(*ast.FunctionLiteral); ok {
+			return function
+		}
+	}
+
+	return nil
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Resolver Helper Methods ]------------------------------------------------
+
+type pendingFunction struct {
+	scope    *Scope  // scope the literal appears in
+	function *ast.FunctionLiteral
+}
+
+type resolver struct {
+	result  *Result
+	pending []pendingFunction
+}
+
+func (resolver *resolver) openScope(parent *Scope, node ast.Node) *Scope {
+	scope := &Scope{
+		Parent:   parent,
+		Node:     node,
+		Bindings: make(map[string]*Binding),
+	}
+
+	if parent != nil {
+		parent.Children = append(parent.Children, scope)
+	}
+
+	if node != nil {
+		resolver.result.Scopes[node] = scope
+	}
+
+	return scope
+}
+
+func (resolver *resolver) declare(
+	scope       *Scope,
+	kind        BindingKind,
+	identifier  *ast.Identifier,
+	declaration ast.Node,
+) {
+	binding := &Binding{
+		Name:        identifier.Value,
+		Kind:        kind,
+		Identifier:  identifier,
+		Declaration: declaration,
+		Scope:       scope,
+	}
+
+	// a redeclaration replaces the earlier binding for all later uses
+	scope.Bindings[identifier.Value] = binding
+	resolver.result.Definitions[identifier] = binding
+}
+
+func (resolver *resolver) use(scope *Scope, identifier *ast.Identifier) {
+	if binding := resolver.lookup(scope, identifier); binding != nil {
+		binding.Uses = append(binding.Uses, identifier)
+	}
+}
+
+// `x = ...` updates the nearest binding of x, it does not count as a use
+func (resolver *resolver) assign(scope *Scope, assignment *ast.AssignExpression) {
+	identifier, ok := assignment.Target.(*ast.Identifier)
+	if !ok {
+		resolver.expression(scope, assignment.Target)
+		return
+	}
+
+	if binding := resolver.lookup(scope, identifier); binding != nil {
+		binding.Assignments = append(binding.Assignments, assignment)
+	}
+}
+
+// binds identifier to the binding visible in scope, nil if there is none
+func (resolver *resolver) lookup(scope *Scope, identifier *ast.Identifier) *Binding {
+	binding := scope.Lookup(identifier.Value)
+	if binding == nil {
+		resolver.result.Diagnostics = append(resolver.result.Diagnostics, Diagnostic{
+			Identifier: identifier,
+			Message:    fmt.Sprintf("undefined: %s", identifier.Value),
+		})
+		return nil
+	}
+
+	resolver.result.Definitions[identifier] = binding
+
+	// every function between the use and the declaration captures the binding
+	for current := scope; current != binding.Scope; current = current.Parent {
+		function, ok := current.Node.(*ast.FunctionLiteral)
+		if !ok {
+			continue
+		}
+
+		if !slices.Contains(resolver.result.FreeVariables[function], binding) {
+			resolver.result.FreeVariables[function] = append(resolver.result.FreeVariables[function], binding)
+		}
+	}
+
+	return binding
+}
+
+func (resolver *resolver) statements(scope *Scope, statements []ast.Statement) {
+	// declared functions are visible in the whole scope, before and after them
+	for _, statement := range statements {
+		if declaration, ok := ast.Unexported(statement).(*ast.FunctionDeclaration); ok && declaration.Name != nil {
+			resolver.declare(scope, FunctionBinding, declaration.Name, declaration)
+		}
+	}
+
+	for _, statement := range statements {
+		resolver.statement(scope, statement)
+	}
+}
+
+func (resolver *resolver) statement(scope *Scope, statement ast.Statement) {
+	switch node := statement.(type) {
+	case *ast.LetStatement:
+		// the value cannot see the name it is bound to (except inside functions)
+		resolver.expression(scope, node.Value)
+		for _, name := range node.Names() {
+			resolver.declare(scope, LetBinding, name, node)
+		}
+
+	case *ast.ReturnStatement:
+		resolver.expression(scope, node.ReturnValue)
+
+	case *ast.ThrowStatement:
+		resolver.expression(scope, node.Value)
+
+	case *ast.ExpressionStatement:
+		resolver.expression(scope, node.Expression)
+
+	case *ast.BlockStatement:
+		resolver.block(scope, node)
+
+	case *ast.FunctionDeclaration:
+		resolver.expression(scope, node.Function)
+
+	case *ast.ImportStatement:
+		resolver.declare(scope, ImportBinding, node.Name, node)
+
+	case *ast.ExportStatement:
+		resolver.statement(scope, node.Statement)
+
+	case *ast.WhileStatement:
+		resolver.expression(scope, node.Condition)
+		resolver.block(scope, node.Body)
+
+	case *ast.ForInStatement:
+		// the iterable is evaluated before the loop variables exist
+		resolver.expression(scope, node.Iterable)
+
+		loopScope := resolver.openScope(scope, node)
+		if node.Key != nil {
+			resolver.declare(loopScope, LoopBinding, node.Key, node)
+		}
+		if node.Value != nil {
+			resolver.declare(loopScope, LoopBinding, node.Value, node)
+		}
+
+		resolver.block(loopScope, node.Body)
+	}
+}
+
+func (resolver *resolver) block(scope *Scope, block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+
+	resolver.statements(resolver.openScope(scope, block), block.Statements)
+}
+
+func (resolver *resolver) expression(scope *Scope, expression ast.Expression) {
+	switch node := expression.(type) {
+	case *ast.Identifier:
+		resolver.use(scope, node)
+
+	case *ast.PrefixExpression:
+		resolver.expression(scope, node.Right)
+
+	case *ast.InfixExpression:
+		resolver.expression(scope, node.Left)
+		resolver.expression(scope, node.Right)
+
+	case *ast.IfExpression:
+		resolver.expression(scope, node.Condition)
+		resolver.block(scope, node.Consequence)
+		resolver.block(scope, node.Alternative)
+
+	case *ast.FunctionLiteral:
+		resolver.pending = append(resolver.pending, pendingFunction{
+			scope:    scope,
+			function: node,
+		})
+
+	case *ast.MacroLiteral:
+		// the body runs at expansion time -> it sees nothing but its parameters
+		// and the predeclared names
+		macroScope := resolver.openScope(resolver.result.Universe, node)
+		for _, parameter := range node.Parameters {
+			resolver.declare(macroScope, ParameterBinding, parameter, node)
+		}
+
+		resolver.statements(macroScope, node.Body.Statements)
+
+	case *ast.CallExpression:
+		// quote(code, captured names...)
+		if arguments, ok := ast.CallArguments(node, "quote"); ok && len(arguments) > 0 {
+			resolver.quote(scope, arguments[0])
+			return
+		}
+
+		resolver.expression(scope, node.Function)
+		for _, argument := range node.Arguments {
+			resolver.expression(scope, argument)
+		}
+
+	case *ast.IndexExpression:
+		resolver.expression(scope, node.Left)
+		resolver.expression(scope, node.Index)
+
+	case *ast.Comprehension:
+		// each iterable is evaluated before the variables of its generator exist
+		inner := scope
+		for _, generator := range node.Generators {
+			resolver.expression(inner, generator.Iterable)
+
+			inner = resolver.openScope(inner, generator)
+			if generator.Key != nil {
+				resolver.declare(inner, LoopBinding, generator.Key, generator)
+			}
+			resolver.declare(inner, LoopBinding, generator.Value, generator)
+
+			for _, filter := range generator.Filters {
+				resolver.expression(inner, filter)
+			}
+		}
+
+		resolver.expression(inner, node.Key)
+		resolver.expression(inner, node.Value)
+
+	case *ast.RangeExpression:
+		resolver.expression(scope, node.Start)
+		resolver.expression(scope, node.End)
+
+	case *ast.TemplateLiteral:
+		for _, embedded := range node.Expressions {
+			resolver.expression(scope, embedded)
+		}
+
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			resolver.expression(scope, element)
+		}
+
+	case *ast.HashLiteral:
+		for i, key := range node.Keys {
+			resolver.expression(scope, key)
+			resolver.expression(scope, node.Values[i])
+		}
+
+	case *ast.MemberExpression:
+		// the property names a field or method, not a variable
+		resolver.expression(scope, node.Object)
+
+	case *ast.MatchExpression:
+		resolver.expression(scope, node.Subject)
+
+		for _, arm := range node.Arms {
+			armScope := resolver.openScope(scope, arm)
+			for _, name := range arm.Pattern.Names() {
+				resolver.declare(armScope, PatternBinding, name, arm)
+			}
+
+			resolver.expression(armScope, arm.Guard)
+			resolver.block(armScope, arm.Body)
+		}
+
+	case *ast.TryExpression:
+		resolver.block(scope, node.Body)
+
+		if node.Handler != nil {
+			catchScope := resolver.openScope(scope, node)
+			resolver.declare(catchScope, CatchBinding, node.Parameter, node)
+			resolver.block(catchScope, node.Handler)
+		}
+
+		resolver.block(scope, node.Finally)
+
+	case *ast.AssignExpression:
+		resolver.expression(scope, node.Value)
+		resolver.assign(scope, node)
+	}
+}
+
+// quoted code is data: only the values spliced in by unquote(x) are resolved
+func (resolver *resolver) quote(scope *Scope, quoted ast.Expression) {
+	ast.Apply(quoted, func(cursor *ast.Cursor) bool {
+		unquoted := ast.CallTo(cursor.Node(), "unquote")
+		if unquoted == nil {
+			return true
+		}
+
+		resolver.expression(scope, unquoted)
+		return false
+	}, nil)
+}
+
+func (resolver *resolver) functionBody(scope *Scope, function *ast.FunctionLiteral) {
+	functionScope := resolver.openScope(scope, function)
+
+	// a default value sees the parameters before it, but not its own
+	for i, parameter := range function.Parameters {
+		resolver.expression(functionScope, function.Default(i))
+		resolver.declare(functionScope, ParameterBinding, parameter, function)
+	}
+
+	if function.Body != nil {
+		resolver.statements(functionScope, function.Body.Statements)
+	}
+}
+
+//---[ Resolver Helper Methods ]------------------------------------------------
@@ -0,0 +1,243 @@
+package resolver
+
+import (
+	"slices"
+	"testing"
+
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+)
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func TestResolveDefinitions(t *testing.T) {
+	input := `
+let x = 5;
+let y = x + 1;
+let x = y;
+x;
+`
+	program := parse(t, input)
+	result  := Resolve(program)
+
+	checkNoDiagnostics(t, result)
+
+	first  := program.Statements[0].(*ast.LetStatement)
+	second := program.Statements[1].(*ast.LetStatement)
+	third  := program.Statements[2].(*ast.LetStatement)
+	last   := program.Statements[3].(*ast.ExpressionStatement).Expression.(*ast.Identifier)
+
+	xInY := second.Value.(*ast.InfixExpression).Left.(*ast.Identifier)
+	if result.Definitions[xInY].Declaration != first {
+		t.Errorf("x in `let y` not bound to first let. got=%v", result.Definitions[xInY].Declaration)
+	}
+
+	if result.Definitions[last].Declaration != third {
+		t.Errorf("final x not bound to redeclaration. got=%v", result.Definitions[last].Declaration)
+	}
+
+	if binding := result.Definitions[first.Name]; binding.Kind != LetBinding || len(binding.Uses) != 1 {
+		t.Errorf("first x binding wrong. kind=%d uses=%d", binding.Kind, len(binding.Uses))
+	}
+}
+
+func TestResolveUndefinedNames(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string
+	}{
+		{"let a = b;", []string{"undefined: b"}},
+		{"x; let x = 1;", []string{"undefined: x"}},
+		{"let x = x + 1;", []string{"undefined: x"}},
+		{"if (true) { let z = 1; z }; z", []string{"undefined: z"}},
+		{"fn(a) { a }; a", []string{"undefined: a"}},
+		{"let add = fn(a, b) { a + b + c };", []string{"undefined: c"}},
+		{"let add = fn(a, b) { a + b + c }; let c = 1;", []string{}},
+		{"let f = fn(n) { if (n < 1) { 0 } else { f } };", []string{}},
+		{"let i = 0; while (i < n) { let j = i; j }; j", []string{"undefined: n", "undefined: j"}},
+		{"for (k, v in k) { k + v }; v", []string{"undefined: k", "undefined: v"}},
+		{"even(2); fn even(n) { odd(n) } fn odd(n) { even(n) }", []string{}},
+		{"if (true) { fn local() { 1 } }; local()", []string{"undefined: local"}},
+		{"let f = fn() { g() }; let x = g(); fn g() { 1 }", []string{}},
+		{"fn(a, b = a + 1, ...rest) { a + b + rest }", []string{}},
+		{"fn(a = b, b = 1) { a }", []string{"undefined: b"}},
+		{"fn(a = a) { a }", []string{"undefined: a"}},
+		{"let [a, ...rest] = a; let {name} = rest; a + name", []string{"undefined: a"}},
+		{"let x = 1; match (x) { [a, b] if a > c => a + b, _ => a }", []string{"undefined: c", "undefined: a"}},
+		{"let m = macro(a) { quote(a + b + unquote(a)) }; m(1)", []string{}},
+		{"let x = 1; let m = macro(a) { x; quote(unquote(y)) };", []string{"undefined: x", "undefined: y"}},
+		{`lib; import "lib.mk" as lib; lib`, []string{"undefined: lib"}},
+		{"export let x = y; export fn f() { g() } fn g() { f() }", []string{"undefined: y"}},
+		{"f(); export fn f() { 1 }", []string{}},
+		{"let p = 1; p.name; p.len(name)", []string{"undefined: name"}},
+		{"let n = 1; `${n} ${m} ${`${k}`}`", []string{"undefined: m", "undefined: k"}},
+		{"let xs = 1; xs[a..]; xs[..b]; c..=xs", []string{"undefined: a", "undefined: b", "undefined: c"}},
+		{`let x = 1; [x, a]; {"x": x, b: [c]}`, []string{"undefined: a", "undefined: b", "undefined: c"}},
+		{"let xs = 1; [x + y for x in y for y in xs if x]; x", []string{"undefined: y", "undefined: x"}},
+		{"let h = 1; {k: v for (k, v) in h if k > v for w in v}; [w for w in w]", []string{"undefined: w"}},
+		{"try { e } catch (e) { throw e } finally { e }", []string{"undefined: e", "undefined: e"}},
+	}
+
+	for _, test := range tests {
+		result := Resolve(parse(t, test.input))
+
+		messages := []string{}
+		for _, diagnostic := range result.Diagnostics {
+			messages = append(messages, diagnostic.Message)
+		}
+
+		if !slices.Equal(messages, test.expected) {
+			t.Errorf("%q - wrong diagnostics. want=%v, got=%v", test.input, test.expected, messages)
+		}
+	}
+}
+
+func TestDiagnosticPosition(t *testing.T) {
+	result := Resolve(parse(t, "let a = 1;\nlet b = a + c;"))
+
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic. got=%v", result.Diagnostics)
+	}
+
+	if result.Diagnostics[0].String() != "2:13: undefined: c" {
+		t.Errorf("diagnostic wrong. got=%q", result.Diagnostics[0].String())
+	}
+}
+
+func TestResolveFreeVariables(t *testing.T) {
+	input := `
+let k = 1;
+let outer = fn(x) {
+	let local = 2;
+	fn(y) { x + y + k + local };
+};
+`
+	program := parse(t, input)
+	result  := Resolve(program)
+
+	checkNoDiagnostics(t, result)
+
+	outer := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	inner := outer.Body.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	tests := []struct{
+		function *ast.FunctionLiteral
+		expected []string
+	}{
+		{outer, []string{"k"}},
+		{inner, []string{"k", "local", "x"}},
+	}
+
+	for _, test := range tests {
+		names := []string{}
+		for _, binding := range result.FreeVariables[test.function] {
+			names = append(names, binding.Name)
+		}
+		slices.Sort(names)
+
+		if !slices.Equal(names, test.expected) {
+			t.Errorf("free variables of %s wrong. want=%v, got=%v",
+				test.function, test.expected, names)
+		}
+	}
+
+	if scope := result.Scopes[inner]; scope.Parent != result.Scopes[outer] {
+		t.Errorf("inner function scope not nested in outer function scope")
+	}
+}
+
+func TestResolveAssignments(t *testing.T) {
+	input := `
+let count = 0;
+let bump = fn(n) {
+	let count = n;
+	count += 1;
+};
+count = count + 1;
+`
+	program := parse(t, input)
+	result  := Resolve(program)
+
+	checkNoDiagnostics(t, result)
+
+	outer := result.Definitions[program.Statements[0].(*ast.LetStatement).Name]
+	bump  := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	inner := result.Definitions[bump.Body.Statements[0].(*ast.LetStatement).Name]
+
+	// each assignment updates the nearest binding
+	if len(outer.Assignments) != 1 || outer.Assignments[0].String() != "(count = (count + 1))" {
+		t.Errorf("outer count assignments wrong. got=%v", outer.Assignments)
+	}
+
+	if len(inner.Assignments) != 1 || inner.Assignments[0].Operator != "+=" {
+		t.Errorf("inner count assignments wrong. got=%v", inner.Assignments)
+	}
+
+	// assignments are not uses
+	if len(outer.Uses) != 1 || len(inner.Uses) != 0 {
+		t.Errorf("uses wrong. outer=%d, inner=%d", len(outer.Uses), len(inner.Uses))
+	}
+
+	diagnostics := Resolve(parse(t, "x = 1; let f = fn() { y += 1 };")).Diagnostics
+	if len(diagnostics) != 2 || diagnostics[0].Message != "undefined: x" || diagnostics[1].Message != "undefined: y" {
+		t.Errorf("diagnostics wrong. got=%v", diagnostics)
+	}
+
+	// a function assigning an outer name captures it, even without reading it
+	program = parse(t, "let n = 0; let reset = fn() { n = 0 };")
+	result  = Resolve(program)
+	reset  := program.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+
+	if free := result.FreeVariables[reset]; len(free) != 1 || free[0].Name != "n" {
+		t.Errorf("free variables of reset wrong. got=%v", free)
+	}
+}
+
+func TestResolvePredeclared(t *testing.T) {
+	program := parse(t, "let n = len + 1;")
+	result  := Resolve(program, "len")
+
+	checkNoDiagnostics(t, result)
+
+	use := program.Statements[0].(*ast.LetStatement).Value.(*ast.InfixExpression).Left.(*ast.Identifier)
+	if binding := result.Definitions[use]; binding == nil || binding.Kind != Predeclared {
+		t.Errorf("len not bound to predeclared binding. got=%+v", binding)
+	}
+
+	// macro bodies see predeclared names, but not the program's
+	program = parse(t, "let x = 1; let m = macro(a) { len(x) };")
+	result  = Resolve(program, "len")
+
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Message != "undefined: x" {
+		t.Errorf("macro body diagnostics wrong. got=%v", result.Diagnostics)
+	}
+}
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func parse(t *testing.T, input string) *ast.Program {
+	p       := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return program
+}
+
+func checkNoDiagnostics(t *testing.T, result *Result) {
+	for _, diagnostic := range result.Diagnostics {
+		t.Errorf("unexpected diagnostic: %s", diagnostic)
+	}
+
+	if len(result.Diagnostics) != 0 {
+		t.FailNow()
+	}
+}
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
@@ -1,13 +1,20 @@
 package token
 
+import (
+	"fmt"
+)
+
 const (
 	// Special Types
 	ILLEGAL = "ILLEGAL"  // token / character not covered by lexer
 	EOF     = "EOF"      // end of file (parser can stop)
+	COMMENT = "COMMENT"  // `// ...` -> collected by lexer, never handed to parser
 
 	// Identifiers + Literals
-	IDENT = "IDENT"
-	INT   = "INT"
+	IDENT    = "IDENT"
+	INT      = "INT"
+	STRING   = "STRING"    // "..." -> Literal holds the text between the quotes
+	TEMPLATE = "TEMPLATE"  // `...${x}...` -> Literal holds the text between the backticks
 
 	// Operators
 	ASSIGN   = "="
@@ -23,9 +30,28 @@ const (
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+
+	ARROW     = "->"   // result type of a function type annotation
+	FAT_ARROW = "=>"   // pattern => body of a match arm
+	PIPE      = "|>"   // x |> f(y) -> f(x, y)
+	ELLIPSIS  = "..."  // rest parameter
+	DOT       = "."    // object.property
+
+	RANGE           = ".."   // a..b -> a up to (excluding) b
+	RANGE_INCLUSIVE = "..="  // a..=b -> a up to (including) b
+
+	OPTIONAL_DOT      = "?."  // object?.property -> null if object is null
+	OPTIONAL_LBRACKET = "?["  // xs?[i] -> null if xs is null
+	NULLISH           = "??"  // a ?? b -> b if a is null
+
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
 
 	// Balanced
 	LPAREN   = "("
@@ -40,9 +66,24 @@ const (
 	LET      = "LET"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
+	NULL     = "NULL"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	FOR      = "FOR"
+	IN       = "IN"
+	MATCH    = "MATCH"
+	MACRO    = "MACRO"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
 )
 
 // type alias (change to enums later?)
@@ -53,14 +94,51 @@ var keywords = map[string]TokenType{
 	"let":    LET,
 	"true":   TRUE,
 	"false":  FALSE,
+	"null":   NULL,
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+
+	"while":    WHILE,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"for":      FOR,
+	"in":       IN,
+	"match":    MATCH,
+	"macro":    MACRO,
+
+	"import": IMPORT,
+	"export": EXPORT,
+	"as":     AS,
+
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"throw":   THROW,
 }
 
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type     TokenType
+	Literal  string
+	Position Position  // where the token starts in the source
+}
+
+// 1-based line & column (in bytes) of a token. Zero value: unknown position
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (position Position) IsValid() bool {
+	return position.Line > 0
+}
+
+func (position Position) String() string {
+	if !position.IsValid() {
+		return "-"
+	}
+
+	return fmt.Sprintf("%d:%d", position.Line, position.Column)
 }
 
 // Checks if identifier is in keywords map
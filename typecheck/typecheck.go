@@ -0,0 +1,1017 @@
+package typecheck
+
+import (
+	"fmt"
+	"slices"
+
+	"monkey/ast"
+	"monkey/token"
+)
+
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (err Error) String() string {
+	return fmt.Sprintf("%s: %s", err.Position, err.Message)
+}
+
+// Binding is the inferred type of a top-level let.
+type Binding struct {
+	Name     string
+	Scheme   *Scheme
+	Position token.Position
+}
+
+func (binding Binding) String() string {
+	return fmt.Sprintf("%s: %s", binding.Name, binding.Scheme)
+}
+
+type Result struct {
+	Types    map[ast.Expression]Type  // inferred type of every expression
+	Bindings []Binding                // top-level declared functions (hoisted), then lets in source order
+	Errors   []Error
+}
+
+
+//---[ Module API Functions ]---------------------------------------------------
+
+// Check infers the types of program with Hindley-Milner style inference.
+//
+// The typing rules are stricter than the evaluator: operands of - * / < >
+// must be int, those of + both int or both string, both sides of == and != must have the same type and if
+// and while conditions must be bool. An if without else has type null. Lets are
+// generalized, so `let id = fn(x) { x }` may be used at any type.
+//
+// Annotations (`let x: int = 5;`, `fn(a: int) -> bool`) are optional. Where
+// present they fix the type of the name or result instead of inferring it.
+//
+// predeclared gives the types of names declared before the program starts
+// (builtins, REPL state); the program may shadow them. It may be nil.
+func Check(program *ast.Program, predeclared map[string]*Scheme) *Result {
+	checker := &checker{
+		result: &Result{
+			Types: make(map[ast.Expression]Type),
+		},
+		forward: make(map[string]*forwardReference),
+	}
+
+	universe := newEnvironment(nil)
+	for name, scheme := range predeclared {
+		universe.schemes[name] = scheme
+	}
+
+	checker.global = newEnvironment(universe)
+	checker.statements(checker.global, program.Statements)
+
+	// uses of names no top-level let ever declared
+	for _, name := range checker.forwardOrder {
+		if reference, ok := checker.forward[name]; ok {
+			checker.errorAt(reference.identifier, "undefined: %s", name)
+		}
+	}
+
+	return checker.result
+}
+
+//---[ Module API Functions ]---------------------------------------------------
+
+
+//---[ Checker Helper Methods ]-------------------------------------------------
+
+type environment struct {
+	parent  *environment
+	schemes map[string]*Scheme
+}
+
+func newEnvironment(parent *environment) *environment {
+	return &environment{
+		parent:  parent,
+		schemes: make(map[string]*Scheme),
+	}
+}
+
+func (env *environment) lookup(name string) *Scheme {
+	for current := env; current != nil; current = current.parent {
+		if scheme, ok := current.schemes[name]; ok {
+			return scheme
+		}
+	}
+
+	return nil
+}
+
+// a name used before (or without) a top-level let declaring it: function
+// bodies may refer to lets further down, as they run only when called
+type forwardReference struct {
+	identifier *ast.Identifier  // first use
+	variable   *TypeVariable
+}
+
+type checker struct {
+	result  *Result
+	global  *environment
+	nextID  int
+
+	returnTypes []Type  // result types of the enclosing functions, innermost last
+
+	forward      map[string]*forwardReference
+	forwardOrder []string
+}
+
+func (checker *checker) fresh() *TypeVariable {
+	checker.nextID++
+	return &TypeVariable{id: checker.nextID}
+}
+
+func (checker *checker) errorAt(node ast.Node, format string, args ...any) {
+	checker.result.Errors = append(checker.result.Errors, Error{
+		Position: ast.Pos(node),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// unifies and reports `expected X, got Y` at node on failure
+func (checker *checker) expect(node ast.Node, expected, actual Type) {
+	if unify(expected, actual) {
+		return
+	}
+
+	printer := newPrinter()
+	checker.errorAt(node, "type mismatch: expected %s, got %s", printer.print(expected), printer.print(actual))
+}
+
+
+func (checker *checker) statements(env *environment, statements []ast.Statement) Type {
+	var last Type = Null
+
+	checker.declareFunctions(env, statements)
+
+	for _, statement := range statements {
+		last = checker.statement(env, statement)
+	}
+
+	return last
+}
+
+func (checker *checker) statement(env *environment, statement ast.Statement) Type {
+	switch node := statement.(type) {
+	case *ast.LetStatement:
+		checker.let(env, node)
+		return Null
+
+	case *ast.ReturnStatement:
+		value := checker.expression(env, node.ReturnValue)
+
+		if depth := len(checker.returnTypes); depth > 0 {
+			checker.expect(node.ReturnValue, checker.returnTypes[depth-1], value)
+		}
+
+		// control never reaches whatever uses the value of a return
+		return checker.fresh()
+
+	case *ast.ThrowStatement:
+		// anything can be thrown. Like return, nothing uses the value of a throw
+		checker.expression(env, node.Value)
+		return checker.fresh()
+
+	case *ast.ExpressionStatement:
+		return checker.expression(env, node.Expression)
+
+	case *ast.BlockStatement:
+		return checker.block(env, node)
+
+	case *ast.FunctionDeclaration:
+		// checked up front by declareFunctions
+		return Null
+
+	case *ast.ImportStatement:
+		// the exports of other modules are not checked along -> unknown
+		checker.bind(env, node.Name, &Scheme{Type: checker.fresh()})
+		return Null
+
+	case *ast.ExportStatement:
+		checker.statement(env, node.Statement)
+		return Null
+
+	case *ast.WhileStatement:
+		condition := checker.expression(env, node.Condition)
+		checker.expect(node.Condition, Bool, condition)
+		checker.block(env, node.Body)
+
+		return Null
+
+	case *ast.ForInStatement:
+		iterable := checker.expression(env, node.Iterable)
+		loop     := checker.loopVariables(env, iterable, node.Key, node.Value)
+
+		checker.block(loop, node.Body)
+
+		return Null
+	}
+
+	// break / continue: like return, nothing uses their value
+	return checker.fresh()
+}
+
+func (checker *checker) let(env *environment, let *ast.LetStatement) {
+	if let.Pattern != nil {
+		// no array / hash types yet -> the destructured parts stay unknown
+		checker.expression(env, let.Value)
+
+		for _, name := range let.Pattern.Names() {
+			checker.bind(env, name, &Scheme{Type: checker.fresh()})
+		}
+
+		return
+	}
+
+	if let.Name == nil {
+		return
+	}
+
+	var value Type
+
+	// functions may call themselves -> name is bound (monomorphic) in its own value
+	if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+		self     := checker.annotation(let.Name.Type)
+		previous := env.schemes[let.Name.Value]
+
+		env.schemes[let.Name.Value] = &Scheme{Type: self}
+		value = checker.expression(env, let.Value)
+		checker.expect(let.Value, self, value)
+
+		// same as value unless annotated -> then the declared type wins
+		value = self
+
+		if previous != nil {
+			env.schemes[let.Name.Value] = previous
+		} else {
+			delete(env.schemes, let.Name.Value)
+		}
+	} else {
+		value = checker.expression(env, let.Value)
+
+		if let.Name.Type != nil {
+			declared := checker.annotation(let.Name.Type)
+			checker.expect(let.Value, declared, value)
+
+			value = declared
+		}
+	}
+
+	checker.bind(env, let.Name, checker.generalize(env, value))
+}
+
+// declares name in env, top-level names also settle forward references
+func (checker *checker) bind(env *environment, name *ast.Identifier, scheme *Scheme) {
+	env.schemes[name.Value] = scheme
+
+	if env != checker.global {
+		return
+	}
+
+	if reference, ok := checker.forward[name.Value]; ok {
+		delete(checker.forward, name.Value)
+		checker.expect(reference.identifier, reference.variable, checker.instantiate(scheme))
+	}
+
+	checker.result.Bindings = append(checker.result.Bindings, Binding{
+		Name:     name.Value,
+		Scheme:   scheme,
+		Position: name.Token.Position,
+	})
+}
+
+// the declared functions of a block are hoisted and may call each other: they
+// are inferred together with monomorphic names, then generalized at once
+func (checker *checker) declareFunctions(env *environment, statements []ast.Statement) {
+	declarations := []*ast.FunctionDeclaration{}
+	types        := []Type{}
+
+	for _, statement := range statements {
+		declaration, ok := ast.Unexported(statement).(*ast.FunctionDeclaration)
+		if !ok || declaration.Name == nil {
+			continue
+		}
+
+		self := checker.fresh()
+		env.schemes[declaration.Name.Value] = &Scheme{Type: self}
+
+		declarations = append(declarations, declaration)
+		types        = append(types, self)
+	}
+
+	for i, declaration := range declarations {
+		value := checker.expression(env, declaration.Function)
+		checker.expect(declaration.Function, types[i], value)
+	}
+
+	// the monomorphic names must not keep their own variables from generalizing
+	for _, declaration := range declarations {
+		delete(env.schemes, declaration.Name.Value)
+	}
+
+	schemes := []*Scheme{}
+	for _, self := range types {
+		schemes = append(schemes, checker.generalize(env, self))
+	}
+
+	for i, declaration := range declarations {
+		checker.bind(env, declaration.Name, schemes[i])
+	}
+}
+
+func (checker *checker) block(env *environment, block *ast.BlockStatement) Type {
+	if block == nil {
+		return Null
+	}
+
+	return checker.statements(newEnvironment(env), block.Statements)
+}
+
+// the statement a block evaluates to, where its type errors are reported
+func valueOf(block *ast.BlockStatement) ast.Node {
+	if block == nil || len(block.Statements) == 0 {
+		return block
+	}
+
+	return block.Statements[len(block.Statements)-1]
+}
+
+func (checker *checker) expression(env *environment, expression ast.Expression) Type {
+	if expression == nil {
+		return checker.fresh()
+	}
+
+	inferred := checker.infer(env, expression)
+	checker.result.Types[expression] = inferred
+
+	return inferred
+}
+
+func (checker *checker) infer(env *environment, expression ast.Expression) Type {
+	switch node := expression.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+
+	case *ast.Boolean:
+		return Bool
+
+	case *ast.StringLiteral:
+		return String
+
+	case *ast.TemplateLiteral:
+		// embedded values of any type are converted to text
+		for _, embedded := range node.Expressions {
+			checker.expression(env, embedded)
+		}
+
+		return String
+
+	case *ast.ArrayLiteral:
+		// no array / hash types yet -> unknown, but the entries are checked
+		for _, element := range node.Elements {
+			checker.expression(env, element)
+		}
+
+		return checker.fresh()
+
+	case *ast.HashLiteral:
+		for i, key := range node.Keys {
+			checker.expression(env, key)
+			checker.expression(env, node.Values[i])
+		}
+
+		return checker.fresh()
+
+	case *ast.NullLiteral:
+		return Null
+
+	case *ast.Identifier:
+		return checker.identifier(env, node)
+
+	case *ast.PrefixExpression:
+		right := checker.expression(env, node.Right)
+
+		switch node.Operator {
+		case "-":
+			checker.expect(node.Right, Int, right)
+			return Int
+		case "!":
+			return Bool
+		}
+
+	case *ast.InfixExpression:
+		left  := checker.expression(env, node.Left)
+		right := checker.expression(env, node.Right)
+
+		switch node.Operator {
+		case "+":
+			// int + int or string + string: the left side decides, the right one if
+			// the left is unknown yet, integers if both are
+			operand := prune(left)
+			if _, unknown := operand.(*TypeVariable); unknown {
+				operand = prune(right)
+			}
+
+			if operand == String {
+				checker.expect(node.Left, String, left)
+				checker.expect(node.Right, String, right)
+				return String
+			}
+
+			fallthrough
+		case "-", "*", "/":
+			checker.expect(node.Left, Int, left)
+			checker.expect(node.Right, Int, right)
+			return Int
+		case "<", ">":
+			checker.expect(node.Left, Int, left)
+			checker.expect(node.Right, Int, right)
+			return Bool
+		case "==", "!=":
+			checker.expect(node.Right, left, right)
+			return Bool
+		case "??":
+			// no optional types: a left side that is not null is never replaced
+			if prune(left) == Null {
+				return right
+			}
+
+			checker.expect(node.Right, left, right)
+			return left
+		}
+
+	case *ast.IfExpression:
+		condition := checker.expression(env, node.Condition)
+		checker.expect(node.Condition, Bool, condition)
+
+		consequence := checker.block(env, node.Consequence)
+		if node.Alternative == nil {
+			return Null
+		}
+
+		alternative := checker.block(env, node.Alternative)
+		checker.expect(valueOf(node.Alternative), consequence, alternative)
+
+		return consequence
+
+	case *ast.FunctionLiteral:
+		return checker.function(env, node)
+
+	case *ast.CallExpression:
+		return checker.call(env, node)
+
+	case *ast.IndexExpression:
+		left := checker.expression(env, node.Left)
+
+		// a slice is a part of what is sliced: a string of a string, ...
+		if slice, ok := node.Index.(*ast.RangeExpression); ok {
+			checker.bounds(env, slice)
+			return left
+		}
+
+		// no indexable types yet -> element type unknown
+		checker.expression(env, node.Index)
+
+		return checker.fresh()
+
+	case *ast.RangeExpression:
+		checker.bounds(env, node)
+		return Range
+
+	case *ast.Comprehension:
+		return checker.comprehension(env, node)
+
+	case *ast.MemberExpression:
+		// a hash field / exported name: unknown. Known types have their Fields
+		object := checker.expression(env, node.Object)
+
+		operator, ok := prune(object).(*TypeOperator)
+		if !ok {
+			return checker.fresh()
+		}
+
+		if node.Optional && operator == Null {
+			return Null
+		}
+
+		field, ok := Fields[operator.Name][node.Property.Value]
+		if !ok {
+			checker.errorAt(node.Property, "%s has no field %s", TypeString(operator), node.Property.Value)
+		}
+
+		if field == nil {
+			return checker.fresh()
+		}
+
+		return field
+
+	case *ast.TryExpression:
+		return checker.try(env, node)
+
+	case *ast.AssignExpression:
+		return checker.assign(env, node)
+
+	case *ast.MatchExpression:
+		return checker.match(env, node)
+
+	case *ast.MacroLiteral:
+		// macros are expanded before the program runs (see package macro)
+		return checker.fresh()
+	}
+
+	checker.errorAt(expression, "cannot infer type of %s", expression)
+	return checker.fresh()
+}
+
+func (checker *checker) identifier(env *environment, identifier *ast.Identifier) Type {
+	if scheme := env.lookup(identifier.Value); scheme != nil {
+		return checker.instantiate(scheme)
+	}
+
+	reference, ok := checker.forward[identifier.Value]
+	if !ok {
+		reference = &forwardReference{
+			identifier: identifier,
+			variable:   checker.fresh(),
+		}
+
+		checker.forward[identifier.Value] = reference
+		checker.forwardOrder = append(checker.forwardOrder, identifier.Value)
+	}
+
+	return reference.variable
+}
+
+func (checker *checker) function(env *environment, function *ast.FunctionLiteral) Type {
+	// parameters and the top level of the body share one scope
+	scope      := newEnvironment(env)
+	parameters := []Type{}
+
+	for i, parameter := range function.Parameters {
+		variable := checker.annotation(parameter.Type)
+
+		// defaults see the parameters before them, like in the resolver
+		if value := function.Default(i); value != nil {
+			checker.expect(value, variable, checker.expression(scope, value))
+		}
+
+		scope.schemes[parameter.Value] = &Scheme{Type: variable}
+		parameters = append(parameters, variable)
+
+		checker.result.Types[parameter] = variable
+	}
+
+	result := checker.annotation(function.ReturnType)
+	checker.returnTypes = append(checker.returnTypes, result)
+
+	var body Type = Null
+	if function.Body != nil {
+		body = checker.statements(scope, function.Body.Statements)
+	}
+
+	checker.returnTypes = checker.returnTypes[:len(checker.returnTypes)-1]
+	checker.expect(valueOf(function.Body), result, body)
+
+	functionType := Function(parameters, result)
+	functionType.Variadic = function.Variadic
+	for _, value := range function.Defaults {
+		if value != nil {
+			functionType.Optional++
+		}
+	}
+
+	return functionType
+}
+
+// the assigned value must keep the target's type, compound operators work on
+// ints like their infix counterparts
+func (checker *checker) assign(env *environment, assign *ast.AssignExpression) Type {
+	target := checker.expression(env, assign.Target)
+	value  := checker.expression(env, assign.Value)
+
+	if assign.Operator != "=" {
+		checker.expect(assign.Target, Int, target)
+		checker.expect(assign.Value, Int, value)
+
+		return Int
+	}
+
+	checker.expect(assign.Value, target, value)
+
+	return target
+}
+
+// the environment of a loop body over iterable: only ranges have a known
+// element type (int), other elements stay unknown
+func (checker *checker) loopVariables(env *environment, iterable Type, key, value *ast.Identifier) *environment {
+	loop := newEnvironment(env)
+
+	for _, variable := range []*ast.Identifier{key, value} {
+		if variable == nil {
+			continue
+		}
+
+		if prune(iterable) == Range {
+			loop.schemes[variable.Value] = &Scheme{Type: Int}
+		} else {
+			loop.schemes[variable.Value] = &Scheme{Type: checker.fresh()}
+		}
+	}
+
+	return loop
+}
+
+// no array / hash types yet -> the result is unknown, but the generators'
+// variables, filters (bools) and elements are checked
+func (checker *checker) comprehension(env *environment, comprehension *ast.Comprehension) Type {
+	inner := env
+
+	for _, generator := range comprehension.Generators {
+		iterable := checker.expression(inner, generator.Iterable)
+		inner     = checker.loopVariables(inner, iterable, generator.Key, generator.Value)
+
+		for _, filter := range generator.Filters {
+			checker.expect(filter, Bool, checker.expression(inner, filter))
+		}
+	}
+
+	if comprehension.Key != nil {
+		checker.expression(inner, comprehension.Key)
+	}
+	checker.expression(inner, comprehension.Value)
+
+	return checker.fresh()
+}
+
+// the bounds of a range or slice are ints, either may be left out of a slice
+func (checker *checker) bounds(env *environment, rng *ast.RangeExpression) {
+	for _, bound := range []ast.Expression{rng.Start, rng.End} {
+		if bound != nil {
+			checker.expect(bound, Int, checker.expression(env, bound))
+		}
+	}
+}
+
+// the catch block replaces the value of the body -> both have to agree. The
+// value of the finally block is dropped
+func (checker *checker) try(env *environment, try *ast.TryExpression) Type {
+	body := checker.block(env, try.Body)
+
+	if try.Handler != nil {
+		scope := newEnvironment(env)
+		scope.schemes[try.Parameter.Value] = &Scheme{Type: ErrorValue}
+
+		handler := checker.block(scope, try.Handler)
+		checker.expect(valueOf(try.Handler), body, handler)
+	}
+
+	checker.block(env, try.Finally)
+
+	return body
+}
+
+// the arms have to agree on their type. Without a catch-all arm, possibly no
+// arm matches -> null, like an if without else
+func (checker *checker) match(env *environment, match *ast.MatchExpression) Type {
+	subject := checker.expression(env, match.Subject)
+
+	var result Type
+	for _, arm := range match.Arms {
+		scope := newEnvironment(env)
+		checker.pattern(scope, arm.Pattern, subject)
+
+		if arm.Guard != nil {
+			guard := checker.expression(scope, arm.Guard)
+			checker.expect(arm.Guard, Bool, guard)
+		}
+
+		body := checker.block(scope, arm.Body)
+		if result == nil {
+			result = body
+		} else {
+			checker.expect(valueOf(arm.Body), result, body)
+		}
+	}
+
+	if !match.HasCatchAll() {
+		return Null
+	}
+
+	return result
+}
+
+// declares the names of pattern in env. Literal patterns fix the type of the
+// value they are compared with, array / hash parts stay unknown (no types yet)
+func (checker *checker) pattern(env *environment, pattern ast.Pattern, value Type) {
+	switch node := pattern.(type) {
+	case *ast.Identifier:
+		env.schemes[node.Value] = &Scheme{Type: value}
+		checker.result.Types[node] = value
+
+	case *ast.LiteralPattern:
+		literal := checker.expression(env, node.Value)
+
+		// no optional types: any value may be compared with null
+		if _, isNull := node.Value.(*ast.NullLiteral); !isNull {
+			checker.expect(node, value, literal)
+		}
+
+	case *ast.ArrayPattern:
+		for _, element := range node.Elements {
+			checker.pattern(env, element, checker.fresh())
+		}
+
+		if node.Rest != nil {
+			checker.pattern(env, node.Rest, checker.fresh())
+		}
+
+	case *ast.HashPattern:
+		for i := range node.Keys {
+			checker.pattern(env, node.Value(i), checker.fresh())
+		}
+	}
+}
+
+// the type an annotation stands for, a fresh variable if there is none
+func (checker *checker) annotation(annotation ast.TypeExpression) Type {
+	switch node := annotation.(type) {
+	case *ast.NamedType:
+		switch node.Name {
+		case "int":
+			return Int
+		case "bool":
+			return Bool
+		case "string":
+			return String
+		case "error":
+			return ErrorValue
+		case "null":
+			return Null
+		case "range":
+			return Range
+		}
+
+		checker.errorAt(node, "unknown type %s", node.Name)
+
+	case *ast.FunctionType:
+		parameters := []Type{}
+		for _, parameter := range node.Parameters {
+			parameters = append(parameters, checker.annotation(parameter))
+		}
+
+		return Function(parameters, checker.annotation(node.Result))
+	}
+
+	return checker.fresh()
+}
+
+func (checker *checker) call(env *environment, call *ast.CallExpression) Type {
+	// quote(x) is the AST of x, which has no type (yet)
+	if arguments, ok := ast.CallArguments(call, "quote"); ok && len(arguments) > 0 {
+		return checker.fresh()
+	}
+
+	var callee Type
+
+	if member, ok := call.Function.(*ast.MemberExpression); ok {
+		receiver := checker.expression(env, member.Object)
+		if operator, ok := prune(receiver).(*TypeOperator); ok {
+			return checker.method(env, call, member, operator)
+		}
+
+		// a function stored in a hash / exported by a module: unknown
+		callee = checker.fresh()
+		checker.result.Types[member] = callee
+	} else {
+		callee = checker.expression(env, call.Function)
+	}
+
+	arguments := []Type{}
+
+	for _, argument := range call.Arguments {
+		arguments = append(arguments, checker.expression(env, argument))
+	}
+
+	function, ok := isFunction(callee)
+	if !ok {
+		// not known to be a function yet -> it has to become one
+		result := checker.fresh()
+		if _, isVariable := prune(callee).(*TypeVariable); !isVariable {
+			checker.errorAt(call.Function, "cannot call non-function %s of type %s", call.Function, TypeString(callee))
+			return result
+		}
+
+		checker.expect(call.Function, Function(arguments, result), callee)
+		return result
+	}
+
+	// values collected by a rest parameter are not checked (no array types)
+	parameters := function.Args[:function.fixedParameters()]
+	required   := len(parameters) - function.Optional
+	if len(arguments) < required || (!function.Variadic && len(arguments) > len(parameters)) {
+		checker.errorAt(call, "wrong number of arguments to %s: want %s, got %d",
+			call.Function, arity(function), len(arguments))
+		return function.Args[len(function.Args)-1]
+	}
+
+	for i := range min(len(parameters), len(arguments)) {
+		checker.expect(call.Arguments[i], parameters[i], arguments[i])
+	}
+
+	return function.Args[len(function.Args)-1]
+}
+
+// receiver.name(args) on a receiver of known type calls the builtin method
+// of that type with the receiver as its first argument
+func (checker *checker) method(
+	env      *environment,
+	call     *ast.CallExpression,
+	member   *ast.MemberExpression,
+	receiver *TypeOperator,
+) Type {
+	arguments := []Type{}
+	for _, argument := range call.Arguments {
+		arguments = append(arguments, checker.expression(env, argument))
+	}
+
+	if member.Optional && receiver == Null {
+		return Null
+	}
+
+	method, ok := Methods[receiver.Name][member.Property.Value]
+	if !ok {
+		checker.errorAt(member.Property, "%s has no method %s", TypeString(receiver), member.Property.Value)
+		return checker.fresh()
+	}
+
+	parameters := method.Args[1:len(method.Args)-1]
+	if len(arguments) != len(parameters) {
+		checker.errorAt(call, "wrong number of arguments to %s: want %d, got %d",
+			call.Function, len(parameters), len(arguments))
+	}
+
+	for i := range min(len(parameters), len(arguments)) {
+		checker.expect(call.Arguments[i], parameters[i], arguments[i])
+	}
+
+	return method.Args[len(method.Args)-1]
+}
+
+// 2, 1 to 2, at least 1
+func arity(function *TypeOperator) string {
+	fixed    := function.fixedParameters()
+	required := fixed - function.Optional
+
+	switch {
+	case function.Variadic:
+		return fmt.Sprintf("at least %d", required)
+	case function.Optional > 0:
+		return fmt.Sprintf("%d to %d", required, fixed)
+	}
+
+	return fmt.Sprintf("%d", fixed)
+}
+
+
+// generalizes over the variables of t that are not fixed by the environment
+func (checker *checker) generalize(env *environment, t Type) *Scheme {
+	fixed := map[*TypeVariable]bool{}
+
+	for current := env; current != nil; current = current.parent {
+		for _, scheme := range current.schemes {
+			schemeVariables := map[*TypeVariable]bool{}
+			freeVariables(scheme.Type, schemeVariables)
+
+			for variable := range schemeVariables {
+				if !slices.Contains(scheme.Generic, variable) {
+					fixed[variable] = true
+				}
+			}
+		}
+	}
+
+	// pending forward references will still be unified later
+	for _, reference := range checker.forward {
+		freeVariables(reference.variable, fixed)
+	}
+
+	scheme := &Scheme{Type: t}
+	for _, variable := range orderedVariables(t) {
+		if !fixed[variable] {
+			scheme.Generic = append(scheme.Generic, variable)
+		}
+	}
+
+	return scheme
+}
+
+func (checker *checker) instantiate(scheme *Scheme) Type {
+	if len(scheme.Generic) == 0 {
+		return scheme.Type
+	}
+
+	mapping := map[*TypeVariable]Type{}
+	for _, variable := range scheme.Generic {
+		mapping[variable] = checker.fresh()
+	}
+
+	return substitute(scheme.Type, mapping)
+}
+
+
+func unify(expected, actual Type) bool {
+	expected, actual = prune(expected), prune(actual)
+
+	if variable, ok := expected.(*TypeVariable); ok {
+		return bindVariable(variable, actual)
+	}
+
+	if variable, ok := actual.(*TypeVariable); ok {
+		return bindVariable(variable, expected)
+	}
+
+	left, right := expected.(*TypeOperator), actual.(*TypeOperator)
+	if left.Name != right.Name || len(left.Args) != len(right.Args) {
+		return false
+	}
+
+	// unify every argument, even after a failure, to learn as much as possible
+	ok := true
+	for i := range left.Args {
+		ok = unify(left.Args[i], right.Args[i]) && ok
+	}
+
+	return ok
+}
+
+func bindVariable(variable *TypeVariable, t Type) bool {
+	if other, ok := t.(*TypeVariable); ok && other == variable {
+		return true
+	}
+
+	// a = fn(a) -> ... has no finite solution
+	if occursIn(variable, t) {
+		return false
+	}
+
+	variable.instance = t
+	return true
+}
+
+func substitute(t Type, mapping map[*TypeVariable]Type) Type {
+	switch pruned := prune(t).(type) {
+	case *TypeVariable:
+		if replacement, ok := mapping[pruned]; ok {
+			return replacement
+		}
+
+		return pruned
+
+	case *TypeOperator:
+		if len(pruned.Args) == 0 {
+			return pruned
+		}
+
+		args := make([]Type, len(pruned.Args))
+		for i, arg := range pruned.Args {
+			args[i] = substitute(arg, mapping)
+		}
+
+		return &TypeOperator{
+			Name:     pruned.Name,
+			Args:     args,
+			Optional: pruned.Optional,
+			Variadic: pruned.Variadic,
+		}
+	}
+
+	return t
+}
+
+// variables of t in order of appearance (keeps Scheme.Generic deterministic)
+func orderedVariables(t Type) []*TypeVariable {
+	ordered := []*TypeVariable{}
+
+	var walk func(Type)
+	walk = func(t Type) {
+		switch pruned := prune(t).(type) {
+		case *TypeVariable:
+			if !slices.Contains(ordered, pruned) {
+				ordered = append(ordered, pruned)
+			}
+		case *TypeOperator:
+			for _, arg := range pruned.Args {
+				walk(arg)
+			}
+		}
+	}
+	walk(t)
+
+	return ordered
+}
+
+//---[ Checker Helper Methods ]-------------------------------------------------
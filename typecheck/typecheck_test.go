@@ -0,0 +1,406 @@
+package typecheck
+
+import (
+	"strings"
+	"testing"
+
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+)
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func TestInferredBindings(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string
+	}{
+		{"let x = 5;", []string{"x: int"}},
+		{"let b = !5;", []string{"b: bool"}},
+		{"let n = 1; let s = `${n} or ${n == 2}`;", []string{"n: int", "s: string"}},
+		{`let r = 0..=3; let s = "abc"[1..]; let t = "abc"[..-1];`, []string{"r: range", "s: string", "t: string"}},
+		{
+			"let sum = fn(n) { let total = 0; for (i in 0..n) { total += i }; total };",
+			[]string{"sum: fn(int) -> int"},
+		},
+		{"let head = fn(xs, n) { xs[..n] };", []string{"head: fn(a, int) -> a"}},
+		{"let evens = fn(n) { [i for i in 0..n if i / 2 * 2 == i] };", []string{"evens: fn(int) -> a"}},
+		{"let c = 1 < 2 == true;", []string{"c: bool"}},
+		{"let add = fn(a, b) { a + b };", []string{"add: fn(int, int) -> int"}},
+		{
+			`let greet = fn(name) { "hi " + name }; let twice = fn(s: string) { s + s };`,
+			[]string{"greet: fn(string) -> string", "twice: fn(string) -> string"},
+		},
+		{"let id = fn(x) { x };", []string{"id: fn(a) -> a"}},
+		{"let k = fn(x, y) { x };", []string{"k: fn(a, b) -> a"}},
+		{
+			"let apply = fn(f, x) { f(x) };",
+			[]string{"apply: fn(fn(a) -> b, a) -> b"},
+		},
+		{
+			"let compose = fn(f, g) { fn(x) { g(f(x)) } };",
+			[]string{"compose: fn(fn(a) -> b, fn(b) -> c) -> fn(a) -> c"},
+		},
+		{
+			"let fact = fn(n) { if (n < 2) { return 1; }; n * fact(n - 1) };",
+			[]string{"fact: fn(int) -> int"},
+		},
+		{
+			"let f = fn(b) { if (b) { 1 } else { 2 } };",
+			[]string{"f: fn(bool) -> int"},
+		},
+		{"let nothing = fn() { };", []string{"nothing: fn() -> null"}},
+		{"let maybe = fn(b) { if (b) { 1 } };", []string{"maybe: fn(bool) -> null"}},
+
+		{
+			"let count = fn(n) { while (n > 0) { if (n == 3) { break; }; continue; }; n };",
+			[]string{"count: fn(int) -> int"},
+		},
+
+		{
+			"let each = fn(xs, f) { for (i, x in xs) { f(i, x) }; true };",
+			[]string{"each: fn(a, fn(b, c) -> d) -> bool"},
+		},
+
+		{
+			"let counter = fn() { let n = 0; fn() { n += 1 } };",
+			[]string{"counter: fn() -> fn() -> int"},
+		},
+		{"let x = 1; let y = x = 2;", []string{"x: int", "y: int"}},
+
+		{
+			"let sign = fn(n) { if (n < 0) { -1 } else if (n == 0) { 0 } else { 1 } };",
+			[]string{"sign: fn(int) -> int"},
+		},
+
+		// declared functions are hoisted, mutually recursive and generalized
+		{
+			"let b = isEven(4); fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } } fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }",
+			[]string{"isEven: fn(int) -> bool", "isOdd: fn(int) -> bool", "b: bool"},
+		},
+		{
+			"fn id(x) { x } let pair = fn(a, b) { id(a) + 1; id(b) == true };",
+			[]string{"id: fn(a) -> a", "pair: fn(int, bool) -> bool"},
+		},
+
+		// let-polymorphism: id is used at two different types
+		{
+			"let id = fn(x) { x }; let a = id(1); let b = id(true);",
+			[]string{"id: fn(a) -> a", "a: int", "b: bool"},
+		},
+
+		// defaults fix their parameter's type, rest values are not checked
+		{
+			"let inc = fn(a, b = 1) { a + b }; let x = inc(1); let y = inc(1, 2);",
+			[]string{"inc: fn(int, [int]) -> int", "x: int", "y: int"},
+		},
+		{
+			"let first = fn(x, ...rest) { x }; let a = first(1, true, 3);",
+			[]string{"first: fn(a, ...b) -> a", "a: int"},
+		},
+
+		// destructured parts are unknown until arrays / hashes get types, then
+		// monomorphic like parameters
+		{
+			"let [a, ...b] = 1; let {c} = 2; let d = a + c;",
+			[]string{"a: int", "b: a", "c: int", "d: int"},
+		},
+
+		// literal patterns fix the subject, plain names bind it
+		{
+			"let sign = fn(n) { match (n) { 0 => 0, m if m < 0 => -1, _ => 1 } };",
+			[]string{"sign: fn(int) -> int"},
+		},
+		{
+			"let f = fn(b) { match (b) { true => 1, [x, ...r] => x } };",
+			[]string{"f: fn(bool) -> null"},
+		},
+		{
+			`let name = fn(s) { match (s) { "a" => 1, null => 0, _ => 2 } };`,
+			[]string{"name: fn(string) -> int"},
+		},
+		{
+			`let kind = fn(h) { match (h) { {kind: "a", "full name": n} => n, null => 0, _ => 1 } };`,
+			[]string{"kind: fn(a) -> int"},
+		},
+
+		// a pipeline is a call with the piped value as first argument
+		{
+			"let add = fn(a, b) { a + b }; let isPositive = fn(n) { n > 0 }; let ok = 1 |> add(2) |> isPositive;",
+			[]string{"add: fn(int, int) -> int", "isPositive: fn(int) -> bool", "ok: bool"},
+		},
+
+		// methods dispatch on the receiver's type, members of unknown values are unknown
+		{
+			`let n = "abc".len(); let size = fn(s) { s.len() }; let get = fn(p) { p.name(1) };`,
+			[]string{"n: int", "size: fn(a) -> b", "get: fn(a) -> b"},
+		},
+
+		// a caught error has a message (and a value / stack of unknown type)
+		{
+			`let n = try { 1 } catch (e) { e.message.len() } finally { "done" }; let check = fn(x) { if (x) { throw "bad" } else { x } };`,
+			[]string{"n: int", "check: fn(bool) -> bool"},
+		},
+
+		// ?? falls back when the left side is null, optional access on null is null
+		{
+			`let a = null ?? 1; let b = 2 ?? 3; let c = fn(p) { p?.name ?? 0 }; let d = null?.len(); let e = null?[0];`,
+			[]string{"a: int", "b: int", "c: fn(a) -> int", "d: null", "e: a"},
+		},
+
+		// exports are checked like the declarations they wrap, imports are unknown
+		{
+			`import "lib.mk" as lib; export let n = inc(1); export fn inc(x) { x + 1 }`,
+			[]string{"inc: fn(int) -> int", "lib: a", "n: int"},
+		},
+
+		// function bodies may use lets declared further down
+		{
+			"let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } }; let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };",
+			[]string{"even: fn(int) -> bool", "odd: fn(int) -> bool"},
+		},
+	}
+
+	for _, test := range tests {
+		result := Check(parse(t, test.input), nil)
+
+		for _, err := range result.Errors {
+			t.Errorf("%q - unexpected error: %s", test.input, err)
+		}
+
+		if len(result.Bindings) != len(test.expected) {
+			t.Errorf("%q - wrong number of bindings. want=%d, got=%v",
+				test.input, len(test.expected), result.Bindings)
+			continue
+		}
+
+		for i, binding := range result.Bindings {
+			if binding.String() != test.expected[i] {
+				t.Errorf("%q - binding[%d] wrong. want=%q, got=%q",
+					test.input, i, test.expected[i], binding.String())
+			}
+		}
+	}
+}
+
+func TestTypeErrors(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string
+	}{
+		{"5 + true;", []string{"1:5: type mismatch: expected int, got bool"}},
+		{`"abc".len() + "d"`, []string{"1:15: type mismatch: expected int, got string"}},
+		{`"abc" + 1`, []string{"1:9: type mismatch: expected string, got int"}},
+		{`[1, -true]; {"a": 1 + true}`, []string{
+			"1:6: type mismatch: expected int, got bool",
+			"1:23: type mismatch: expected int, got bool",
+		}},
+		{`"abc".size()`, []string{"1:7: string has no method size"}},
+		{`0..true`, []string{"1:4: type mismatch: expected int, got bool"}},
+		{"[x for x in 0..3 if x]", []string{"1:21: type mismatch: expected bool, got int"}},
+		{`{k: v for (k, v) in 0..3 for s in "a"[v..k] if s.len() > true}`, []string{"1:58: type mismatch: expected int, got bool"}},
+		{`"abc"["a"..=2].len() + (0..2)`, []string{
+			"1:7: type mismatch: expected int, got string",
+			"1:25: type mismatch: expected int, got range",
+		}},
+		{"let f = fn(r: range) { for (k, v in r) { k + v == true } }", []string{"1:51: type mismatch: expected int, got bool"}},
+		{"`a\n${1 + true}`.len()", []string{"2:7: type mismatch: expected int, got bool"}},
+		{`1 ?? true`, []string{"1:6: type mismatch: expected int, got bool"}},
+		{`let x = null; x + 1`, []string{"1:15: type mismatch: expected int, got null"}},
+		{`let s: null = null; s.len()`, []string{"1:23: null has no method len"}},
+		{`"abc".len(1)`, []string{`1:1: wrong number of arguments to "abc".len: want 0, got 1`}},
+		{"let f = fn(x) { x }; f.name", []string{"1:24: fn(a) -> a has no field name"}},
+		{"try { 1 } catch (e) { e.message }", []string{"1:23: type mismatch: expected int, got string"}},
+		{"let f = fn(e: error) { e.value + e.code }", []string{"1:36: error has no field code"}},
+		{"-true", []string{"1:2: type mismatch: expected int, got bool"}},
+		{`match (1) { "a" => 1, _ => 2 }`, []string{`1:13: type mismatch: expected int, got string`}},
+		{"1 == false", []string{"1:6: type mismatch: expected int, got bool"}},
+		{"if (1) { 2 }", []string{"1:5: type mismatch: expected bool, got int"}},
+		{
+			"if (true) { 1 } else { false }",
+			[]string{"1:24: type mismatch: expected int, got bool"},
+		},
+		{
+			"let add = fn(a, b) { a + b };\nadd(1, true);",
+			[]string{"2:8: type mismatch: expected int, got bool"},
+		},
+		{
+			"let add = fn(a, b) { a + b };\nadd(1);",
+			[]string{"2:1: wrong number of arguments to add: want 2, got 1"},
+		},
+		{"let x = 5; x(1);", []string{"1:12: cannot call non-function x of type int"}},
+		{"let f = fn(x) { x(x) };", []string{"1:17: type mismatch: expected fn(a) -> b, got a"}},
+		{
+			"let f = fn(n) { if (n) { return 1; }; false };",
+			[]string{"1:39: type mismatch: expected int, got bool"},
+		},
+		{"let y = z + 1;", []string{"1:9: undefined: z"}},
+		{"while (1) { break; }", []string{"1:8: type mismatch: expected bool, got int"}},
+		{
+			"if (true) { 1 } else if (false) { 2 }",
+			[]string{"1:22: type mismatch: expected int, got null"},
+		},
+		{"fn f(n) { n + 1 } f(true);", []string{"1:21: type mismatch: expected int, got bool"}},
+		{"let x = 1; x = true;", []string{"1:16: type mismatch: expected int, got bool"}},
+		{"let b = true; b += 1;", []string{"1:15: type mismatch: expected int, got bool"}},
+
+		{"let f = fn(a, b = true) { a + b };", []string{"1:31: type mismatch: expected int, got bool"}},
+		{"let f = fn(a = 1, b = a == 2) { b }; f(true);", []string{"1:40: type mismatch: expected int, got bool"}},
+		{
+			"let inc = fn(a, b = 1) { a + b };\ninc(1, 2, 3);",
+			[]string{"2:1: wrong number of arguments to inc: want 1 to 2, got 3"},
+		},
+		{
+			"let f = fn(a, ...rest) { a };\nf();",
+			[]string{"2:1: wrong number of arguments to f: want at least 1, got 0"},
+		},
+
+		{"match (1) { true => 1, _ => 2 }", []string{"1:13: type mismatch: expected int, got bool"}},
+		{"match (1) { n if n => 1, _ => 2 }", []string{"1:18: type mismatch: expected bool, got int"}},
+		{"match (1) { 1 => 1, _ => false }", []string{"1:26: type mismatch: expected int, got bool"}},
+
+		{
+			"let add = fn(a, b) { a + b };\ntrue |> add(2);",
+			[]string{"2:1: type mismatch: expected int, got bool"},
+		},
+
+		// parameters are monomorphic, unlike let-bound functions
+		{"let g = fn(f) { f(1) + f(true) };", []string{"1:26: type mismatch: expected int, got bool"}},
+	}
+
+	for _, test := range tests {
+		result := Check(parse(t, test.input), nil)
+
+		messages := []string{}
+		for _, err := range result.Errors {
+			messages = append(messages, err.String())
+		}
+
+		if len(messages) != len(test.expected) {
+			t.Errorf("%q - wrong errors. want=%q, got=%q", test.input, test.expected, messages)
+			continue
+		}
+
+		for i, message := range messages {
+			if message != test.expected[i] {
+				t.Errorf("%q - errors[%d] wrong. want=%q, got=%q",
+					test.input, i, test.expected[i], message)
+			}
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	tests := []struct{
+		input    string
+		expected []string  // bindings, then errors
+	}{
+		{"let x: int = 5;", []string{"x: int"}},
+		{"let id: fn(int) -> int = fn(x) { x };", []string{"id: fn(int) -> int"}},
+		{"let f = fn(a: bool, b) -> int { b };", []string{"f: fn(bool, int) -> int"}},
+		{
+			"let fact: fn(int) -> int = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };",
+			[]string{"fact: fn(int) -> int"},
+		},
+		{"let x: bool = 5;", []string{"x: bool", "1:15: type mismatch: expected bool, got int"}},
+		{"let f = fn(a: bool) { a + 1 };", []string{"f: fn(bool) -> int", "1:23: type mismatch: expected int, got bool"}},
+		{"let f = fn() -> int { true };", []string{"f: fn() -> int", "1:23: type mismatch: expected int, got bool"}},
+		{"let x: text = 1;", []string{"x: int", "1:8: unknown type text"}},
+		{"let s: string = 1;", []string{"s: string", "1:17: type mismatch: expected string, got int"}},
+		{
+			"let g: fn(int) -> int = fn(a, b) { a };",
+			[]string{"g: fn(int) -> int", "1:25: type mismatch: expected fn(int) -> int, got fn(a, b) -> a"},
+		},
+	}
+
+	for _, test := range tests {
+		result := Check(parse(t, test.input), nil)
+
+		actual := []string{}
+		for _, binding := range result.Bindings {
+			actual = append(actual, binding.String())
+		}
+		for _, err := range result.Errors {
+			actual = append(actual, err.String())
+		}
+
+		if strings.Join(actual, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%q - wrong result. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestPredeclared(t *testing.T) {
+	element := NewVariable()
+	rest    := NewVariable()
+
+	predeclared := map[string]*Scheme{
+		"print": {Generic: []*TypeVariable{rest}, Type: VariadicFunction(nil, rest, Null)},
+		"size":  {Generic: []*TypeVariable{element}, Type: Function([]Type{element}, Int)},
+		"limit": {Type: Int},
+	}
+
+	tests := []struct{
+		input    string
+		expected []string  // bindings, then errors
+	}{
+		{`print("a", 1); print(true); print();`, []string{}},
+		{`let n = size("abc") + size(true) + limit;`, []string{"n: int"}},
+		{"let p = print;", []string{"p: fn(...a) -> null"}},
+		{"let limit = true; let b = limit;", []string{"limit: bool", "b: bool"}},
+		{"size(1, 2);", []string{"1:1: wrong number of arguments to size: want 1, got 2"}},
+		{"let x = print(1) + 1;", []string{"x: int", "1:9: type mismatch: expected int, got null"}},
+		{"missing(1);", []string{"1:1: undefined: missing"}},
+	}
+
+	for _, test := range tests {
+		result := Check(parse(t, test.input), predeclared)
+
+		actual := []string{}
+		for _, binding := range result.Bindings {
+			actual = append(actual, binding.String())
+		}
+		for _, err := range result.Errors {
+			actual = append(actual, err.String())
+		}
+
+		if strings.Join(actual, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%q - wrong result. want=%q, got=%q", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestExpressionTypes(t *testing.T) {
+	program := parse(t, "let id = fn(x) { x }; id(5) + 1;")
+	result  := Check(program, nil)
+
+	sum  := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+	call := sum.Left.(*ast.CallExpression)
+
+	if got := TypeString(result.Types[call]); got != "int" {
+		t.Errorf("type of id(5) wrong. got=%s", got)
+	}
+
+	if got := TypeString(result.Types[sum]); got != "int" {
+		t.Errorf("type of id(5) + 1 wrong. got=%s", got)
+	}
+}
+
+//―――[ Main Tests ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+
+func parse(t *testing.T, input string) *ast.Program {
+	p       := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return program
+}
+
+//―――[ Helper Functions ]―――――――――――――――――――――――――――――――――――――――――――――――――――――――
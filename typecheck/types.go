@@ -0,0 +1,235 @@
+package typecheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is either a *TypeVariable or a *TypeOperator.
+type Type interface {
+	typeNode()
+}
+
+// TypeVariable stands for a type not known yet. Once unified, instance points
+// at the type it was bound to.
+type TypeVariable struct {
+	id       int
+	instance Type
+}
+
+// TypeOperator is a concrete type: int, bool, string, error, null, or fn with the parameter
+// types followed by the result type in Args. For fn, the last Optional
+// parameters (before a Variadic rest parameter) have default values.
+type TypeOperator struct {
+	Name     string
+	Args     []Type
+	Optional int
+	Variadic bool
+}
+
+func (variable *TypeVariable) typeNode() {}
+func (operator *TypeOperator) typeNode() {}
+
+var (
+	Int    = &TypeOperator{Name: "int"}
+	Bool   = &TypeOperator{Name: "bool"}
+	String = &TypeOperator{Name: "string"}
+	Null   = &TypeOperator{Name: "null"}  // value of an if without else / empty block
+	Range  = &TypeOperator{Name: "range"} // a..b: the ints from a, iterated by for-in
+	Array  = &TypeOperator{Name: "array"} // receiver of the array Methods; literals are not typed as arrays (yet)
+
+	// what catch (e) binds e to: the message, thrown value and call stack of a throw
+	ErrorValue = &TypeOperator{Name: "error"}
+)
+
+// Fields are the members of values of the (non-function) types, by type name.
+// A nil type is unknown: the value of an error is whatever was thrown, and
+// there is no type for its stack (yet).
+var Fields = map[string]map[string]Type{
+	"error": {
+		"message": String,
+		"value":   nil,
+		"stack":   nil,
+	},
+}
+
+// Methods are the builtins callable as `receiver.name(args)`, by the name of
+// the receiver's type. Their first parameter is the receiver. They mirror the
+// methods of the evaluator, one entry for each.
+var Methods = map[string]map[string]*TypeOperator{
+	"string": {
+		"len": Function([]Type{String}, Int),
+	},
+	"array": {
+		"len": Function([]Type{Array}, Int),
+	},
+}
+
+const functionName = "fn"
+
+func Function(parameters []Type, result Type) *TypeOperator {
+	args := append([]Type{}, parameters...)
+
+	return &TypeOperator{
+		Name: functionName,
+		Args: append(args, result),
+	}
+}
+
+// VariadicFunction is Function with a last parameter of type rest, which
+// collects the remaining arguments.
+func VariadicFunction(parameters []Type, rest, result Type) *TypeOperator {
+	function := Function(append(append([]Type{}, parameters...), rest), result)
+	function.Variadic = true
+
+	return function
+}
+
+// NewVariable returns a type variable to generalize the Scheme of a
+// predeclared name over, e.g. len: forall a. fn(a) -> int.
+func NewVariable() *TypeVariable {
+	return &TypeVariable{}
+}
+
+// Scheme is a type generalized over some of its variables by a let binding,
+// e.g. `let id = fn(x) { x }` has the scheme forall a. fn(a) -> a
+type Scheme struct {
+	Generic []*TypeVariable
+	Type    Type
+}
+
+
+//---[ Type Helper Functions ]--------------------------------------------------
+
+// follows bound variables to the type they stand for
+func prune(t Type) Type {
+	variable, ok := t.(*TypeVariable)
+	if !ok || variable.instance == nil {
+		return t
+	}
+
+	variable.instance = prune(variable.instance)
+	return variable.instance
+}
+
+func occursIn(variable *TypeVariable, t Type) bool {
+	switch pruned := prune(t).(type) {
+	case *TypeVariable:
+		return pruned == variable
+	case *TypeOperator:
+		for _, arg := range pruned.Args {
+			if occursIn(variable, arg) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func freeVariables(t Type, into map[*TypeVariable]bool) {
+	switch pruned := prune(t).(type) {
+	case *TypeVariable:
+		into[pruned] = true
+	case *TypeOperator:
+		for _, arg := range pruned.Args {
+			freeVariables(arg, into)
+		}
+	}
+}
+
+func isFunction(t Type) (*TypeOperator, bool) {
+	operator, ok := prune(t).(*TypeOperator)
+	if !ok || operator.Name != functionName {
+		return nil, false
+	}
+
+	return operator, true
+}
+
+// number of fn parameters that are not the rest parameter
+func (operator *TypeOperator) fixedParameters() int {
+	fixed := len(operator.Args) - 1
+	if operator.Variadic {
+		fixed--
+	}
+
+	return fixed
+}
+
+//---[ Type Helper Functions ]--------------------------------------------------
+
+
+//---[ Type Printing ]----------------------------------------------------------
+
+// printer names type variables a, b, c... in order of first appearance, so
+// types printed through the same printer agree on their names
+type printer struct {
+	names map[*TypeVariable]string
+}
+
+func newPrinter() *printer {
+	return &printer{
+		names: make(map[*TypeVariable]string),
+	}
+}
+
+// TypeString renders t with its variables named a, b, c...
+func TypeString(t Type) string {
+	return newPrinter().print(t)
+}
+
+func (scheme *Scheme) String() string {
+	return TypeString(scheme.Type)
+}
+
+func (printer *printer) print(t Type) string {
+	switch pruned := prune(t).(type) {
+	case *TypeVariable:
+		name, ok := printer.names[pruned]
+		if !ok {
+			name = variableName(len(printer.names))
+			printer.names[pruned] = name
+		}
+
+		return name
+
+	case *TypeOperator:
+		if pruned.Name != functionName {
+			return pruned.Name
+		}
+
+		// optional parameters print as [int], the rest parameter as ...int
+		last       := len(pruned.Args) - 1
+		fixed      := pruned.fixedParameters()
+		parameters := []string{}
+		for i, parameter := range pruned.Args[:last] {
+			printed := printer.print(parameter)
+
+			switch {
+			case i >= fixed:
+				printed = "..." + printed
+			case i >= fixed-pruned.Optional:
+				printed = "[" + printed + "]"
+			}
+
+			parameters = append(parameters, printed)
+		}
+
+		return fmt.Sprintf("fn(%s) -> %s", strings.Join(parameters, ", "), printer.print(pruned.Args[last]))
+	}
+
+	return "?"
+}
+
+// a ... z, a1 ... z1, ...
+func variableName(index int) string {
+	name := string(rune('a' + index%26))
+	if index >= 26 {
+		name += fmt.Sprintf("%d", index/26)
+	}
+
+	return name
+}
+
+//---[ Type Printing ]----------------------------------------------------------